@@ -4,19 +4,57 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
 
+	"github.com/lordvidex/oncall-go-client/internal/exitcode"
 	"github.com/lordvidex/oncall-go-client/internal/oncall"
 )
 
 var (
-	filename string
+	filename     string
+	configFormat string
+	valuesFile   string
+	rateLimit    float64
+	burst        int
+	concurrency  int
+	timeout      time.Duration
+	retries      int
+	retryDelay   time.Duration
+	upsert       bool
+	syncMode     bool
+	prune        bool
+	plan         bool
+	dryRun       bool
+	exportFile   string
+	exportTeams  string
 )
 
 func init() {
-	flag.StringVar(&filename, "f", "", "yaml config file to read oncall teams from")
+	flag.StringVar(&filename, "f", "", "yaml, json or toml config file, or directory of config files merged together, to read oncall teams from")
+	flag.StringVar(&configFormat, "config-format", "", "format of -f: yaml, json or toml, by its extension if empty")
+	flag.StringVar(&valuesFile, "values", "", "if set, the config files are rendered as Go templates of the values of this yaml, json or toml file")
+	flag.Float64Var(&rateLimit, "rate-limit", 0, "maximum requests per second sent to oncall, 0 disables the limit")
+	flag.IntVar(&burst, "burst", 10, "number of requests allowed at once above -rate-limit")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "timeout of each request made to the oncall server")
+	flag.IntVar(&retries, "retries", 1, "attempts of each read, update or delete request failing with a timeout, a reset connection or a 5xx status, creates are only retried when rate limited")
+	flag.DurationVar(&retryDelay, "retry-delay", 500*time.Millisecond, "delay before the first retry, doubled at each attempt")
+	flag.IntVar(&concurrency, "concurrency", 1, "number of requests sent to oncall at once while creating teams and users")
+	flag.BoolVar(&syncMode, "sync", false, "reconcile oncall with the config: only missing or different entities are created or updated")
+	flag.BoolVar(&prune, "prune", false, "with -sync, also delete the members, rosters, services, admins and events of the configured teams missing from the config")
+	flag.BoolVar(&plan, "plan", false, "with -sync, only log the changes that would be applied")
+	flag.BoolVar(&dryRun, "dry-run", false, "log the requests that would change oncall instead of sending them, reads are still sent")
+	flag.StringVar(&exportFile, "export", "", "write the live state of oncall to this yaml config file instead of applying -f, - for stdout")
+	flag.StringVar(&exportTeams, "export-teams", "", "comma separated teams written by -export, every team if empty")
+	flag.BoolVar(&upsert, "upsert", false, "update the fields of existing teams and users that differ from the config")
 }
 
 func main() {
@@ -24,23 +62,211 @@ func main() {
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	logger := zerolog.New(zerolog.NewConsoleWriter())
 
+	if err := run(logger); err != nil {
+		exitcode.Fatal(logger, err, "failed to create entities")
+	}
+	logger.Info().Msgf("finished loading configs from %s", filename)
+}
+
+// run creates the entities of the config file, the error sets the exit code, see exitcode.Of
+func run(logger zerolog.Logger) error {
+	if exportFile != "" {
+		return export(logger)
+	}
 	if filename == "" {
-		logger.Fatal().Msg("filename must be provided")
+		return exitcode.Wrap(exitcode.Config, errors.New("filename must be provided"))
 	}
 
-	client, err := oncall.New()
+	opts := []oncall.Option{
+		oncall.WithRateLimit(rateLimit, burst),
+		oncall.WithTimeout(timeout),
+	}
+	if retries > 1 {
+		// creates are not retried, an event create that reached oncall would be duplicated
+		opts = append(opts, oncall.WithRetry(retries, retryDelay))
+	}
+	if upsert {
+		opts = append(opts, oncall.WithUpsert())
+	}
+	if dryRun {
+		opts = append(opts, oncall.WithDryRun(true))
+	}
+	client, err := oncall.New(opts...)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	if dryRun {
+		defer func() {
+			logger.Info().Int("requests", len(client.PlannedRequests())).Msg("dry run, nothing was changed")
+		}()
+	}
+	var values map[string]any
+	if valuesFile != "" {
+		if values, err = oncall.ReadValues(valuesFile); err != nil {
+			return exitcode.Wrap(exitcode.Config, fmt.Errorf("loading values: %w", err))
+		}
+	}
+	config, err := oncall.LoadConfig(filename, oncall.WithFormat(configFormat), oncall.WithValues(values))
 	if err != nil {
-		logger.Fatal().Err(err).Send()
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("loading config: %w", err))
+	}
+	if err = config.Validate(); err != nil {
+		logFailures(logger, err)
+		return exitcode.Wrap(exitcode.Validation, err)
+	}
+	logScheduleWarnings(logger, config)
+	if roles, err := client.Roles(context.Background()); err != nil {
+		logger.Warn().Err(err).Msg("error fetching the roles of oncall, roles are not validated")
+	} else if err = config.ValidateRoles(roles); err != nil {
+		logFailures(logger, err)
+		return exitcode.Wrap(exitcode.Validation, err)
+	}
+	if syncMode {
+		return runSync(logger, client, config)
+	}
+	report, err := client.CreateEntities(context.Background(), config, oncall.WithConcurrency(concurrency))
+	if report != nil {
+		logOutcomes(logger, report)
 	}
-	config, err := oncall.LoadConfig(filename)
 	if err != nil {
-		logger.Error().Err(err).Msg("error loading config")
-		return
+		logFailures(logger, err)
+		if code := exitcode.Of(err); code != exitcode.Partial && report.Succeeded() {
+			// the failures share a cause, but other changes were applied
+			return exitcode.Wrap(exitcode.Partial, err)
+		}
+	}
+	return err
+}
+
+// export writes the config of the live teams to exportFile
+func export(logger zerolog.Logger) error {
+	var teams []string
+	for _, t := range strings.Split(exportTeams, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			teams = append(teams, t)
+		}
+	}
+	client, err := oncall.New(oncall.WithTimeout(timeout), oncall.WithRateLimit(rateLimit, burst))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+	config, err := client.ExportConfig(context.Background(), teams...)
+	if err != nil {
+		logFailures(logger, err)
+		if len(config.Teams) == 0 {
+			return err
+		}
+	}
+	out := os.Stdout
+	if exportFile != "-" {
+		f, err := os.Create(exportFile)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Config, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if encErr := errors.Join(enc.Encode(config), enc.Close()); encErr != nil {
+		return encErr
+	}
+	logger.Info().Int("teams", len(config.Teams)).Str("file", exportFile).Msg("exported config")
+	// teams were exported without some of their entities
+	return exitcode.Wrap(exitcode.Partial, err)
+}
+
+// runSync reconciles oncall with config and logs each change
+func runSync(logger zerolog.Logger, client *oncall.Client, config oncall.Config) error {
+	var opts []oncall.SyncOption
+	if prune {
+		opts = append(opts, oncall.SyncPrune())
+	}
+	if plan {
+		opts = append(opts, oncall.SyncPlan())
+	}
+	report, err := client.Sync(context.Background(), config, opts...)
+	if report == nil {
+		return err
+	}
+	applied := 0
+	for _, ch := range report.Changes {
+		e := logger.Info()
+		if ch.Err != nil {
+			e = logger.Error().Err(ch.Err)
+		}
+		e.Str("action", string(ch.Action)).
+			Str("kind", ch.Kind).
+			Str("name", ch.Name).
+			Str("team", ch.Team).
+			Str("detail", ch.Detail).
+			Interface("fields", ch.Fields).
+			Bool("applied", ch.Applied).
+			Send()
+		if ch.Applied {
+			applied++
+		}
 	}
-	if _, err = client.CreateEntities(config); err != nil {
-		logger.Error().Err(err).Msg("failed to create entities")
+	logger.Info().Int("changes", len(report.Changes)).Int("applied", applied).Bool("plan", report.Plan).Msg("sync finished")
+	if err != nil && applied > 0 && exitcode.Of(err) != exitcode.Partial {
+		return exitcode.Wrap(exitcode.Partial, err)
+	}
+	return err
+}
+
+// logFailures logs each entity of a *oncall.MultiError
+func logFailures(logger zerolog.Logger, err error) {
+	var multi *oncall.MultiError
+	if !errors.As(err, &multi) {
 		return
 	}
+	for _, e := range multi.Errors {
+		logger.Error().
+			Str("op", e.Op).
+			Str("kind", e.Kind).
+			Str("name", e.Name).
+			Str("team", e.Team).
+			Str("detail", e.Detail).
+			Err(e.Err).
+			Send()
+	}
+	logger.Error().Int("failures", len(multi.Errors)).Send()
+}
 
-	logger.Info().Msgf("finished loading configs from %s", filename)
+// logScheduleWarnings logs the warnings of ValidateSchedule, such as duties in the past
+func logScheduleWarnings(logger zerolog.Logger, config oncall.Config) {
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			for _, issue := range oncall.ValidateSchedule(u.Name, t.Name, t.SchedulingTimezone, u.Schedule) {
+				if !issue.Warning {
+					continue
+				}
+				logger.Warn().
+					Str("kind", string(issue.Kind)).
+					Str("user", issue.User).
+					Str("team", issue.Team).
+					Str("date", issue.Date).
+					Msg(issue.Message)
+			}
+		}
+	}
+}
+
+// logOutcomes logs the number of teams and users per outcome of their creation
+func logOutcomes(logger zerolog.Logger, report *oncall.EntityReport) {
+	teams := make(map[oncall.Outcome]int)
+	users := make(map[oncall.Outcome]int)
+	for _, t := range report.Teams {
+		teams[t.Create.Outcome]++
+		for _, u := range t.Users {
+			users[u.Create.Outcome]++
+		}
+	}
+	for kind, counts := range map[string]map[oncall.Outcome]int{"teams": teams, "users": users} {
+		e := logger.Info().Str("kind", kind)
+		for outcome, n := range counts {
+			e = e.Int(string(outcome), n)
+		}
+		e.Msg("outcomes")
+	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import "sync"
+
+// anomalyDetector flags available-member counts that drop abruptly below their rolling baseline
+type anomalyDetector struct {
+	mu sync.Mutex
+	// window is the number of past samples making up the baseline
+	window int
+	// threshold is the relative drop from the baseline considered anomalous, e.g. 0.5 for half
+	threshold float64
+	samples   map[[2]string][]float64
+}
+
+func newAnomalyDetector(window int, threshold float64) *anomalyDetector {
+	return &anomalyDetector{
+		window:    window,
+		threshold: threshold,
+		samples:   make(map[[2]string][]float64),
+	}
+}
+
+// observe records the value of a team role and reports whether it is anomalous
+// compared to the mean of the previous samples. No anomaly is reported until the window is full.
+func (d *anomalyDetector) observe(team, role string, v float64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := [2]string{team, role}
+	past := d.samples[key]
+	anomalous := false
+	if len(past) == d.window {
+		var sum float64
+		for _, p := range past {
+			sum += p
+		}
+		baseline := sum / float64(len(past))
+		anomalous = baseline > 0 && v < baseline*(1-d.threshold)
+		past = past[1:]
+	}
+	d.samples[key] = append(past, v)
+	return anomalous
+}
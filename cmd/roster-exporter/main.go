@@ -1,12 +1,18 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,6 +20,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/lordvidex/oncall-go-client/internal/oncall"
+	"github.com/lordvidex/oncall-go-client/internal/sink"
 )
 
 var (
@@ -28,6 +35,61 @@ var (
 		},
 		[]string{"role", "team"},
 	)
+	availableTeamMembersAnomalyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_avail_users_anomaly",
+			Help: "1 if the number of available team members dropped abruptly below its rolling baseline, 0 otherwise",
+		},
+		[]string{"role", "team"},
+	)
+	shiftRemainingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_current_shift_seconds_remaining",
+			Help: "Seconds until the last current shift of a team role ends, absent when nobody is on call",
+		},
+		[]string{"team", "role"},
+	)
+	totalCoverageGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_total_coverage",
+			Help: "1 if at least one member of a team is on call in any pageable role, 0 otherwise",
+		},
+		[]string{"team"},
+	)
+	coveragePriorityGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_coverage_priority",
+			Help: "Position in -pageable-roles of the first role of a team with someone on call, starting at 1, 0 when nobody is",
+		},
+		[]string{"team"},
+	)
+	onCallUserGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_on_call_user",
+			Help: "1 for each user currently on call in a team role, only exported with -on-call-users",
+		},
+		[]string{"team", "role", "user"},
+	)
+	rotationGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_next_rotation_timestamp_seconds",
+			Help: "Unix time the users on call in a team role change next, absent when the role has no shift",
+		},
+		[]string{"team", "role"},
+	)
+	nextShiftGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oncall_next_shift_scheduled",
+			Help: "1 if a shift follows the current one of a team role, 0 otherwise",
+		},
+		[]string{"team", "role"},
+	)
+	activeUsersGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oncall_active_users",
+			Help: "The number of active users registered in oncall",
+		},
+	)
 	errorsCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "oncall_http_errors_total",
@@ -42,6 +104,12 @@ var (
 		},
 		[]string{"path"},
 	)
+	scrapeDeadlineCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "oncall_scrape_deadline_exceeded_total",
+			Help: "Amount of collections cut short by the deadline of the scrape, leaving partial data",
+		},
+	)
 	statusCodeHist = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "oncall_http_status_code",
@@ -53,22 +121,94 @@ var (
 )
 
 var (
-	scrapeStr string
-	oncallURL string
-	port      int
-	silent    bool
+	scrapeStr   string
+	oncallURL   string
+	timeout     time.Duration
+	port        int
+	silent      bool
+	openMetrics bool
+
+	metricsSink  string
+	statsdAddr   string
+	statsdPrefix string
+
+	anomalyWindow    int
+	anomalyThreshold float64
+
+	appName string
+
+	tlsCA       string
+	tlsCert     string
+	tlsKey      string
+	tlsInsecure bool
+	proxyURL    string
+
+	onScrape bool
+
+	teamFilter    string
+	teamsPageSize int
+
+	onCallUsers bool
+
+	pageableRolesStr string
+	pageableRoles    []string
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	hedgeDelay time.Duration
+	hedgeMax   int
+
+	timezoneMismatchHours float64
 )
 
 func init() {
 	flag.StringVar(&scrapeStr, "scrape-duration", "30s", "interval to update and fetch new metrics")
 	flag.StringVar(&oncallURL, "oncall", "http://oncall-web:8080", "url of the oncall server")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "timeout of each request made to the oncall server")
 	flag.IntVar(&port, "port", 9213, "port for hosting metrics")
 	flag.BoolVar(&silent, "silent", false, "if true, logs are not printed for oncall client")
+	flag.IntVar(&anomalyWindow, "anomaly-window", 0, "number of past scrapes making the baseline of the anomaly detector, 0 disables it")
+	flag.Float64Var(&anomalyThreshold, "anomaly-threshold", 0.5, "relative drop from the baseline flagged as an anomaly")
+	flag.StringVar(&metricsSink, "metrics-sink", "prometheus", "metrics backend: prometheus, statsd or dogstatsd. Metrics are always served for scraping")
+	flag.StringVar(&statsdAddr, "statsd-addr", "127.0.0.1:8125", "udp address of the statsd agent used by -metrics-sink")
+	flag.StringVar(&statsdPrefix, "statsd-prefix", "oncall_exporter", "prefix of the metric names sent to statsd")
+	flag.StringVar(&appName, "app", "", "if set, requests are signed as this oncall application with the key in $ONCALL_APP_KEY instead of logging in")
+	flag.BoolVar(&onScrape, "on-scrape", false, "if true, oncall is queried on each scrape within the scrape timeout instead of every -scrape-duration")
+	flag.IntVar(&breakerThreshold, "breaker-threshold", 0, "consecutive oncall failures opening the circuit breaker, 0 disables it")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", 30*time.Second, "time the circuit breaker stays open before probing oncall again")
+	flag.DurationVar(&hedgeDelay, "hedge-delay", 0, "if positive, a read not answered by oncall within it is sent again and the slower attempt cancelled, 0 disables hedging")
+	flag.IntVar(&hedgeMax, "hedge-max", 1, "number of extra attempts hedging a read, with -hedge-delay")
+	flag.StringVar(&tlsCA, "tls-ca", "", "PEM file of the CA certificates trusted for an https oncall server, in addition to the system ones")
+	flag.StringVar(&tlsCert, "tls-cert", "", "PEM client certificate presented to oncall, with -tls-key")
+	flag.StringVar(&tlsKey, "tls-key", "", "PEM key of -tls-cert")
+	flag.BoolVar(&tlsInsecure, "tls-insecure", false, "if true, the certificate of oncall is not verified")
+	flag.StringVar(&proxyURL, "proxy", "", "url of the HTTP proxy used to reach oncall, $HTTPS_PROXY and $HTTP_PROXY are used if empty")
+	flag.StringVar(&teamFilter, "team-filter", "", "if set, only the teams whose name contains it are exported")
+	flag.IntVar(&teamsPageSize, "teams-page-size", 50, "number of teams processed between checks of the scrape deadline")
+	flag.BoolVar(&onCallUsers, "on-call-users", false, "if true, oncall_on_call_user names the users on call, one series per user")
+	flag.StringVar(&pageableRolesStr, "pageable-roles", "primary,secondary,manager", "comma separated roles covering a team in oncall_total_coverage, by priority")
+	flag.Float64Var(&timezoneMismatchHours, "timezone-mismatch-hours", 0, "if positive, oncall_timezone_mismatch counts the members of each team whose timezone is more hours away from the scheduling timezone, 0 disables it")
+	flag.BoolVar(&openMetrics, "openmetrics", false, "if true, OpenMetrics format with _created series is negotiated on /metrics")
 
 	prometheus.MustRegister(availableTeamMembersGauge)
+	prometheus.MustRegister(activeUsersGauge)
+	prometheus.MustRegister(shiftRemainingGauge)
+	prometheus.MustRegister(nextShiftGauge)
+	prometheus.MustRegister(totalCoverageGauge)
+	prometheus.MustRegister(rotationGauge)
+	prometheus.MustRegister(onCallUserGauge)
+	prometheus.MustRegister(coveragePriorityGauge)
+	prometheus.MustRegister(availableTeamMembersAnomalyGauge)
 	prometheus.MustRegister(requestDurationHist)
 	prometheus.MustRegister(statusCodeHist)
 	prometheus.MustRegister(errorsCounter)
+	prometheus.MustRegister(scrapeDeadlineCounter)
+	prometheus.MustRegister(timezoneMismatchGauge)
+
+	// the teams path is always scraped, so it can be created before the first tick
+	errorsCounter.WithLabelValues("teams")
+	errorsCounter.WithLabelValues("users")
 }
 
 func main() {
@@ -80,6 +220,11 @@ func main() {
 	if err != nil {
 		log.Fatal("failed to parse scrape-duration")
 	}
+	for _, role := range strings.Split(pageableRolesStr, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			pageableRoles = append(pageableRoles, role)
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -88,8 +233,15 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create app exporter: %v", err)
 	}
-	go app.worker(ctx)
-	http.Handle("/metrics", promhttp.Handler())
+	if err = sink.Start(ctx, metricsSink, statsdAddr, statsdPrefix, scrapeDuration, logger); err != nil {
+		log.Fatalf("failed to create metrics sink: %v", err)
+	}
+	if onScrape {
+		http.Handle("/metrics", app.scrapeHandler(metricsHandler()))
+	} else {
+		go app.worker(ctx)
+		http.Handle("/metrics", metricsHandler())
+	}
 
 	http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 }
@@ -97,31 +249,63 @@ func main() {
 type app struct {
 	logger zerolog.Logger
 	// oncall Client is used to make http calls to oncall server
-	cl *oncall.Client
+	cl oncall.API
 	// scrapeDuration is the amount of time before new metrics are scraped
 	scrapeDuration time.Duration
-	// reloginDuration is the time taken before client is relogged in, to refresh token
-	reloginDuration time.Duration
+	// anomalies compares available members with their baseline, nil when disabled
+	anomalies *anomalyDetector
+	// mu serializes collections triggered by concurrent scrapes
+	mu sync.Mutex
 }
 
 func NewApp(logger zerolog.Logger, oncallURL string, scrapeDuration time.Duration) (*app, error) {
-	opts := []oncall.Option{oncall.WithURL(oncallURL)}
+	opts := []oncall.Option{
+		oncall.WithURL(oncallURL),
+		oncall.WithTimeout(timeout),
+		oncall.WithCircuitBreaker(breakerThreshold, breakerCooldown),
+		oncall.WithHedging(hedgeDelay, hedgeMax),
+	}
+	opts = append(opts, oncall.TLSOptions(tlsCA, tlsCert, tlsKey, tlsInsecure)...)
+	if proxyURL != "" {
+		opts = append(opts, oncall.WithProxy(proxyURL))
+	}
 	if silent {
 		opts = append(opts, oncall.WithLogger(zerolog.Nop()))
 	}
+	if appName != "" {
+		key := os.Getenv("ONCALL_APP_KEY")
+		if key == "" {
+			return nil, errors.New("ONCALL_APP_KEY must be set with -app")
+		}
+		opts = append(opts, oncall.WithAppAuth(appName, key))
+	}
 	cl, err := oncall.New(opts...)
 	if err != nil {
 		return nil, err
 	}
 	a := &app{
-		logger:          logger,
-		scrapeDuration:  scrapeDuration,
-		reloginDuration: time.Hour,
-		cl:              cl,
+		logger:         logger,
+		scrapeDuration: scrapeDuration,
+		cl:             cl,
+	}
+	if anomalyWindow > 0 {
+		a.anomalies = newAnomalyDetector(anomalyWindow, anomalyThreshold)
 	}
 	if err = a.login(); err != nil {
 		return nil, err
 	}
+	prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "oncall_client_relogins_total",
+		Help: "Total count of logins made again after the oncall session expired",
+	}, func() float64 { return float64(cl.Relogins()) }))
+	prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "oncall_client_hedged_requests_total",
+		Help: "Total count of extra attempts sent to oncall for reads not answered within -hedge-delay",
+	}, func() float64 { return float64(cl.Hedges()) }))
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "oncall_client_breaker_state",
+		Help: "State of the circuit breaker of the oncall client: 0 closed, 1 open, 2 half-open",
+	}, func() float64 { return float64(cl.BreakerState()) }))
 	return a, nil
 }
 
@@ -132,9 +316,7 @@ func (a *app) worker(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			a.updateMetrics()
-		case <-time.After(a.reloginDuration):
-			a.login()
+			a.updateMetrics(ctx)
 		}
 	}
 }
@@ -143,30 +325,163 @@ func (a *app) login() error {
 	return a.cl.Login(context.Background())
 }
 
-func (a *app) updateMetrics() error {
-	teamsResult, err := a.cl.GetTeams()
+// scrapeMargin is kept from the scrape timeout to encode and send the metrics
+const scrapeMargin = 500 * time.Millisecond
+
+// scrapeHandler collects the metrics before serving them with next, bounded by
+// the timeout Prometheus announces in X-Prometheus-Scrape-Timeout-Seconds
+func (a *app) scrapeHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		budget := a.scrapeDuration
+		if v, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64); err == nil && v > 0 {
+			budget = time.Duration(v * float64(time.Second))
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), max(budget-scrapeMargin, budget/2))
+		defer cancel()
+		a.mu.Lock()
+		if err := a.updateMetrics(ctx); err != nil {
+			a.logger.Warn().Err(err).Msg("error collecting metrics")
+		}
+		a.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// updateMetrics queries oncall and updates the metrics. When ctx is done midway the
+// remaining teams keep their previous values and the deadline counter is incremented.
+func (a *app) updateMetrics(ctx context.Context) error {
+	defer func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			scrapeDeadlineCounter.Inc()
+		}
+	}()
+	var errs []error
+	active := true
+	usersResult, err := a.cl.GetUsers(ctx, oncall.UserFilter{Active: &active})
 	if err != nil {
-		errorsCounter.WithLabelValues("teams").Inc()
-		return err
+		errs = append(errs, err)
+		errorsCounter.WithLabelValues("users").Inc()
+	} else {
+		requestDurationHist.WithLabelValues(usersResult.URLPath).Observe(usersResult.ResponseTime.Seconds())
+		statusCodeHist.WithLabelValues(usersResult.URLPath).Observe(float64(usersResult.StatusCode))
+		activeUsersGauge.Set(float64(len(usersResult.Data)))
 	}
-	errorsCounter.WithLabelValues("teams").Add(0) // to write metrics
-	requestDurationHist.WithLabelValues(teamsResult.URLPath).Observe(teamsResult.ResponseTime.Seconds())
-	statusCodeHist.WithLabelValues(teamsResult.URLPath).Observe(float64(teamsResult.StatusCode))
 
-	var errs []error
-	for _, team := range teamsResult.Data {
-		data, err := a.cl.GetSummary(team)
-		if err != nil {
-			errs = append(errs, err)
-			errorsCounter.WithLabelValues("teams/" + team).Inc()
-			continue
+	it := a.cl.TeamsIterator(oncall.TeamFilter{NameContains: teamFilter}, teamsPageSize)
+pages:
+	for it.Next(ctx) {
+		for _, team := range it.Teams() {
+			if ctx.Err() != nil {
+				break pages
+			}
+			err := a.updateTeam(ctx, team)
+			if errors.Is(err, oncall.ErrCircuitOpen) {
+				// oncall is down, the remaining teams would fail the same way
+				errs = append(errs, err)
+				break pages
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
 		}
-		requestDurationHist.WithLabelValues(data.URLPath).Observe(data.ResponseTime.Seconds())
-		statusCodeHist.WithLabelValues(data.URLPath).Observe(float64(data.StatusCode))
-		errorsCounter.WithLabelValues("teams/" + team).Add(0)
-		for _, role := range roles {
-			availableTeamMembersGauge.WithLabelValues(role, team).Set(float64(data.Data[role]))
+	}
+	if teamsResult := it.Response(); teamsResult != nil {
+		errorsCounter.WithLabelValues("teams").Add(0) // to write metrics
+		requestDurationHist.WithLabelValues(teamsResult.URLPath).Observe(teamsResult.ResponseTime.Seconds())
+		statusCodeHist.WithLabelValues(teamsResult.URLPath).Observe(float64(teamsResult.StatusCode))
+	}
+	if err := it.Err(); err != nil {
+		if it.Response() == nil {
+			errorsCounter.WithLabelValues("teams").Inc()
 		}
+		errs = append(errs, err)
+	} else if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
 	}
 	return errors.Join(errs...)
 }
+
+// updateTeam updates the metrics of the shifts of team
+func (a *app) updateTeam(ctx context.Context, team string) error {
+	data, err := a.cl.GetSummary(ctx, team)
+	if errors.Is(err, oncall.ErrCircuitOpen) {
+		return err
+	}
+	if err != nil {
+		errorsCounter.WithLabelValues("teams/" + team).Inc()
+		return err
+	}
+	requestDurationHist.WithLabelValues(data.URLPath).Observe(data.ResponseTime.Seconds())
+	statusCodeHist.WithLabelValues(data.URLPath).Observe(float64(data.StatusCode))
+	errorsCounter.WithLabelValues("teams/" + team).Add(0)
+	now := time.Now()
+	for _, role := range roles {
+		current := data.Data.Current[role]
+		v := float64(len(current))
+		availableTeamMembersGauge.WithLabelValues(role, team).Set(v)
+		if a.anomalies != nil {
+			var anomaly float64
+			if a.anomalies.observe(team, role, v) {
+				anomaly = 1
+			}
+			availableTeamMembersAnomalyGauge.WithLabelValues(role, team).Set(anomaly)
+		}
+		if len(current) == 0 {
+			shiftRemainingGauge.DeleteLabelValues(team, role)
+		} else {
+			end := slices.MaxFunc(current, func(x, y oncall.Shift) int { return cmp.Compare(x.End, y.End) }).End
+			shiftRemainingGauge.WithLabelValues(team, role).Set(max(time.Unix(end, 0).Sub(now).Seconds(), 0))
+		}
+		nextShiftGauge.WithLabelValues(team, role).Set(boolToFloat(len(data.Data.Next[role]) > 0))
+		if at, ok := data.Data.Rotation(role); ok {
+			rotationGauge.WithLabelValues(team, role).Set(float64(at.Unix()))
+		} else {
+			rotationGauge.DeleteLabelValues(team, role)
+		}
+	}
+	if onCallUsers {
+		// users leaving the rotation must not keep their series
+		onCallUserGauge.DeletePartialMatch(prometheus.Labels{"team": team})
+		for role := range data.Data.Current {
+			for _, user := range data.Data.OnCall(role) {
+				onCallUserGauge.WithLabelValues(team, role, user).Set(1)
+			}
+		}
+	}
+	priority := coveringRole(data.Data.Current)
+	totalCoverageGauge.WithLabelValues(team).Set(boolToFloat(priority > 0))
+	coveragePriorityGauge.WithLabelValues(team).Set(float64(priority))
+	if timezoneMismatchHours > 0 {
+		return a.updateTimezoneMismatch(ctx, team)
+	}
+	return nil
+}
+
+// coveringRole returns the position, starting at 1, of the first pageable role with a current
+// shift, 0 if none has
+func coveringRole(current map[string][]oncall.Shift) int {
+	for i, role := range pageableRoles {
+		if len(current[role]) > 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsHandler serves the default registry, negotiating OpenMetrics when enabled
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics:                   openMetrics,
+			EnableOpenMetricsTextCreatedSamples: openMetrics,
+		}),
+	)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+var timezoneMismatchGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "oncall_timezone_mismatch",
+		Help: "Number of members of a team whose timezone is more than -timezone-mismatch-hours away from the scheduling timezone of the team",
+	},
+	[]string{"team"},
+)
+
+// locations caches the timezones of the teams and users
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// updateTimezoneMismatch exports the number of members of team living far from its scheduling
+// timezone, whose whole day shifts start in their night
+func (a *app) updateTimezoneMismatch(ctx context.Context, team string) error {
+	res, err := a.cl.GetTeam(ctx, team)
+	if errors.Is(err, oncall.ErrCircuitOpen) {
+		return err
+	}
+	if err != nil {
+		errorsCounter.WithLabelValues("teams/" + team).Inc()
+		return err
+	}
+	requestDurationHist.WithLabelValues(res.URLPath).Observe(res.ResponseTime.Seconds())
+	statusCodeHist.WithLabelValues(res.URLPath).Observe(float64(res.StatusCode))
+	n, ok := timezoneMismatches(res.Data, time.Now(), timezoneMismatchHours)
+	if !ok {
+		timezoneMismatchGauge.DeleteLabelValues(team)
+		return nil
+	}
+	timezoneMismatchGauge.WithLabelValues(team).Set(float64(n))
+	return nil
+}
+
+// timezoneMismatches counts the users of t whose UTC offset at now differs by more than hours
+// from the one of the scheduling timezone of t, around the clock so that UTC+12 and UTC-11 are
+// an hour apart. Users without a known timezone are not counted. It is false when the team
+// has no known scheduling timezone.
+func timezoneMismatches(t oncall.TeamRecord, now time.Time, hours float64) (int, bool) {
+	if t.SchedulingTimezone == "" {
+		return 0, false
+	}
+	loc, err := loadLocation(t.SchedulingTimezone)
+	if err != nil {
+		return 0, false
+	}
+	_, team := now.In(loc).Zone()
+	n := 0
+	for _, u := range t.Users {
+		if u.TimeZone == "" {
+			continue
+		}
+		userLoc, err := loadLocation(u.TimeZone)
+		if err != nil {
+			continue
+		}
+		_, user := now.In(userLoc).Zone()
+		diff := math.Abs(float64(user-team)) / 3600
+		if min(diff, 24-diff) > hours {
+			n++
+		}
+	}
+	return n, true
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/sla"
+)
+
+// maxRecomputePoints bounds the number of evaluations of a single recompute request
+const maxRecomputePoints = 10000
+
+// adminHandler exposes endpoints to evaluate metrics outside of the ticker.
+// Every request must carry "Authorization: Bearer <ADMIN_TOKEN>".
+//
+//	POST /admin/evaluate?alias=<alias>                         evaluates now, all metrics if alias is empty
+//	POST /admin/recompute?alias=<alias>&from=&to=[&step=]      replaces the records of alias in [from, to]
+//	GET  /admin/report?alias=<alias>&from=&to=[&step=]         reconciles Prometheus history with the records
+//	GET  /admin/consistency?alias=<alias>&from=&to=            compares the records with the values pushed to the Pushgateway
+//	POST /admin/statement?month=YYYY-MM[&alias=<alias>]        writes the monthly SLA statements again
+//	GET  /admin/baseline?[alias=<alias>][&week=YYYY-MM-DD]      compares the latency of a week with the 4 weeks before
+func (a *app) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/evaluate", a.handleEvaluate)
+	mux.HandleFunc("/admin/recompute", a.handleRecompute)
+	mux.HandleFunc("/admin/report", a.handleReport)
+	mux.HandleFunc("/admin/consistency", a.handleConsistency)
+	mux.HandleFunc("/admin/statement", a.handleStatement)
+	mux.HandleFunc("/admin/baseline", a.handleBaseline)
+	return a.authenticate(mux)
+}
+
+func (a *app) authenticate(next http.Handler) http.Handler {
+	want := []byte("Bearer " + a.Cfg.AdminToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *app) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	metrics := a.Metrics
+	if alias := r.URL.Query().Get("alias"); alias != "" {
+		m, ok := a.metricByAlias(alias)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+			return
+		}
+		metrics = []metric{m}
+	}
+	if err := a.insertMetricsOf(r.Context(), metrics); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"records": len(metrics)})
+}
+
+func (a *app) handleRecompute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	m, ok := a.metricByAlias(q.Get("alias"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+		return
+	}
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be an RFC3339 time"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil || to.Before(from) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be an RFC3339 time after from"})
+		return
+	}
+	step := a.interval
+	if s := q.Get("step"); s != "" {
+		if step, err = time.ParseDuration(s); err != nil || step <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "step must be a positive duration"})
+			return
+		}
+	}
+	if int64(to.Sub(from)/step) >= maxRecomputePoints {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "too many points, increase step"})
+		return
+	}
+
+	deleted, inserted, err := a.recompute(r.Context(), m, from, to, step)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"deleted": deleted, "records": inserted})
+}
+
+// recompute replaces the stored records of m in [from, to] with evaluations every step. Unlike
+// the ticker, a failed evaluation fails the recompute instead of recording the default SLI, and
+// the stored records are only replaced once every point is evaluated.
+func (a *app) recompute(ctx context.Context, m metric, from, to time.Time, step time.Duration) (deleted, inserted int64, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cache = make(map[queryKey]queryResult)
+	var records []sla.Record
+	for at := from; !at.After(to); at = at.Add(step) {
+		v, err := a.evaluateSLI(ctx, m, at)
+		if err != nil {
+			return 0, 0, fmt.Errorf("evaluating %s at %s: %w", m.Alias, at.Format(time.RFC3339), err)
+		}
+		records = append(records, sla.Record{Time: at, Alias: m.Alias, Metric: m.Metric, SLO: m.SLO, Value: v, Met: m.met(v)})
+	}
+	if deleted, err = a.store.ReplaceRange(ctx, m.Alias, from, to, records); err != nil {
+		return 0, 0, err
+	}
+	inserted = int64(len(records))
+	a.L.Info().
+		Str("alias", m.Alias).
+		Time("from", from).
+		Time("to", to).
+		Int64("deleted", deleted).
+		Int64("inserted", inserted).
+		Msg("recomputed records")
+	return deleted, inserted, nil
+}
+
+func (a *app) metricByAlias(alias string) (metric, bool) {
+	for _, m := range a.Metrics {
+		if m.Alias == alias {
+			return m, true
+		}
+	}
+	return metric{}, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
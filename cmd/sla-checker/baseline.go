@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/notify"
+	"github.com/lordvidex/oncall-go-client/internal/sla"
+)
+
+const (
+	week = 7 * 24 * time.Hour
+	// baselineWeeks is the number of weeks before the compared week forming the baseline
+	baselineWeeks = 4
+	// minBaselineSamples is the number of records both windows need before a regression is flagged
+	minBaselineSamples = 20
+	// baselineWeek is the layout of the week parameter of /admin/baseline
+	baselineWeek = "2006-01-02"
+)
+
+// weekStart returns the Monday 00:00 UTC of the week of t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// compareBaseline compares the records of m in the week starting at from with the records of
+// the baselineWeeks weeks before it. Records within maintenance windows are left out.
+func (a *app) compareBaseline(ctx context.Context, m metric, from time.Time) (sla.Baseline, error) {
+	records, err := a.store.Records(ctx, m.Alias, from.Add(-baselineWeeks*week), from.Add(week-time.Nanosecond))
+	if err != nil {
+		return sla.Baseline{}, err
+	}
+	var current, baseline []float64
+	for _, r := range records {
+		if inMaintenance(m.Maintenance, r.Time) {
+			continue
+		}
+		if r.Time.Before(from) {
+			baseline = append(baseline, r.Value)
+		} else {
+			current = append(current, r.Value)
+		}
+	}
+	return a.buildBaseline(m, from, current, baseline), nil
+}
+
+// buildBaseline flags a regression when the latencies of current are significantly higher than
+// the baseline ones, with a one-sided Mann-Whitney U test below BASELINE_ALPHA, and the median
+// grew by at least BASELINE_MIN_INCREASE
+func (a *app) buildBaseline(m metric, from time.Time, current, baseline []float64) sla.Baseline {
+	sort.Float64s(current)
+	sort.Float64s(baseline)
+	b := sla.Baseline{
+		Week:            from,
+		Alias:           m.Alias,
+		Samples:         len(current),
+		BaselineSamples: len(baseline),
+		P50:             quantile(current, 0.5),
+		P90:             quantile(current, 0.9),
+		P99:             quantile(current, 0.99),
+		BaselineP50:     quantile(baseline, 0.5),
+		BaselineP90:     quantile(baseline, 0.9),
+		BaselineP99:     quantile(baseline, 0.99),
+		PValue:          1,
+	}
+	if len(current) < minBaselineSamples || len(baseline) < minBaselineSamples {
+		return b
+	}
+	b.PValue = mannWhitneyGreater(current, baseline)
+	b.Regression = b.PValue < a.Cfg.BaselineAlpha && b.P50 >= b.BaselineP50*(1+a.Cfg.BaselineMinIncrease)
+	return b
+}
+
+// quantile interpolates the q quantile of sorted, 0 if it is empty
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// mannWhitneyGreater returns the p-value of the values of x being stochastically greater than
+// the values of y, both sorted, using the normal approximation with tie and continuity corrections
+func mannWhitneyGreater(x, y []float64) float64 {
+	n1, n2 := float64(len(x)), float64(len(y))
+	n := n1 + n2
+
+	// rank sum of x, ties sharing their average rank
+	var rankX, ties float64
+	i, j := 0, 0
+	rank := 1.0
+	for i < len(x) || j < len(y) {
+		v := math.Inf(1)
+		if i < len(x) {
+			v = x[i]
+		}
+		if j < len(y) && y[j] < v {
+			v = y[j]
+		}
+		var inX, inY int
+		for i < len(x) && x[i] == v {
+			i++
+			inX++
+		}
+		for j < len(y) && y[j] == v {
+			j++
+			inY++
+		}
+		t := float64(inX + inY)
+		rankX += float64(inX) * (rank + (t-1)/2)
+		ties += t*t*t - t
+		rank += t
+	}
+
+	u := rankX - n1*(n1+1)/2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (u - n1*n2/2 - 0.5) / sigma
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// weeklyBaselines stores the baselines of the previous week once per week and notifies the
+// regressions. Metrics whose baseline of the week is already stored are skipped, so a restart
+// does not notify the same regressions again.
+func (a *app) weeklyBaselines(ctx context.Context, now time.Time) {
+	prev := weekStart(now).Add(-week)
+	if prev.Equal(a.baselinesDone) {
+		return
+	}
+	var errs []error
+	for _, m := range a.Metrics {
+		if !m.Baseline {
+			continue
+		}
+		stored, err := a.baselineStored(ctx, m.Alias, prev)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Alias, err))
+			continue
+		}
+		if stored {
+			continue
+		}
+		b, err := a.compareBaseline(ctx, m, prev)
+		if err == nil {
+			err = a.store.InsertBaseline(ctx, b)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Alias, err))
+			continue
+		}
+		if b.Regression {
+			a.notifyRegression(ctx, m, b)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		a.L.Error().Err(err).Time("week", prev).Msg("error comparing latency baselines")
+		return
+	}
+	a.baselinesDone = prev
+}
+
+// baselineStored reports whether the baseline of alias for the week starting at week is stored
+func (a *app) baselineStored(ctx context.Context, alias string, week time.Time) (bool, error) {
+	baselines, err := a.store.Baselines(ctx, alias, week)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range baselines {
+		if b.Week.Equal(week) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *app) notifyRegression(ctx context.Context, m metric, b sla.Baseline) {
+	if a.notifier == nil {
+		return
+	}
+	err := a.notifier.Notify(ctx, notify.Event{
+		Kind:     notify.KindRegression,
+		Time:     b.Week,
+		Alias:    m.Alias,
+		Metric:   m.Metric,
+		SLO:      m.SLO,
+		Value:    b.P50,
+		Baseline: b.BaselineP50,
+		Labels:   m.Labels,
+	})
+	if err != nil {
+		a.L.Error().Err(err).Str("alias", m.Alias).Msg("error sending regression notification")
+	}
+}
+
+// handleBaseline serves GET /admin/baseline[?alias=][&week=YYYY-MM-DD], comparing the latency of
+// the week of the given day, the previous week by default, with the weeks before it
+func (a *app) handleBaseline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	from := weekStart(time.Now()).Add(-week)
+	if s := q.Get("week"); s != "" {
+		day, err := time.Parse(baselineWeek, s)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "week must be formatted as YYYY-MM-DD"})
+			return
+		}
+		from = weekStart(day)
+	}
+	var metrics []metric
+	if alias := q.Get("alias"); alias != "" {
+		m, ok := a.metricByAlias(alias)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+			return
+		}
+		metrics = []metric{m}
+	} else {
+		for _, m := range a.Metrics {
+			if m.Baseline {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	baselines := make([]sla.Baseline, 0, len(metrics))
+	for _, m := range metrics {
+		b, err := a.compareBaseline(r.Context(), m, from)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		baselines = append(baselines, b)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"week": from, "baselines": baselines})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultBudgetTarget is the fraction of records of a metric that must meet the objective
+// when the metric does not set budget_target
+const defaultBudgetTarget = 0.99
+
+// budget is the error budget of a metric over the gate window
+type budget struct {
+	Alias  string  `json:"alias"`
+	Window string  `json:"window"`
+	Target float64 `json:"target"`
+	Total  int64   `json:"records"`
+	Met    int64   `json:"met"`
+	// Remaining is the fraction of the error budget left, negative when overspent
+	Remaining float64 `json:"remaining"`
+	Threshold float64 `json:"threshold"`
+	Open      bool    `json:"open"`
+}
+
+// apiHandler serves the unauthenticated read-only API used by CI/CD pipelines
+//
+//	GET /api/gate/{alias}    200 if the remaining error budget of alias exceeds GATE_THRESHOLD, 409 otherwise
+func (a *app) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/gate/", a.handleGate)
+	return mux
+}
+
+func (a *app) handleGate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	m, ok := a.metricByAlias(strings.TrimPrefix(r.URL.Path, "/api/gate/"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+		return
+	}
+	b, err := a.budget(r.Context(), m)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if !b.Open {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, b)
+}
+
+// budget computes the error budget left for m over the gate window
+func (a *app) budget(ctx context.Context, m metric) (budget, error) {
+	target := m.BudgetTarget
+	if target <= 0 || target >= 1 {
+		target = defaultBudgetTarget
+	}
+	b := budget{
+		Alias:     m.Alias,
+		Window:    a.Cfg.GateWindow.String(),
+		Target:    target,
+		Threshold: a.Cfg.GateThreshold,
+	}
+	met, total, err := a.store.Counts(ctx, m.Alias, time.Now().Add(-a.Cfg.GateWindow))
+	if err != nil {
+		return b, err
+	}
+	b.Met, b.Total = met, total
+	// without records nothing was spent
+	b.Remaining = 1
+	if total > 0 {
+		allowed := (1 - target) * float64(total)
+		b.Remaining = 1 - float64(total-met)/allowed
+	}
+	b.Open = b.Remaining > b.Threshold
+	return b, nil
+}
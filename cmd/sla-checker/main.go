@@ -2,59 +2,114 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/caarlos0/env/v9"
-	"github.com/jackc/pgx/v5/pgxpool"
-	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/m7shapan/njson"
-	"github.com/pressly/goose/v3"
 	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
 
-	"github.com/lordvidex/oncall-go-client/migrations"
+	"github.com/lordvidex/oncall-go-client/internal/exitcode"
+	"github.com/lordvidex/oncall-go-client/internal/notify"
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+	"github.com/lordvidex/oncall-go-client/internal/sla"
 )
 
 type config struct {
-	DatabaseURL    string `env:"DATABASE_URL,notEmpty,unset"`
-	PromURL        string `env:"PROMETHEUS_URL" envDefault:"http://oncall-prometheus:9090"`
-	ScrapeInterval string `env:"SCRAPE_INTERVAL" envDefault:"1m"`
-	LogLevel       string `env:"LOG_LEVEL"                   envDefault:"info"`
-	MetricsFile    string `env:"METRICS_FILE,notEmpty"`
+	DatabaseURL    string        `env:"DATABASE_URL,notEmpty,unset"`
+	PromURL        string        `env:"PROMETHEUS_URL" envDefault:"http://oncall-prometheus:9090"`
+	ScrapeInterval string        `env:"SCRAPE_INTERVAL" envDefault:"1m"`
+	LogLevel       string        `env:"LOG_LEVEL"                   envDefault:"info"`
+	MetricsFile    string        `env:"METRICS_FILE,notEmpty"`
+	AdminAddr      string        `env:"ADMIN_ADDR"`
+	AdminToken     string        `env:"ADMIN_TOKEN,unset" json:"-"`
+	TemplatesDir   string        `env:"NOTIFY_TEMPLATES_DIR"`
+	OncallURL      string        `env:"ONCALL_URL"`
+	OncallCA       string        `env:"ONCALL_TLS_CA"`
+	OncallCert     string        `env:"ONCALL_TLS_CERT"`
+	OncallKey      string        `env:"ONCALL_TLS_KEY"`
+	OncallInsecure bool          `env:"ONCALL_TLS_INSECURE"`
+	OncallProxy    string        `env:"ONCALL_PROXY"`
+	APIAddr        string        `env:"API_ADDR"`
+	GateWindow     time.Duration `env:"GATE_WINDOW"    envDefault:"720h"`
+	GateThreshold  float64       `env:"GATE_THRESHOLD" envDefault:"0.1"`
+	// PushgatewayURL enables the dual-write of evaluations to a Pushgateway next to Postgres
+	PushgatewayURL string `env:"PUSHGATEWAY_URL"`
+	PushgatewayJob string `env:"PUSHGATEWAY_JOB" envDefault:"sla_checker"`
+	// StatementDir enables the monthly SLA statements, written as <month>/<owner>/<alias>.html
+	StatementDir string `env:"STATEMENT_DIR"`
+	// StatementPDFCommand converts a statement to PDF, e.g. "wkhtmltopdf {html} {pdf}"
+	StatementPDFCommand string `env:"STATEMENT_PDF_COMMAND"`
+	// StatementUploadCommand publishes each statement file, e.g. "aws s3 cp {file} s3://bucket/sla/"
+	StatementUploadCommand string `env:"STATEMENT_UPLOAD_COMMAND"`
+	// StatementSignatories are the roles of the signatures block, e.g. "Service owner,Customer"
+	StatementSignatories []string `env:"STATEMENT_SIGNATORIES" envSeparator:","`
+	// BaselineAlpha is the significance level of the weekly latency regression test
+	BaselineAlpha float64 `env:"BASELINE_ALPHA" envDefault:"0.01"`
+	// BaselineMinIncrease is the relative increase of the median latency a regression needs
+	BaselineMinIncrease float64 `env:"BASELINE_MIN_INCREASE" envDefault:"0.1"`
+	// BreachOnCallRoles are the roles of the users on call for the team of a breached metric
+	// named in its notification
+	BreachOnCallRoles []string `env:"BREACH_ONCALL_ROLES" envSeparator:"," envDefault:"primary,secondary"`
+	// Once evaluates the metrics a single time and exits with a code classifying the failures
+	Once bool `env:"RUN_ONCE"`
 }
 
-func (a *app) promFetch(ctx context.Context, query string, defaultSLI float64) (value float64, err error) {
+// queryKey identifies a PromQL evaluation within a single tick
+type queryKey struct {
+	query  string
+	bucket int64
+}
+
+type queryResult struct {
+	value float64
+	err   error
+}
+
+// evaluate fetches query at the given time, reusing the result of an identical query
+// in the same time bucket instead of asking Prometheus again
+func (a *app) evaluate(ctx context.Context, query string, at time.Time) (float64, error) {
+	key := queryKey{query: query, bucket: at.Unix()}
+	if res, ok := a.cache[key]; ok {
+		return res.value, res.err
+	}
+	v, err := a.promFetch(ctx, query, at)
+	a.cache[key] = queryResult{value: v, err: err}
+	return v, err
+}
+
+func (a *app) promFetch(ctx context.Context, query string, at time.Time) (value float64, err error) {
 	queryParams := url.Values{
 		"query": []string{query},
-		"time":  []string{strconv.FormatInt(time.Now().Unix(), 10)},
+		"time":  []string{strconv.FormatInt(at.Unix(), 10)},
 	}
 	endpoint, err := url.JoinPath(a.Cfg.PromURL, "api/v1/query")
 	if err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 	endpoint = endpoint + "?" + queryParams.Encode()
 	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 	res, err := a.HTTPClient.Do(req)
 	if err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 	defer res.Body.Close()
 
 	bytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 
 	var result = struct {
@@ -63,14 +118,14 @@ func (a *app) promFetch(ctx context.Context, query string, defaultSLI float64) (
 		Value: "",
 	}
 	if err = njson.Unmarshal(bytes, &result); err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 	if result.Value == "" {
-		return defaultSLI, errors.New("empty response")
+		return 0, errors.New("empty response")
 	}
 	f, err := strconv.ParseFloat(result.Value, 64)
 	if err != nil {
-		return defaultSLI, err
+		return 0, err
 	}
 	return f, nil
 }
@@ -78,9 +133,30 @@ func (a *app) promFetch(ctx context.Context, query string, defaultSLI float64) (
 type app struct {
 	L          *zerolog.Logger
 	HTTPClient *http.Client
-	pool       *pgxpool.Pool
+	store      *sla.Store
 	Cfg        config
 	Metrics    []metric `yaml:"metrics"`
+	notifier   notify.Notifier
+	// oncall receives the SLA status of the metrics owned by a team, nil unless ONCALL_URL is set
+	oncall oncall.API
+
+	// interval is the time between evaluations, also used as the cache bucket size
+	interval time.Duration
+	// mu serializes evaluations of the ticker and the admin API, which share the cache
+	mu    sync.Mutex
+	cache map[queryKey]queryResult
+	// latest holds the last evaluation of each metric by alias
+	latest map[string]verdict
+	// onCall caches the responders of each team for the current tick
+	onCall map[string][]notify.Responder
+	// dual also pushes the evaluations to a Pushgateway, nil unless PUSHGATEWAY_URL is set
+	dual *dualWriter
+	// statementsDone is the month of the last monthly statements written
+	statementsDone time.Time
+	// baselinesDone is the week of the last latency baselines stored
+	baselinesDone time.Time
+	// fetchErrors counts the metrics whose evaluation failed since the last runOnce
+	fetchErrors int
 }
 
 type metric struct {
@@ -89,83 +165,237 @@ type metric struct {
 	SLO        float64 `yaml:"slo"`
 	DefaultSLI float64 `yaml:"default_value"`
 	LessThan   bool    `yaml:"less_than"`
+	// Labels are passed to notification templates, e.g. runbook links or owners
+	Labels map[string]string `yaml:"labels"`
+	// Team is the oncall team owning this metric: its description shows the status of the
+	// metric and the breach notifications name its on-call users
+	Team string `yaml:"team"`
+	// BudgetTarget is the fraction of records that must meet the objective, used by the deployment gate
+	BudgetTarget float64 `yaml:"budget_target"`
+	// Owner is named on the monthly statements, Team if empty
+	Owner string `yaml:"owner"`
+	// Maintenance windows are excluded from the availability of the monthly statements
+	Maintenance []maintenanceWindow `yaml:"maintenance"`
+	// Annotations are listed in the monthly statement of their month
+	Annotations []annotation `yaml:"annotations"`
+	// Baseline compares the weekly distribution of this latency metric with the previous weeks
+	Baseline bool `yaml:"baseline"`
+	// Transform computes the SLI from queries instead of Metric, which then defaults to a
+	// description of the transform
+	Transform *transform `yaml:"transform"`
+}
+
+// met reports whether v satisfies the objective of the metric
+func (m metric) met(v float64) bool {
+	if m.LessThan {
+		return v < m.SLO
+	}
+	return v > m.SLO
+}
+
+// sli evaluates m at the given time, falling back to its default value on error
+func (a *app) sli(ctx context.Context, m metric, at time.Time) float64 {
+	v, err := a.evaluateSLI(ctx, m, at)
+	if err != nil {
+		a.fetchErrors++
+		a.L.Error().
+			Err(err).
+			Str("metric", m.Metric).
+			Msg("error fetching metric")
+		return m.DefaultSLI
+	}
+	return v
 }
 
 func (a *app) insertMetrics(ctx context.Context) error {
-	for _, m := range a.Metrics {
-		v, err := a.promFetch(ctx, m.Metric, m.DefaultSLI)
-		logger := a.L.With().Str("metric", m.Metric).Logger()
+	return a.insertMetricsOf(ctx, a.Metrics)
+}
+
+// insertMetricsOf evaluates the given metrics now and stores the results
+func (a *app) insertMetricsOf(ctx context.Context, metrics []metric) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// all metrics of a tick are evaluated at the same instant so that shared queries hit the cache
+	at := time.Now().Truncate(a.interval)
+	a.cache = make(map[queryKey]queryResult)
+	a.onCall = make(map[string][]notify.Responder)
+	for _, m := range metrics {
+		v := a.sli(ctx, m, at)
+		met := m.met(v)
+		err := a.store.Insert(ctx, m.Alias, m.Metric, m.SLO, v, met)
 		if err != nil {
-			logger.Error().
-				Err(err).
-				Msg("error fetching metric")
+			a.L.Error().Err(err).Str("metric", m.Metric).Msg("error inserting to db")
+			return err
 		}
-		var met bool
-		if m.LessThan {
-			met = v < m.SLO
-		} else {
-			met = v > m.SLO
+		a.latest[m.Alias] = verdict{at: at, value: v, met: met}
+		if a.dual != nil {
+			a.dual.observe(m, a.latest[m.Alias])
 		}
-		err = a.insertDB(ctx, m.Alias, m.Metric, m.SLO, v, met)
-		if err != nil {
-			logger.Error().Err(err).Msg("error inserting to db")
-			return err
+		if !met {
+			a.notifyBreach(ctx, m, v, at)
+		}
+	}
+	if a.dual != nil {
+		if err := a.dual.push(ctx); err != nil {
+			// Postgres stays the source of truth while migrating
+			a.L.Error().Err(err).Msg("error pushing to pushgateway")
 		}
 	}
 	return nil
 }
 
-func (a *app) insertDB(ctx context.Context, alias, metric string, slo, value float64, slaMet bool) error {
-	_, err := a.pool.Exec(
-		ctx,
-		`INSERT INTO sla_record (alias, metric, slo, value, met) 
-VALUES ($1, $2, $3, $4, $5)`,
-		alias,
-		metric,
-		slo,
-		value,
-		slaMet,
-	)
-	return err
+func (a *app) notifyBreach(ctx context.Context, m metric, v float64, at time.Time) {
+	if a.notifier == nil {
+		return
+	}
+	err := a.notifier.Notify(ctx, notify.Event{
+		Kind:   notify.KindBreach,
+		Time:   at,
+		Alias:  m.Alias,
+		Metric: m.Metric,
+		SLO:    m.SLO,
+		Value:  v,
+		Team:   m.Team,
+		OnCall: a.responders(ctx, m.Team),
+		Labels: m.Labels,
+	})
+	if err != nil {
+		a.L.Error().Err(err).Str("alias", m.Alias).Msg("error sending breach notification")
+	}
 }
 
 func (a *app) loadMetrics() error {
 	f, err := os.Open(a.Cfg.MetricsFile)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.Config, err)
 	}
 	defer f.Close()
 	if err = yaml.NewDecoder(f).Decode(a); err != nil {
-		return err
+		return exitcode.Wrap(exitcode.Config, err)
 	}
 	if len(a.Metrics) == 0 {
-		return errors.New("no metrics loaded")
+		return exitcode.Wrap(exitcode.Validation, errors.New("no metrics loaded"))
 	}
+	seen := make(map[string]bool)
 	for i := 0; i < len(a.Metrics); i++ {
 		a.Metrics[i].Metric = strings.TrimSpace(a.Metrics[i].Metric)
+		if t := a.Metrics[i].Transform; t != nil {
+			if err = t.validate(); err != nil {
+				return exitcode.Wrap(exitcode.Validation, fmt.Errorf("metric %s: %w", a.Metrics[i].Alias, err))
+			}
+			if a.Metrics[i].Metric == "" {
+				a.Metrics[i].Metric = t.String()
+			}
+		}
+		m := a.Metrics[i]
+		switch {
+		case m.Alias == "" || m.Metric == "":
+			return exitcode.Wrap(exitcode.Validation, fmt.Errorf("metric #%d: alias and metric or transform are required", i))
+		case seen[m.Alias]:
+			return exitcode.Wrap(exitcode.Validation, fmt.Errorf("metric %s: duplicate alias", m.Alias))
+		}
+		seen[m.Alias] = true
 	}
 	return nil
 }
 
-func (a *app) Start(ctx context.Context) error {
-	if err := a.runMigrations(); err != nil {
-		return err
+// runOnce evaluates and stores the metrics a single time and publishes their status.
+// Metrics that could not be evaluated make the run partial, or unreachable if none could.
+func (a *app) runOnce(ctx context.Context) error {
+	a.fetchErrors = 0
+	if err := a.insertMetrics(ctx); err != nil {
+		return exitcode.Wrap(exitcode.Unreachable, err)
 	}
+	if err := a.publishStatus(ctx); err != nil {
+		if exitcode.Of(err) == exitcode.Auth {
+			return err
+		}
+		return exitcode.Wrap(exitcode.Partial, err)
+	}
+	switch {
+	case a.fetchErrors == len(a.Metrics):
+		return exitcode.Wrap(exitcode.Unreachable, errors.New("no metric could be evaluated"))
+	case a.fetchErrors > 0:
+		return exitcode.Wrap(exitcode.Partial, fmt.Errorf("%d of %d metrics could not be evaluated", a.fetchErrors, len(a.Metrics)))
+	}
+	a.L.Info().Int("metrics", len(a.Metrics)).Msg("metrics evaluated")
+	return nil
+}
 
+func (a *app) Start(ctx context.Context) error {
 	dur, err := time.ParseDuration(a.Cfg.ScrapeInterval)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.Config, err)
 	}
+	a.interval = dur
 
 	if err = a.loadMetrics(); err != nil {
 		return err
 	}
 
-	pool, err := pgxpool.New(ctx, a.Cfg.DatabaseURL)
+	templates, err := notify.LoadTemplates(a.Cfg.TemplatesDir)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.Config, err)
+	}
+
+	if err = sla.Migrate(a.Cfg.DatabaseURL); err != nil {
+		return exitcode.Wrap(exitcode.Unreachable, err)
+	}
+	a.notifier = &notify.LogNotifier{L: *a.L, Templates: templates}
+
+	store, err := sla.New(ctx, a.Cfg.DatabaseURL)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Unreachable, err)
+	}
+	defer store.Close()
+	a.store = store
+
+	if a.Cfg.PushgatewayURL != "" {
+		a.dual = newDualWriter(a.Cfg.PushgatewayURL, a.Cfg.PushgatewayJob, a.HTTPClient)
+	}
+
+	if a.Cfg.OncallURL != "" {
+		opts := append(
+			[]oncall.Option{oncall.WithURL(a.Cfg.OncallURL), oncall.WithLogger(*a.L)},
+			oncall.TLSOptions(a.Cfg.OncallCA, a.Cfg.OncallCert, a.Cfg.OncallKey, a.Cfg.OncallInsecure)...,
+		)
+		if a.Cfg.OncallProxy != "" {
+			opts = append(opts, oncall.WithProxy(a.Cfg.OncallProxy))
+		}
+		cl, err := oncall.New(opts...)
+		if err != nil {
+			return exitcode.Wrap(exitcode.Config, err)
+		}
+		a.oncall = cl
+	}
+
+	if a.Cfg.Once {
+		return a.runOnce(ctx)
+	}
+
+	if a.Cfg.AdminAddr != "" {
+		if a.Cfg.AdminToken == "" {
+			return exitcode.Wrap(exitcode.Config, errors.New("ADMIN_TOKEN must be set to enable the admin API"))
+		}
+		srv := &http.Server{Addr: a.Cfg.AdminAddr, Handler: a.adminHandler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.L.Error().Err(err).Msg("admin server stopped")
+			}
+		}()
+		defer srv.Close()
+	}
+
+	if a.Cfg.APIAddr != "" {
+		srv := &http.Server{Addr: a.Cfg.APIAddr, Handler: a.apiHandler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.L.Error().Err(err).Msg("api server stopped")
+			}
+		}()
+		defer srv.Close()
 	}
-	a.pool = pool
 
 	ticker := time.NewTicker(dur)
 
@@ -177,34 +407,19 @@ func (a *app) Start(ctx context.Context) error {
 			if err = a.insertMetrics(ctx); err != nil {
 				a.L.Error().Err(err).Msg("error inserting metrics")
 			}
+			_ = a.publishStatus(ctx)
+			a.monthlyStatements(ctx, time.Now())
+			a.weeklyBaselines(ctx, time.Now())
 		}
 	}
 
 }
 
-func (a *app) runMigrations() error {
-	goose.SetBaseFS(migrations.FS)
-	if err := goose.SetDialect("pgx"); err != nil {
-		return err
-	}
-	db, err := sql.Open("pgx", a.Cfg.DatabaseURL)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = db.Close()
-	}()
-
-	if err = goose.Up(db, "."); err != nil {
-		return err
-	}
-	return nil
-}
-
 func main() {
+	logger := zerolog.New(zerolog.NewConsoleWriter())
 	var cfg config
 	if err := env.Parse(&cfg); err != nil {
-		log.Fatal(err)
+		exitcode.Fatal(logger, exitcode.Wrap(exitcode.Config, err), "invalid environment")
 	}
 
 	lvl, err := zerolog.ParseLevel(cfg.LogLevel)
@@ -212,7 +427,6 @@ func main() {
 		lvl = zerolog.InfoLevel
 	}
 	zerolog.SetGlobalLevel(lvl)
-	logger := zerolog.New(zerolog.NewConsoleWriter())
 
 	logger.Debug().Interface("config", cfg).Send()
 
@@ -223,8 +437,9 @@ func main() {
 		Cfg:        cfg,
 		L:          &logger,
 		HTTPClient: http.DefaultClient,
+		latest:     make(map[string]verdict),
 	}
 	if err := app.Start(ctx); err != nil {
-		logger.Fatal().Err(err).Msg("app is stopping")
+		exitcode.Fatal(logger, err, "app is stopping")
 	}
 }
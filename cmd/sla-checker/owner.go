@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"github.com/lordvidex/oncall-go-client/internal/notify"
+)
+
+// responders returns the users on call for team in the BREACH_ONCALL_ROLES roles, in the
+// order of the roles. The lookups are cached for the current tick, and a team that cannot be
+// looked up has no responders rather than holding the notification back.
+func (a *app) responders(ctx context.Context, team string) []notify.Responder {
+	if a.oncall == nil || team == "" {
+		return nil
+	}
+	if r, ok := a.onCall[team]; ok {
+		return r
+	}
+	var responders []notify.Responder
+	defer func() { a.onCall[team] = responders }()
+
+	res, err := a.oncall.GetSummary(ctx, team)
+	if err != nil {
+		a.L.Error().Err(err).Str("team", team).Msg("error looking up on-call users")
+		return nil
+	}
+	for _, role := range a.Cfg.BreachOnCallRoles {
+		for _, s := range res.Data.Current[role] {
+			r := notify.Responder{User: s.User, FullName: s.FullName, Role: role}
+			if u, err := a.oncall.GetUser(ctx, s.User); err != nil {
+				a.L.Warn().Err(err).Str("user", s.User).Msg("error looking up on-call user contacts")
+			} else {
+				r.Email, r.Phone, r.Slack = u.Data.Contacts.Email, u.Data.Contacts.Call, u.Data.Contacts.Slack
+			}
+			responders = append(responders, r)
+		}
+	}
+	return responders
+}
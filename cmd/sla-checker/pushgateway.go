@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// discrepancyValue is reported when the pushed value differs from the recorded one
+const discrepancyValue = "value_mismatch"
+
+// pushedValueQuery reads back the SLI pushed for an alias, as scraped from the Pushgateway
+const pushedValueQuery = `last_over_time(sla_checker_value{alias=%q}[%s])`
+
+// dualWriter pushes every evaluation to a Pushgateway next to the Postgres records, so both
+// storages can run in parallel while migrating and be compared with /admin/consistency
+type dualWriter struct {
+	pusher *push.Pusher
+	value  *prometheus.GaugeVec
+	slo    *prometheus.GaugeVec
+	met    *prometheus.GaugeVec
+	at     *prometheus.GaugeVec
+}
+
+func newDualWriter(url, job string, client *http.Client) *dualWriter {
+	d := &dualWriter{
+		value: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sla_checker_value",
+			Help: "Last evaluated value of an SLI",
+		}, []string{"alias", "metric"}),
+		slo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sla_checker_slo",
+			Help: "Objective of an SLI",
+		}, []string{"alias"}),
+		met: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sla_checker_met",
+			Help: "1 if the last evaluation of an SLI met its objective, 0 otherwise",
+		}, []string{"alias"}),
+		at: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sla_checker_evaluation_timestamp_seconds",
+			Help: "Time of the last evaluation of an SLI",
+		}, []string{"alias"}),
+	}
+	d.pusher = push.New(url, job).
+		Client(client).
+		Collector(d.value).
+		Collector(d.slo).
+		Collector(d.met).
+		Collector(d.at)
+	return d
+}
+
+// observe sets the gauges of m, they are sent on the next push
+func (d *dualWriter) observe(m metric, v verdict) {
+	d.value.WithLabelValues(m.Alias, m.Metric).Set(v.value)
+	d.slo.WithLabelValues(m.Alias).Set(m.SLO)
+	d.met.WithLabelValues(m.Alias).Set(boolToFloat(v.met))
+	d.at.WithLabelValues(m.Alias).Set(float64(v.at.Unix()))
+}
+
+// push replaces the group of the checker on the Pushgateway with the current gauges
+func (d *dualWriter) push(ctx context.Context) error {
+	return d.pusher.PushContext(ctx)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// handleConsistency serves GET /admin/consistency?alias=&from=&to=, comparing each record
+// stored in Postgres with the value pushed to the Pushgateway for the same evaluation
+func (a *app) handleConsistency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.dual == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "PUSHGATEWAY_URL is not set"})
+		return
+	}
+	q := r.URL.Query()
+	m, ok := a.metricByAlias(q.Get("alias"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+		return
+	}
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be an RFC3339 time"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil || to.Before(from) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be an RFC3339 time after from"})
+		return
+	}
+
+	rep, err := a.consistency(r.Context(), m, from, to)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// consistency reads back the pushed value of every record of m in [from, to]. The pushed series
+// is looked up one interval after the record, once Prometheus scraped the Pushgateway.
+func (a *app) consistency(ctx context.Context, m metric, from, to time.Time) (*report, error) {
+	records, err := a.store.Records(ctx, m.Alias, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) >= maxRecomputePoints {
+		return nil, fmt.Errorf("%d records, narrow the range", len(records))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache = make(map[queryKey]queryResult)
+
+	query := fmt.Sprintf(pushedValueQuery, m.Alias, a.interval)
+	rep := &report{Alias: m.Alias, From: from, To: to, Step: a.interval.String()}
+	var pushedMet, pushedTotal, recMet int
+	for _, rec := range records {
+		p := reportPoint{Time: rec.Time, Recorded: &rec.Value, RecordedMet: &rec.Met}
+		if rec.Met {
+			recMet++
+		}
+		if v, err := a.evaluate(ctx, query, rec.Time.Add(a.interval)); err == nil {
+			met := m.met(v)
+			p.PromValue, p.PromMet = &v, &met
+			pushedTotal++
+			if met {
+				pushedMet++
+			}
+		}
+		switch {
+		case p.PromMet == nil:
+			p.Discrepancy = discrepancyPrometheusError
+		case *p.PromMet != rec.Met:
+			p.Discrepancy = discrepancyVerdict
+		case !sameValue(*p.PromValue, rec.Value):
+			p.Discrepancy = discrepancyValue
+		}
+		if p.Discrepancy != "" {
+			rep.Discrepancies++
+		}
+		rep.Points = append(rep.Points, p)
+	}
+	if pushedTotal > 0 {
+		rep.PromCompliance = float64(pushedMet) / float64(pushedTotal)
+	}
+	if len(records) > 0 {
+		rep.RecordedCompliance = float64(recMet) / float64(len(records))
+	}
+	return rep, nil
+}
+
+// sameValue compares a pushed value with a recorded one, which Postgres stores as a real
+func sameValue(pushed, recorded float64) bool {
+	return math.Abs(pushed-recorded) <= 1e-4*math.Max(1, math.Abs(recorded))
+}
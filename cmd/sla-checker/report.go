@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/sla"
+)
+
+// Discrepancies between Prometheus history and the stored records
+const (
+	discrepancyMissingRecord   = "missing_record"
+	discrepancyPrometheusError = "prometheus_error"
+	discrepancyVerdict         = "verdict_mismatch"
+)
+
+// reportPoint compares the evaluation of a metric from Prometheus history at an instant
+// with the record stored by the checker closest to that instant
+type reportPoint struct {
+	Time        time.Time `json:"time"`
+	PromValue   *float64  `json:"prometheus_value,omitempty"`
+	PromMet     *bool     `json:"prometheus_met,omitempty"`
+	Recorded    *float64  `json:"recorded_value,omitempty"`
+	RecordedMet *bool     `json:"recorded_met,omitempty"`
+	Discrepancy string    `json:"discrepancy,omitempty"`
+}
+
+type report struct {
+	Alias string    `json:"alias"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Step  string    `json:"step"`
+	// PromCompliance and RecordedCompliance are the fractions of points meeting the objective
+	PromCompliance     float64       `json:"prometheus_compliance"`
+	RecordedCompliance float64       `json:"recorded_compliance"`
+	Discrepancies      int           `json:"discrepancies"`
+	Points             []reportPoint `json:"points"`
+}
+
+// handleReport serves GET /admin/report?alias=&from=&to=[&step=], evaluating the metric at
+// every step directly from Prometheus and reconciling it with the stored records
+func (a *app) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	q := r.URL.Query()
+	m, ok := a.metricByAlias(q.Get("alias"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+		return
+	}
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be an RFC3339 time"})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil || to.Before(from) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to must be an RFC3339 time after from"})
+		return
+	}
+	step := time.Hour
+	if s := q.Get("step"); s != "" {
+		if step, err = time.ParseDuration(s); err != nil || step <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "step must be a positive duration"})
+			return
+		}
+	}
+	if int64(to.Sub(from)/step) >= maxRecomputePoints {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "too many points, increase step"})
+		return
+	}
+
+	rep, err := a.timeTravel(r.Context(), m, from, to, step)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func (a *app) timeTravel(ctx context.Context, m metric, from, to time.Time, step time.Duration) (*report, error) {
+	// records up to half a step around the range can match its first and last points
+	records, err := a.store.Records(ctx, m.Alias, from.Add(-step/2), to.Add(step/2))
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache = make(map[queryKey]queryResult)
+
+	rep := &report{Alias: m.Alias, From: from, To: to, Step: step.String()}
+	var promMet, promTotal, recMet, recTotal int
+	for at := from; !at.After(to); at = at.Add(step) {
+		p := reportPoint{Time: at}
+		if v, err := a.evaluateSLI(ctx, m, at); err == nil {
+			met := m.met(v)
+			p.PromValue, p.PromMet = &v, &met
+			promTotal++
+			if met {
+				promMet++
+			}
+		}
+		if rec, ok := closestRecord(records, at, step/2); ok {
+			p.Recorded, p.RecordedMet = &rec.Value, &rec.Met
+			recTotal++
+			if rec.Met {
+				recMet++
+			}
+		}
+		switch {
+		case p.PromMet == nil:
+			p.Discrepancy = discrepancyPrometheusError
+		case p.RecordedMet == nil:
+			p.Discrepancy = discrepancyMissingRecord
+		case *p.PromMet != *p.RecordedMet:
+			p.Discrepancy = discrepancyVerdict
+		}
+		if p.Discrepancy != "" {
+			rep.Discrepancies++
+		}
+		rep.Points = append(rep.Points, p)
+	}
+	if promTotal > 0 {
+		rep.PromCompliance = float64(promMet) / float64(promTotal)
+	}
+	if recTotal > 0 {
+		rep.RecordedCompliance = float64(recMet) / float64(recTotal)
+	}
+	return rep, nil
+}
+
+// closestRecord returns the record nearest to at within maxDistance. records are sorted by time.
+func closestRecord(records []sla.Record, at time.Time, maxDistance time.Duration) (sla.Record, bool) {
+	i := sort.Search(len(records), func(i int) bool { return !records[i].Time.Before(at) })
+	best, found := sla.Record{}, false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(records) {
+			continue
+		}
+		d := records[j].Time.Sub(at).Abs()
+		if d <= maxDistance && (!found || d < best.Time.Sub(at).Abs()) {
+			best, found = records[j], true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/sla"
+)
+
+// statementMonth is the layout of the month of a statement, e.g. 2026-09
+const statementMonth = "2006-01"
+
+//go:embed statement.html
+var statementHTML string
+
+var statementTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.3f%%", f*100) },
+	"time":    func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+}).Parse(statementHTML))
+
+// maintenanceWindow is a planned interruption, records within it are excluded from statements
+// and latency baselines
+type maintenanceWindow struct {
+	From   time.Time `yaml:"from"   json:"from"`
+	To     time.Time `yaml:"to"     json:"to"`
+	Reason string    `yaml:"reason" json:"reason"`
+}
+
+func (w maintenanceWindow) contains(t time.Time) bool {
+	return !t.Before(w.From) && !t.After(w.To)
+}
+
+// inMaintenance reports whether t falls in one of windows
+func inMaintenance(windows []maintenanceWindow, t time.Time) bool {
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotation is a note shown in the statements of the month it falls in, e.g. an incident link
+type annotation struct {
+	Time time.Time `yaml:"time" json:"time"`
+	Text string    `yaml:"text" json:"text"`
+}
+
+// breach is a run of consecutive records missing the objective
+type breach struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Records int       `json:"records"`
+	// Worst is the value furthest from the objective during the breach
+	Worst float64 `json:"worst"`
+}
+
+// statement is the monthly SLA statement of a metric
+type statement struct {
+	Alias     string    `json:"alias"`
+	Metric    string    `json:"metric"`
+	Owner     string    `json:"owner"`
+	Month     string    `json:"month"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Objective string    `json:"objective"`
+	Target    float64   `json:"target"`
+	Records   int       `json:"records"`
+	Excluded  int       `json:"excluded"`
+	Met       int       `json:"met"`
+	// Availability is the fraction of the records outside maintenance meeting the objective
+	Availability float64             `json:"availability"`
+	Compliant    bool                `json:"compliant"`
+	Breaches     []breach            `json:"breaches"`
+	Maintenance  []maintenanceWindow `json:"maintenance"`
+	Annotations  []annotation        `json:"annotations"`
+	Signatories  []string            `json:"signatories"`
+	GeneratedAt  time.Time           `json:"generated_at"`
+}
+
+// owner is the owner named on the statements of m
+func (m metric) owner() string {
+	switch {
+	case m.Owner != "":
+		return m.Owner
+	case m.Team != "":
+		return m.Team
+	}
+	return "unowned"
+}
+
+// buildStatement summarizes the records of m over the month starting at from
+func (a *app) buildStatement(m metric, from time.Time, records []sla.Record) statement {
+	to := from.AddDate(0, 1, 0)
+	target := m.BudgetTarget
+	if target <= 0 || target >= 1 {
+		target = defaultBudgetTarget
+	}
+	op := ">"
+	if m.LessThan {
+		op = "<"
+	}
+	st := statement{
+		Alias:       m.Alias,
+		Metric:      m.Metric,
+		Owner:       m.owner(),
+		Month:       from.Format(statementMonth),
+		From:        from,
+		To:          to,
+		Objective:   fmt.Sprintf("%s %g", op, m.SLO),
+		Target:      target,
+		Signatories: a.Cfg.StatementSignatories,
+		GeneratedAt: time.Now(),
+	}
+	for _, w := range m.Maintenance {
+		if w.From.Before(to) && w.To.After(from) {
+			st.Maintenance = append(st.Maintenance, w)
+		}
+	}
+	for _, n := range m.Annotations {
+		if !n.Time.Before(from) && n.Time.Before(to) {
+			st.Annotations = append(st.Annotations, n)
+		}
+	}
+
+	var current *breach
+	for _, r := range records {
+		st.Records++
+		if inMaintenance(st.Maintenance, r.Time) {
+			st.Excluded++
+			continue
+		}
+		if r.Met {
+			st.Met++
+			current = nil
+			continue
+		}
+		if current == nil {
+			st.Breaches = append(st.Breaches, breach{Start: r.Time, Worst: r.Value})
+			current = &st.Breaches[len(st.Breaches)-1]
+		}
+		current.End = r.Time
+		current.Records++
+		if (m.LessThan && r.Value > current.Worst) || (!m.LessThan && r.Value < current.Worst) {
+			current.Worst = r.Value
+		}
+	}
+	st.Availability = 1
+	if counted := st.Records - st.Excluded; counted > 0 {
+		st.Availability = float64(st.Met) / float64(counted)
+	}
+	st.Compliant = st.Availability >= target
+	return st
+}
+
+// writeStatements writes the statement of every metric for the month starting at from into
+// STATEMENT_DIR as <month>/<owner>/<alias>.html, converted to PDF and uploaded by the
+// configured commands. Once every step of a metric succeeded, <alias>.done marks it and the
+// statement is kept unless overwrite is set; a statement whose conversion or upload failed
+// is written again by the next call.
+func (a *app) writeStatements(ctx context.Context, metrics []metric, from time.Time, overwrite bool) ([]string, error) {
+	var (
+		files []string
+		errs  []error
+	)
+	for _, m := range metrics {
+		file := filepath.Join(a.Cfg.StatementDir, from.Format(statementMonth), safeName(m.owner()), safeName(m.Alias)+".html")
+		marker := strings.TrimSuffix(file, ".html") + ".done"
+		if _, err := os.Stat(marker); err == nil && !overwrite {
+			continue
+		}
+		if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		written, err := a.writeStatement(ctx, m, from, file)
+		files = append(files, written...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Alias, err))
+			continue
+		}
+		if err = os.WriteFile(marker, nil, 0o644); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		a.L.Info().Str("alias", m.Alias).Strs("files", written).Msg("wrote sla statement")
+	}
+	return files, errors.Join(errs...)
+}
+
+// writeStatement writes the statement of m to file and runs the PDF and upload commands on it,
+// returning the files written
+func (a *app) writeStatement(ctx context.Context, m metric, from time.Time, file string) ([]string, error) {
+	records, err := a.store.Records(ctx, m.Alias, from, from.AddDate(0, 1, 0).Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = statementTemplate.Execute(&buf, a.buildStatement(m, from, records)); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	written := []string{file}
+	var errs []error
+	if a.Cfg.StatementPDFCommand != "" {
+		pdf := strings.TrimSuffix(file, ".html") + ".pdf"
+		if err = runHook(ctx, a.Cfg.StatementPDFCommand, map[string]string{"{html}": file, "{pdf}": pdf}); err != nil {
+			errs = append(errs, fmt.Errorf("rendering pdf: %w", err))
+		} else {
+			written = append(written, pdf)
+		}
+	}
+	if a.Cfg.StatementUploadCommand != "" {
+		for _, f := range written {
+			if err = runHook(ctx, a.Cfg.StatementUploadCommand, map[string]string{"{file}": f}); err != nil {
+				errs = append(errs, fmt.Errorf("uploading %s: %w", f, err))
+			}
+		}
+	}
+	return written, errors.Join(errs...)
+}
+
+// monthlyStatements writes the statements of the previous month once per month
+func (a *app) monthlyStatements(ctx context.Context, now time.Time) {
+	if a.Cfg.StatementDir == "" {
+		return
+	}
+	now = now.UTC()
+	prev := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	if prev.Equal(a.statementsDone) {
+		return
+	}
+	if _, err := a.writeStatements(ctx, a.Metrics, prev, false); err != nil {
+		a.L.Error().Err(err).Str("month", prev.Format(statementMonth)).Msg("error writing sla statements")
+		return
+	}
+	a.statementsDone = prev
+}
+
+// handleStatement serves POST /admin/statement?month=YYYY-MM[&alias=], writing the statements
+// of the month again
+func (a *app) handleStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if a.Cfg.StatementDir == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "STATEMENT_DIR is not set"})
+		return
+	}
+	q := r.URL.Query()
+	from, err := time.Parse(statementMonth, q.Get("month"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "month must be formatted as YYYY-MM"})
+		return
+	}
+	metrics := a.Metrics
+	if alias := q.Get("alias"); alias != "" {
+		m, ok := a.metricByAlias(alias)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown alias"})
+			return
+		}
+		metrics = []metric{m}
+	}
+	files, err := a.writeStatements(r.Context(), metrics, from, true)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "files": files})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"files": files})
+}
+
+// runHook runs command, a space separated program and arguments, after replacing the
+// placeholders of args in each argument
+func runHook(ctx context.Context, command string, args map[string]string) error {
+	fields := strings.Fields(command)
+	for i, f := range fields {
+		for k, v := range args {
+			f = strings.ReplaceAll(f, k, v)
+		}
+		fields[i] = f
+	}
+	out, err := exec.CommandContext(ctx, fields[0], fields[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// safeName makes s usable as a single path element
+func safeName(s string) string {
+	if s == "." || s == ".." {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, s)
+}
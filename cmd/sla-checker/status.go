@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+// statusMarker separates the team's own description from the SLA summary maintained by the checker
+const statusMarker = "--- SLA status ---"
+
+// verdict is the latest evaluation of a metric
+type verdict struct {
+	at    time.Time
+	value float64
+	met   bool
+}
+
+// publishStatus writes the latest verdicts of the metrics owned by each team
+// into the description of that team, replacing the previous summary
+func (a *app) publishStatus(ctx context.Context) error {
+	if a.oncall == nil {
+		return nil
+	}
+	a.mu.Lock()
+	byTeam := make(map[string][]string)
+	for _, m := range a.Metrics {
+		v, ok := a.latest[m.Alias]
+		if m.Team == "" || !ok {
+			continue
+		}
+		state := "OK"
+		if !v.met {
+			state = "BREACHED"
+		}
+		op := ">"
+		if m.LessThan {
+			op = "<"
+		}
+		byTeam[m.Team] = append(byTeam[m.Team], fmt.Sprintf("%s: %s (%g, objective %s %g) at %s",
+			m.Alias, state, v.value, op, m.SLO, v.at.UTC().Format(time.RFC3339)))
+	}
+	a.mu.Unlock()
+
+	var errs []error
+	for team, lines := range byTeam {
+		slices.Sort(lines)
+		if err := a.updateDescription(ctx, team, lines); err != nil {
+			a.L.Error().Err(err).Str("team", team).Msg("error publishing sla status")
+			errs = append(errs, fmt.Errorf("%s: %w", team, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *app) updateDescription(ctx context.Context, team string, lines []string) error {
+	res, err := a.oncall.GetTeam(ctx, team)
+	if err != nil {
+		return err
+	}
+	own, _, _ := strings.Cut(res.Data.Description, statusMarker)
+	desc := strings.TrimRight(own, "\n")
+	if desc != "" {
+		desc += "\n\n"
+	}
+	desc += statusMarker + "\n" + strings.Join(lines, "\n")
+	if desc == res.Data.Description {
+		return nil
+	}
+	_, err = a.oncall.UpdateTeam(ctx, team, oncall.Team{Description: desc})
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// transform computes the SLI of a metric from queries instead of the single instant value of
+// metric. Exactly one of its blocks is set:
+//
+//	transform:
+//	  ratio:
+//	    numerator: sum(rate(http_requests_total{code!~"5.."}[5m]))
+//	    denominator: sum(rate(http_requests_total[5m]))
+//
+//	transform:
+//	  percentile:
+//	    histogram: sum by (le) (rate(http_request_duration_seconds_bucket[5m]))
+//	    quantile: 0.99
+//
+//	transform:
+//	  freshness:
+//	    timestamp: max(last_successful_backup_timestamp_seconds)
+type transform struct {
+	Ratio      *ratioSLI      `yaml:"ratio"`
+	Percentile *percentileSLI `yaml:"percentile"`
+	Freshness  *freshnessSLI  `yaml:"freshness"`
+}
+
+// ratioSLI is the numerator query divided by the denominator query
+type ratioSLI struct {
+	Numerator   string `yaml:"numerator"`
+	Denominator string `yaml:"denominator"`
+}
+
+// percentileSLI is the quantile of the buckets of a histogram, whose query keeps the le label
+type percentileSLI struct {
+	Histogram string  `yaml:"histogram"`
+	Quantile  float64 `yaml:"quantile"`
+}
+
+// freshnessSLI is the age in seconds of the unix timestamp returned by the timestamp query
+type freshnessSLI struct {
+	Timestamp string `yaml:"timestamp"`
+}
+
+// validate checks that a single block is set with its parameters
+func (t *transform) validate() error {
+	var set []string
+	if t.Ratio != nil {
+		set = append(set, "ratio")
+		if strings.TrimSpace(t.Ratio.Numerator) == "" || strings.TrimSpace(t.Ratio.Denominator) == "" {
+			return errors.New("ratio needs a numerator and a denominator")
+		}
+	}
+	if t.Percentile != nil {
+		set = append(set, "percentile")
+		if strings.TrimSpace(t.Percentile.Histogram) == "" {
+			return errors.New("percentile needs a histogram")
+		}
+		if t.Percentile.Quantile <= 0 || t.Percentile.Quantile >= 1 {
+			return fmt.Errorf("percentile quantile %v must be between 0 and 1", t.Percentile.Quantile)
+		}
+	}
+	if t.Freshness != nil {
+		set = append(set, "freshness")
+		if strings.TrimSpace(t.Freshness.Timestamp) == "" {
+			return errors.New("freshness needs a timestamp")
+		}
+	}
+	switch len(set) {
+	case 0:
+		return errors.New("transform needs one of ratio, percentile or freshness")
+	case 1:
+		return nil
+	}
+	return fmt.Errorf("transform sets %s, only one can be set", strings.Join(set, " and "))
+}
+
+// String describes the transform, stored as the metric of the records
+func (t *transform) String() string {
+	switch {
+	case t.Ratio != nil:
+		return fmt.Sprintf("ratio((%s) / (%s))", t.Ratio.Numerator, t.Ratio.Denominator)
+	case t.Percentile != nil:
+		return t.Percentile.query()
+	case t.Freshness != nil:
+		return fmt.Sprintf("freshness(%s)", t.Freshness.Timestamp)
+	}
+	return ""
+}
+
+func (p *percentileSLI) query() string {
+	return fmt.Sprintf("histogram_quantile(%g, %s)", p.Quantile, p.Histogram)
+}
+
+// evaluateSLI evaluates the SLI of m at the given time, through its transform if it has one
+func (a *app) evaluateSLI(ctx context.Context, m metric, at time.Time) (float64, error) {
+	t := m.Transform
+	switch {
+	case t == nil:
+		return a.evaluate(ctx, m.Metric, at)
+	case t.Ratio != nil:
+		num, err := a.evaluate(ctx, t.Ratio.Numerator, at)
+		if err != nil {
+			return 0, fmt.Errorf("numerator: %w", err)
+		}
+		den, err := a.evaluate(ctx, t.Ratio.Denominator, at)
+		if err != nil {
+			return 0, fmt.Errorf("denominator: %w", err)
+		}
+		if den == 0 {
+			return 0, errors.New("denominator is 0")
+		}
+		return num / den, nil
+	case t.Percentile != nil:
+		return a.evaluate(ctx, t.Percentile.query(), at)
+	case t.Freshness != nil:
+		ts, err := a.evaluate(ctx, t.Freshness.Timestamp, at)
+		if err != nil {
+			return 0, err
+		}
+		return float64(at.Unix()) - ts, nil
+	}
+	return 0, errors.New("empty transform")
+}
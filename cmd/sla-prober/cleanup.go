@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+// probeEntity is an entity created on the oncall server by the prober
+type probeEntity struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+}
+
+// pendingCleanup tracks the probe entities that were created but not deleted yet
+type pendingCleanup struct {
+	mu       sync.Mutex
+	entities map[probeEntity]time.Time
+}
+
+func newPendingCleanup() *pendingCleanup {
+	return &pendingCleanup{entities: make(map[probeEntity]time.Time)}
+}
+
+func (p *pendingCleanup) add(e probeEntity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.entities[e]; !ok {
+		p.entities[e] = time.Now()
+	}
+}
+
+func (p *pendingCleanup) remove(e probeEntity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.entities, e)
+}
+
+// leftovers returns the pending entities sorted by kind and name
+func (p *pendingCleanup) leftovers() []probeEntity {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res := make([]probeEntity, 0, len(p.entities))
+	for e := range p.entities {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Kind != res[j].Kind {
+			return res[i].Kind < res[j].Kind
+		}
+		if res[i].Team != res[j].Team {
+			return res[i].Team < res[j].Team
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// track marks everything the server acknowledged in a cycle as pending cleanup
+func (a *app) track(report *oncall.EntityReport) {
+	if report == nil {
+		return
+	}
+	for _, t := range report.Teams {
+		if t.Create.StatusCode != 0 {
+			a.pending.add(probeEntity{Kind: "team", Name: t.Name})
+		}
+		for _, u := range t.Users {
+			if u.Create.StatusCode != 0 {
+				a.pending.add(probeEntity{Kind: "user", Name: u.Name})
+			}
+			if u.AddToTeam.StatusCode != 0 {
+				a.pending.add(probeEntity{Kind: "team_user", Name: u.Name, Team: t.Name})
+			}
+		}
+	}
+}
+
+// cleanup deletes the probe users and their memberships, keeping whatever failed as pending.
+// Probe teams are only deleted with deleteTeams, set when they are named for a single cycle,
+// and stay pending otherwise.
+func (a *app) cleanup(config oncall.Config, deleteTeams bool) {
+	// cleanup also runs once the cycle is cancelled
+	ctx := context.Background()
+	gone := func(err error) bool {
+		return err == nil || errors.Is(err, oncall.ErrNotFound)
+	}
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			if err := a.cl.DeleteUserFromTeam(ctx, u.Name, t.Name); gone(err) {
+				a.pending.remove(probeEntity{Kind: "team_user", Name: u.Name, Team: t.Name})
+			}
+			if err := a.cl.DeleteUser(ctx, u.Name); gone(err) {
+				a.pending.remove(probeEntity{Kind: "user", Name: u.Name})
+			}
+		}
+		if !deleteTeams {
+			continue
+		}
+		if err := a.cl.DeleteTeam(ctx, t.Name); gone(err) {
+			a.pending.remove(probeEntity{Kind: "team", Name: t.Name})
+		}
+	}
+	a.detectLeaks(ctx, config)
+}
+
+// detectLeaks counts the active probe users and team memberships still present on the server
+// after cleanup, deleted users stay as inactive records. Probe teams are kept on purpose and
+// are not leaks.
+func (a *app) detectLeaks(ctx context.Context, config oncall.Config) {
+	// only the users containing the literal prefix of the probe names are listed
+	active := true
+	filter := oncall.UserFilter{Active: &active}
+	filter.NameContains, _ = a.probeNames.LiteralPrefix()
+	users, err := a.cl.GetUsers(ctx, filter)
+	if err != nil {
+		a.logger.Warn().Err(err).Msg("failed to list users for leak detection")
+	} else {
+		var leaked int
+		for _, u := range users.Data {
+			if u.Active && a.probeNames.MatchString(u.Name) {
+				leaked++
+			}
+		}
+		leakedEntities.WithLabelValues("user").Set(float64(leaked))
+	}
+
+	var (
+		leaked int
+		failed bool
+	)
+	for _, t := range config.Teams {
+		team, err := a.cl.GetTeam(ctx, t.Name)
+		if errors.Is(err, oncall.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			a.logger.Warn().Err(err).Str("team", t.Name).Msg("failed to get team for leak detection")
+			failed = true
+			continue
+		}
+		for name := range team.Data.Users {
+			if a.probeNames.MatchString(name) {
+				leaked++
+			}
+		}
+	}
+	if !failed {
+		leakedEntities.WithLabelValues("team_user").Set(float64(leaked))
+	}
+}
+
+// shutdownReport lists the probe entities that may still exist on the oncall server
+type shutdownReport struct {
+	Time      time.Time     `json:"time"`
+	Leftovers []probeEntity `json:"leftovers"`
+}
+
+// report logs the entities left on the server and writes them to reportFile if set
+func (a *app) report() error {
+	r := shutdownReport{Time: time.Now(), Leftovers: a.pending.leftovers()}
+	for _, e := range r.Leftovers {
+		a.logger.Warn().
+			Str("kind", e.Kind).
+			Str("name", e.Name).
+			Str("team", e.Team).
+			Msg("probe entity may still exist on oncall")
+	}
+	a.logger.Info().Int("leftovers", len(r.Leftovers)).Msg("shutdown report")
+	if reportFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportFile, b, 0o644)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+// runIDLayout is the layout of the run IDs, naming the evidence directory of each cycle
+const runIDLayout = "20060102T150405Z"
+
+// evidence is a failed scenario request and what oncall answered to it
+type evidence struct {
+	RunID    string          `json:"run_id"`
+	Scenario string          `json:"scenario"`
+	Time     time.Time       `json:"time"`
+	Request  evidenceMessage `json:"request"`
+	Response evidenceMessage `json:"response"`
+	Latency  time.Duration   `json:"latency_ns"`
+}
+
+// evidenceMessage is a sanitized request or response: credentials are redacted from the
+// headers, contacts from JSON bodies, other bodies are withheld, and the body is truncated
+type evidenceMessage struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+	Truncated  bool        `json:"truncated,omitempty"`
+}
+
+// evidenceStore writes the evidence of each cycle to <dir>/<run id>/, keeping the maxRuns
+// most recent runs
+type evidenceStore struct {
+	dir     string
+	maxRuns int
+	maxBody int
+}
+
+// save writes the evidence of the failed scenario answered with r
+func (s *evidenceStore) save(runID, scenario string, r *oncall.Response[any]) error {
+	run := filepath.Join(s.dir, runID)
+	_, err := os.Stat(run)
+	created := os.IsNotExist(err)
+	if err = os.MkdirAll(run, 0o700); err != nil {
+		return err
+	}
+	e := evidence{
+		RunID:    runID,
+		Scenario: scenario,
+		Time:     time.Now(),
+		Request: evidenceMessage{
+			Method: r.Method,
+			URL:    r.URL,
+			Header: redactHeader(r.RequestHeader),
+		},
+		Response: evidenceMessage{
+			StatusCode: r.StatusCode,
+			Header:     redactHeader(r.Header),
+		},
+		Latency: r.ResponseTime,
+	}
+	e.Request.Body, e.Request.Truncated = s.truncate(redactBody(r.RequestBody))
+	e.Response.Body, e.Response.Truncated = s.truncate(redactBody(r.RawBody))
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(run, scenario+"-"+e.Time.UTC().Format("150405.000000000")+".json")
+	if err = os.WriteFile(file, b, 0o600); err != nil {
+		return err
+	}
+	if created {
+		return s.prune()
+	}
+	return nil
+}
+
+func (s *evidenceStore) truncate(body []byte) (string, bool) {
+	if len(body) > s.maxBody {
+		return string(body[:s.maxBody]), true
+	}
+	return string(body), false
+}
+
+// prune removes the oldest run directories beyond maxRuns
+func (s *evidenceStore) prune() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var runs []string
+	for _, e := range entries {
+		if _, err := time.Parse(runIDLayout, e.Name()); e.IsDir() && err == nil {
+			runs = append(runs, e.Name())
+		}
+	}
+	sort.Strings(runs)
+	for len(runs) > s.maxRuns {
+		if err = os.RemoveAll(filepath.Join(s.dir, runs[0])); err != nil {
+			return err
+		}
+		runs = runs[1:]
+	}
+	return nil
+}
+
+// redactHeader copies h with the values of the headers carrying credentials replaced
+func redactHeader(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		if sensitiveHeader(k) {
+			v = []string{"REDACTED"}
+		}
+		res[k] = v
+	}
+	return res
+}
+
+// contactFields are the keys of the JSON bodies holding contacts or credentials, e.g. of
+// create_user requests and user records
+var contactFields = map[string]bool{
+	"contacts":              true,
+	"call":                  true,
+	"sms":                   true,
+	"email":                 true,
+	"slack":                 true,
+	"phone_number":          true,
+	"override_phone_number": true,
+	"password":              true,
+}
+
+// withheldBody replaces the bodies that cannot be redacted
+const withheldBody = "[body withheld: not valid JSON or truncated]"
+
+// redactBody returns body with the values of its contact fields replaced. Bodies that are not
+// JSON, such as those truncated by the client, may hold contacts and are withheld.
+func redactBody(body []byte) []byte {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body
+	}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil || d.More() {
+		return []byte(withheldBody)
+	}
+	if !redactJSON(v) {
+		return body
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte(withheldBody)
+	}
+	return b
+}
+
+// redactJSON replaces the contact fields of the decoded JSON value v and reports whether
+// there were any
+func redactJSON(v any) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			if contactFields[strings.ToLower(k)] && value != nil {
+				v[k] = "REDACTED"
+				redacted = true
+			} else if redactJSON(value) {
+				redacted = true
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if redactJSON(value) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "cookie", "set-cookie":
+		return true
+	}
+	for _, part := range []string{"auth", "token", "secret", "csrf", "signature", "key"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+var (
+	journeyTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_journey_total",
+		Help: "Total count of runs of a configured journey",
+	}, []string{"journey"})
+	journeySuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_journey_success_total",
+		Help: "Total count of runs of a configured journey where every step passed",
+	}, []string{"journey"})
+	journeyDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_journey_duration_seconds",
+		Help: "Duration of the last run of a configured journey",
+	}, []string{"journey"})
+	journeyStepFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_journey_step_failures_total",
+		Help: "Total count of failed steps of a configured journey",
+	}, []string{"journey", "step"})
+)
+
+// journeyConfig is the file read with -journeys
+type journeyConfig struct {
+	Journeys []journey `yaml:"journeys"`
+}
+
+// journey is an ordered list of requests sharing variables. A journey stops at its first failed step.
+type journey struct {
+	Name  string            `yaml:"name"`
+	Vars  map[string]string `yaml:"vars"`
+	Steps []journeyStep     `yaml:"steps"`
+}
+
+// journeyStep is a single request of a journey. Path and Body are templates executed with the
+// variables of the journey, e.g. "/api/v0/teams/{{.team}}/summary" or
+// "/api/v0/events?team={{.team}}".
+type journeyStep struct {
+	Name   string `yaml:"name"`
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	Body   string `yaml:"body"`
+	// Status is the expected status code, any 2xx status if zero
+	Status int `yaml:"status"`
+	// Extract stores values of the json response as variables, keyed by variable name.
+	// Paths are dot separated keys and indices, e.g. "current.primary.0.user".
+	Extract map[string]string `yaml:"extract"`
+	Assert  []journeyAssert   `yaml:"assert"`
+}
+
+// journeyAssert checks a value of the json response. Equals is a template.
+type journeyAssert struct {
+	Path   string `yaml:"path"`
+	Equals string `yaml:"equals"`
+	// Exists only checks that the path is present
+	Exists bool `yaml:"exists"`
+}
+
+func loadJourneys(filename string) ([]journey, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfg journeyConfig
+	if err = yaml.NewDecoder(f).Decode(&cfg); err != nil {
+		return nil, err
+	}
+	for _, j := range cfg.Journeys {
+		if j.Name == "" {
+			return nil, fmt.Errorf("journey without a name")
+		}
+		for i, s := range j.Steps {
+			if s.Method == "" || s.Path == "" {
+				return nil, fmt.Errorf("journey %s: step %d needs a method and a path", j.Name, i)
+			}
+		}
+	}
+	return cfg.Journeys, nil
+}
+
+// runJourneys runs every configured journey once
+func (a *app) runJourneys(ctx context.Context) {
+	for _, j := range a.journeys {
+		start := time.Now()
+		step, err := a.runJourney(ctx, j)
+		if errors.Is(err, oncall.ErrRateLimited) {
+			a.logger.Info().Err(err).Str("journey", j.Name).Str("step", step).Msg("journey throttled by oncall")
+			scenarioSkipped.WithLabelValues(scenarioJourney, "throttled").Inc()
+			continue
+		}
+		journeyTotal.WithLabelValues(j.Name).Inc()
+		journeyDurationSeconds.WithLabelValues(j.Name).Set(time.Since(start).Seconds())
+		if err != nil {
+			a.logger.Warn().Err(err).Str("journey", j.Name).Str("step", step).Msg("journey failed")
+			journeyStepFailures.WithLabelValues(j.Name, step).Inc()
+			continue
+		}
+		journeySuccess.WithLabelValues(j.Name).Inc()
+	}
+}
+
+// runJourney runs the steps of j in order and returns the name of the step that failed
+func (a *app) runJourney(ctx context.Context, j journey) (string, error) {
+	vars := map[string]string{"now": strconv.FormatInt(time.Now().Unix(), 10)}
+	for k, v := range j.Vars {
+		vars[k] = v
+	}
+	for i, s := range j.Steps {
+		name := s.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		if err := a.runStep(ctx, s, vars); err != nil {
+			return name, err
+		}
+	}
+	return "", nil
+}
+
+func (a *app) runStep(ctx context.Context, s journeyStep, vars map[string]string) error {
+	path, err := render(s.Path, vars)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if s.Body != "" {
+		b, err := render(s.Body, vars)
+		if err != nil {
+			return err
+		}
+		body = []byte(b)
+	}
+
+	res, err := a.cl.Raw(ctx, strings.ToUpper(s.Method), path, body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusTooManyRequests && s.Status != http.StatusTooManyRequests {
+		return fmt.Errorf("%s: %w", path, oncall.ErrRateLimited)
+	}
+	if s.Status != 0 && res.StatusCode != s.Status {
+		return fmt.Errorf("%s: status %d, expected %d", path, res.StatusCode, s.Status)
+	}
+	if s.Status == 0 && (res.StatusCode < 200 || res.StatusCode >= 300) {
+		return fmt.Errorf("%s: status %d", path, res.StatusCode)
+	}
+	if len(s.Extract) == 0 && len(s.Assert) == 0 {
+		return nil
+	}
+
+	var doc any
+	if err = json.Unmarshal(res.Data, &doc); err != nil {
+		return fmt.Errorf("%s: decoding response: %w", path, err)
+	}
+	for name, p := range s.Extract {
+		v, ok := lookup(doc, p)
+		if !ok {
+			return fmt.Errorf("%s: nothing to extract at %s", path, p)
+		}
+		vars[name] = v
+	}
+	for _, as := range s.Assert {
+		v, ok := lookup(doc, as.Path)
+		if !ok {
+			return fmt.Errorf("%s: %s not found", path, as.Path)
+		}
+		if as.Exists {
+			continue
+		}
+		want, err := render(as.Equals, vars)
+		if err != nil {
+			return err
+		}
+		if v != want {
+			return fmt.Errorf("%s: %s is %q, expected %q", path, as.Path, v, want)
+		}
+	}
+	return nil
+}
+
+func render(text string, vars map[string]string) (string, error) {
+	t, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// lookup returns the value at a dot separated path of a decoded json document.
+// Scalars are formatted as in json, objects and arrays are re-encoded.
+func lookup(doc any, path string) (string, bool) {
+	cur := doc
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch v := cur.(type) {
+			case map[string]any:
+				next, ok := v[key]
+				if !ok {
+					return "", false
+				}
+				cur = next
+			case []any:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(v) {
+					return "", false
+				}
+				cur = v[i]
+			default:
+				return "", false
+			}
+		}
+	}
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "null", true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
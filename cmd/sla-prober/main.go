@@ -1,11 +1,17 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,68 +19,205 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 
+	"github.com/lordvidex/oncall-go-client/internal/exitcode"
+	"github.com/lordvidex/oncall-go-client/internal/leader"
 	"github.com/lordvidex/oncall-go-client/internal/oncall"
+	"github.com/lordvidex/oncall-go-client/internal/sink"
+	"github.com/lordvidex/oncall-go-client/internal/sla"
 )
 
 var (
 	// user
-	createUserScenarioTotal = promauto.NewCounter(prometheus.CounterOpts{
+	createUserScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_create_user_scenario_total",
 		Help: "Total count of runs the create user scenario to oncall API",
-	})
-	createUserScenarioSuccess = promauto.NewCounter(prometheus.CounterOpts{
+	}, []string{"team"})
+	createUserScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_create_user_scenario_success_total",
 		Help: "Total count of success runs the create user scenario to oncall API",
-	})
-	createUserScenarioDurationSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	}, []string{"team"})
+	createUserScenarioDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "prober_create_user_scenario_duration_seconds",
 		Help: "Total duration of runs the create user scenario to oncall API",
-	})
+	}, []string{"team"})
 
 	// team
-	createTeamScenarioTotal = promauto.NewCounter(prometheus.CounterOpts{
+	createTeamScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_create_team_scenario_total",
 		Help: "Total count of runs the create team scenario to oncall API",
-	})
-	createTeamScenarioSuccess = promauto.NewCounter(prometheus.CounterOpts{
+	}, []string{"team"})
+	createTeamScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_create_team_scenario_success_total",
 		Help: "Total count of success runs the create team scenario to oncall API",
-	})
-	createTeamScenarioDurationSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	}, []string{"team"})
+	createTeamScenarioDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "prober_create_team_scenario_duration_seconds",
 		Help: "Total duration of runs the create team scenario to oncall API",
-	})
+	}, []string{"team"})
 
 	// add user to team
-	addUserToTeamScenarioTotal = promauto.NewCounter(prometheus.CounterOpts{
+	addUserToTeamScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_add_user_to_team_scenario_total",
 		Help: "Total count of runs the create team scenario to oncall API",
-	})
-	addUserToTeamScenarioSuccess = promauto.NewCounter(prometheus.CounterOpts{
+	}, []string{"team"})
+	addUserToTeamScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "prober_add_user_to_team_scenario_success_total",
 		Help: "Total count of success runs to add user to team scenario to oncall API",
-	})
-	addUserToTeamScenarioDurationSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	}, []string{"team"})
+	addUserToTeamScenarioDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "prober_add_user_to_team_scenario_duration_seconds",
 		Help: "Total duration of runs to add user to team scenario to oncall API",
+	}, []string{"team"})
+
+	// override
+	overrideScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_override_scenario_total",
+		Help: "Total count of runs of the override scenario to oncall API",
+	}, []string{"team"})
+	overrideScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_override_scenario_success_total",
+		Help: "Total count of success runs of the override scenario to oncall API",
+	}, []string{"team"})
+	overrideScenarioDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_override_scenario_duration_seconds",
+		Help: "Total duration of runs of the override scenario to oncall API",
+	}, []string{"team"})
+
+	rosterAssertionFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_roster_assertion_failures_total",
+		Help: "Total count of cycles where the expected user was not on call for a team role",
+	}, []string{"team", "role"})
+
+	entityErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_entity_errors_total",
+		Help: "Total count of failed operations on probe entities, by kind of entity and operation",
+	}, []string{"team", "kind", "op"})
+
+	leakedEntities = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_leaked_entities",
+		Help: "Number of probe entities still present on the oncall server after cleanup, by kind of entity",
+	}, []string{"kind"})
+
+	isLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "prober_is_leader",
+		Help: "1 if this replica runs the scenarios, 0 if it is a warm standby",
 	})
+
+	apiCallsPerCycle = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prober_api_calls_per_cycle",
+		Help:    "Number of requests sent to oncall API during a single probe cycle",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"method"})
 )
 
 var (
-	filename  string
-	scrapeStr string
-	oncallURL string
-	port      int
-	silent    bool
+	filename     string
+	configFormat string
+	valuesFile   string
+	// configValues are the values of -values, the config is rendered with
+	configValues map[string]any
+	scrapeStr    string
+	oncallURL    string
+	timeout      time.Duration
+	port         int
+	silent       bool
+	openMetrics  bool
+
+	metricsSink  string
+	statsdAddr   string
+	statsdPrefix string
+
+	slaDatabaseURL string
+	slaObjective   float64
+	reportFile     string
+	resultsFile    string
+	once           bool
+	deleteAllow    string
+	journeysFile   string
+	probeOverride  bool
+	probeUI        bool
+	uiPaths        string
+	uiMarker       string
+
+	probeSecurity           bool
+	securityPath            string
+	securityHeaderList      string
+	securityCertMinValidity time.Duration
+
+	leaderDatabaseURL string
+	leaderLockKey     int64
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	tlsCA       string
+	tlsCert     string
+	tlsKey      string
+	tlsInsecure bool
+	proxyURL    string
+
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	disableHTTP2        bool
+
+	sdEnabled bool
+	sdAddress string
+	sdLabels  string
+	sdPeers   string
+
+	evidenceDir     string
+	evidenceMaxRuns int
+	evidenceMaxBody int
 )
 
 func init() {
-	flag.StringVar(&filename, "f", "", "yaml config file to read probe data from")
+	flag.StringVar(&filename, "f", "", "yaml, json or toml config file, or directory of config files merged together, to read probe data from")
+	flag.StringVar(&configFormat, "config-format", "", "format of -f: yaml, json or toml, by its extension if empty")
+	flag.StringVar(&valuesFile, "values", "", "if set, the config files are rendered as Go templates of the values of this yaml, json or toml file")
 
 	flag.StringVar(&scrapeStr, "scrape-duration", "60s", "interval to update and fetch new metrics")
 	flag.StringVar(&oncallURL, "oncall", "http://oncall-web:8080", "url of the oncall server")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "timeout of each request made to the oncall server")
 	flag.IntVar(&port, "port", 8080, "port for hosting metrics.. Prober hosts metrics on /probe")
 	flag.BoolVar(&silent, "silent", false, "if true, logs are not printed for oncall client")
+	flag.StringVar(&metricsSink, "metrics-sink", "prometheus", "metrics backend: prometheus, statsd or dogstatsd. Metrics are always served for scraping")
+	flag.StringVar(&statsdAddr, "statsd-addr", "127.0.0.1:8125", "udp address of the statsd agent used by -metrics-sink")
+	flag.StringVar(&statsdPrefix, "statsd-prefix", "oncall_prober", "prefix of the metric names sent to statsd")
+	flag.BoolVar(&openMetrics, "openmetrics", false, "if true, OpenMetrics format with _created series is negotiated on /probe")
+	flag.StringVar(&slaDatabaseURL, "sla-database-url", "", "if set, scenario success rates are written directly to this SLA database")
+	flag.Float64Var(&slaObjective, "sla-slo", 0.99, "success rate objective used for records written with -sla-database-url")
+	flag.BoolVar(&probeUI, "probe-ui", false, "if true, the pages of -ui-paths of the oncall web UI are fetched every cycle")
+	flag.StringVar(&uiPaths, "ui-paths", "/", "comma separated paths of the web UI pages probed by -probe-ui. oncall serves its login form on the root page")
+	flag.StringVar(&uiMarker, "ui-marker", "oncall", "case insensitive text every page probed by -probe-ui must contain")
+	flag.BoolVar(&probeSecurity, "probe-security", false, "if true, the security headers and the TLS certificate of the oncall web UI are checked every cycle")
+	flag.StringVar(&securityPath, "security-path", "/", "path of the web UI page checked by -probe-security")
+	flag.StringVar(&securityHeaderList, "security-headers", "Content-Security-Policy,X-Frame-Options", "comma separated headers the page of -security-path must be served with")
+	flag.DurationVar(&securityCertMinValidity, "security-cert-min-validity", 0, "if positive, -probe-security fails while the certificate of oncall expires within it")
+	flag.BoolVar(&probeOverride, "probe-override", false, "if true, the second user of each team overrides the first hour of an event of the first user every cycle")
+	flag.StringVar(&journeysFile, "journeys", "", "yaml file of journeys run after the scenarios of each cycle")
+	flag.StringVar(&leaderDatabaseURL, "leader-database-url", "", "if set, replicas elect a leader through a postgres advisory lock and standbys only expose metrics")
+	flag.Int64Var(&leaderLockKey, "leader-lock-key", 7415, "postgres advisory lock key shared by the replicas of a prober")
+	flag.StringVar(&deleteAllow, "delete-allow", "^probe", "regexp of the team and user names the prober may delete")
+	flag.IntVar(&breakerThreshold, "breaker-threshold", 0, "consecutive oncall failures opening the circuit breaker, 0 disables it")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", 30*time.Second, "time the circuit breaker stays open before probing oncall again")
+	flag.BoolVar(&sdEnabled, "sd", false, "if true, targets for Prometheus HTTP service discovery are served on /sd")
+	flag.StringVar(&sdAddress, "sd-address", "", "address advertised on /sd, hostname:port if empty")
+	flag.StringVar(&sdLabels, "sd-labels", "", "comma separated key=value labels advertised on /sd, e.g. env=prod,region=eu")
+	flag.StringVar(&sdPeers, "sd-peers", "", "file of target groups of sibling probers, in the Prometheus file_sd format, appended to /sd")
+	flag.StringVar(&tlsCA, "tls-ca", "", "PEM file of the CA certificates trusted for an https oncall server, in addition to the system ones")
+	flag.StringVar(&tlsCert, "tls-cert", "", "PEM client certificate presented to oncall, with -tls-key")
+	flag.StringVar(&tlsKey, "tls-key", "", "PEM key of -tls-cert")
+	flag.BoolVar(&tlsInsecure, "tls-insecure", false, "if true, the certificate of oncall is not verified")
+	flag.StringVar(&proxyURL, "proxy", "", "url of the HTTP proxy used to reach oncall, $HTTPS_PROXY and $HTTP_PROXY are used if empty")
+	flag.IntVar(&maxIdleConnsPerHost, "max-idle-conns-per-host", 0, "idle connections to oncall kept for reuse, the net/http default of 2 if 0")
+	flag.DurationVar(&idleConnTimeout, "idle-conn-timeout", 90*time.Second, "time an idle connection to oncall is kept, should exceed -scrape-duration for connections to be reused")
+	flag.BoolVar(&disableHTTP2, "disable-http2", false, "if true, HTTP/1.1 is used even if oncall negotiates HTTP/2")
+	flag.BoolVar(&once, "once", false, "if true, the scenarios are run a single time and the prober exits with a code classifying the failures")
+	flag.StringVar(&evidenceDir, "evidence-dir", "", "if set, the sanitized request and response of each failed scenario are written to <dir>/<run id>/")
+	flag.IntVar(&evidenceMaxRuns, "evidence-max-runs", 100, "number of most recent runs kept in -evidence-dir")
+	flag.IntVar(&evidenceMaxBody, "evidence-max-body", 4096, "bytes of each body kept in -evidence-dir")
+	flag.StringVar(&resultsFile, "results-file", "", "if set, the versioned result of every cycle is appended to this file as a JSON line")
+	flag.StringVar(&reportFile, "report-file", "", "if set, the shutdown report of leftover probe entities is written to this file as JSON")
 }
 
 func main() {
@@ -83,64 +226,265 @@ func main() {
 	logger := zerolog.New(zerolog.NewConsoleWriter())
 
 	if filename == "" {
-		logger.Fatal().Msg("filename must be provided")
+		exitcode.Fatal(logger, exitcode.Wrap(exitcode.Config, errors.New("filename must be provided")), "invalid flags")
 	}
 
 	scrapeDuration, err := time.ParseDuration(scrapeStr)
 	if err != nil {
-		log.Fatal("failed to parse scrape-duration")
+		exitcode.Fatal(logger, exitcode.Wrap(exitcode.Config, err), "failed to parse scrape-duration")
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
 	app, err := NewApp(logger, oncallURL, scrapeDuration)
 	if err != nil {
-		log.Fatalf("failed to create prober: %v", err)
+		exitcode.Fatal(logger, err, "failed to create prober")
+	}
+	if slaDatabaseURL != "" {
+		if err = app.openSLAStore(ctx, slaDatabaseURL); err != nil {
+			exitcode.Fatal(logger, exitcode.Wrap(exitcode.Unreachable, err), "failed to open sla store")
+		}
+		defer app.store.Close()
+	}
+	if once {
+		os.Exit(app.runOnce(ctx))
+	}
+	if leaderDatabaseURL != "" {
+		app.elector = leader.New(leaderDatabaseURL, leaderLockKey, logger)
+		go app.elector.Run(ctx, scrapeDuration/2, func(l bool) {
+			isLeader.Set(boolToFloat(l))
+		})
+	} else {
+		isLeader.Set(1)
+	}
+	if err = sink.Start(ctx, metricsSink, statsdAddr, statsdPrefix, scrapeDuration, logger); err != nil {
+		exitcode.Fatal(logger, exitcode.Wrap(exitcode.Config, err), "failed to create metrics sink")
 	}
-	go app.worker(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.worker(ctx)
+	}()
+
+	http.Handle("/probe", metricsHandler())
+	if sdEnabled {
+		sd, err := newDiscovery(sdAddress, sdLabels, sdPeers, app.scale.Profile)
+		if err != nil {
+			exitcode.Fatal(logger, exitcode.Wrap(exitcode.Config, err), "failed to create service discovery")
+		}
+		http.Handle("/sd", sd)
+	}
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port)}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().Err(err).Msg("metrics server stopped")
+			cancel()
+		}
+	}()
 
-	http.Handle("/probe", promhttp.Handler())
-	http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
+	<-ctx.Done()
+	<-done
+	shutdownCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	_ = srv.Shutdown(shutdownCtx)
+	if err = app.report(); err != nil {
+		logger.Error().Err(err).Msg("failed to write shutdown report")
+	}
+}
+
+// runOnce runs the scenarios a single time, without serving metrics, and returns the exit code
+// of the cycle, see exitcode.Of
+func (a *app) runOnce(ctx context.Context) int {
+	err := a.runScenarios(ctx)
+	if reportErr := a.report(); reportErr != nil {
+		a.logger.Error().Err(reportErr).Msg("failed to write shutdown report")
+	}
+	code := exitcode.Of(err)
+	if err != nil {
+		a.logger.Error().Err(err).Str("class", exitcode.Name(code)).Int("exit_code", code).Msg("probe cycle failed")
+	}
+	return code
 }
 
 type app struct {
 	logger zerolog.Logger
 	// oncall Client is used to make http calls to oncall server
-	cl *oncall.Client
+	cl oncall.API
 	// oncall Config contains the test data to run SLA probe checks
 	config oncall.Config
 	// scrapeDuration is the amount of time before new metrics are scraped
 	scrapeDuration time.Duration
-	// reloginDuration is the time taken before client is relogged in, to refresh token
-	reloginDuration time.Duration
+	// store receives locally evaluated SLIs, nil unless -sla-database-url is set
+	store *sla.Store
+	// pending holds the probe entities that may still exist on the server
+	pending *pendingCleanup
+	// journeys are loaded from -journeys
+	journeys []journey
+	// scale is the load profile applied to config
+	scale scale
+	// schedule restricts the times scenarios run at
+	schedule scenarioSchedule
+	// timezones are the scheduling timezones of the timezone scenario
+	timezones timezoneMatrix
+	// elector decides whether this replica runs the scenarios, nil unless -leader-database-url is set
+	elector *leader.Elector
+	// evidence stores the failed scenario requests, nil unless -evidence-dir is set
+	evidence *evidenceStore
+	// naming names the probe entities of each cycle
+	naming naming
+	// cycles counts the probe cycles run
+	cycles int64
+	// runID identifies the current cycle in the logs and the evidence
+	runID string
+	// probeNames matches the names of the entities created by the prober, see -delete-allow
+	probeNames *regexp.Regexp
+	// stats keeps the success and failure streaks of the scenarios
+	stats *scenarioStats
+}
+
+// configOptions read the blocks of the config file
+func configOptions() []oncall.LoadOption {
+	return []oncall.LoadOption{oncall.WithFormat(configFormat), oncall.WithValues(configValues)}
 }
 
 func NewApp(logger zerolog.Logger, oncallURL string, scrapeDuration time.Duration) (*app, error) {
-	cfg, err := oncall.LoadConfig(filename)
+	if valuesFile != "" {
+		values, err := oncall.ReadValues(valuesFile)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.Config, err)
+		}
+		configValues = values
+	}
+	cfg, err := oncall.LoadConfig(filename, append(configOptions(), oncall.AllowKeys("scale", "schedule", "timezone_matrix", "naming", "journeys"))...)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	sc, err := loadScale(filename)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	schedule, err := loadSchedule(filename)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	timezones, err := loadTimezoneMatrix(filename)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.Config, err)
 	}
+	names, err := loadNaming(filename)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	cfg = sc.apply(cfg)
+	if err = cfg.Validate(); err != nil {
+		return nil, exitcode.Wrap(exitcode.Validation, err)
+	}
+	sc.observe(cfg)
 
-	opts := []oncall.Option{oncall.WithURL(oncallURL)}
+	allow, err := regexp.Compile(deleteAllow)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	opts := []oncall.Option{
+		oncall.WithURL(oncallURL),
+		oncall.WithTimeout(timeout),
+		oncall.WithDeleteProtection(allow),
+		oncall.WithCircuitBreaker(breakerThreshold, breakerCooldown),
+	}
+	opts = append(opts, oncall.TLSOptions(tlsCA, tlsCert, tlsKey, tlsInsecure)...)
+	if proxyURL != "" {
+		opts = append(opts, oncall.WithProxy(proxyURL))
+	}
+	opts = append(opts, oncall.WithIdleConnTimeout(idleConnTimeout))
+	if maxIdleConnsPerHost > 0 {
+		opts = append(opts, oncall.WithMaxIdleConnsPerHost(maxIdleConnsPerHost))
+	}
+	if disableHTTP2 {
+		opts = append(opts, oncall.WithoutHTTP2())
+	}
 	if silent {
 		opts = append(opts, oncall.WithLogger(zerolog.Nop()))
 	}
 	cl, err := oncall.New(opts...)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+	a := &app{
+		logger:         logger,
+		scrapeDuration: scrapeDuration,
+		config:         cfg,
+		cl:             cl,
+		pending:        newPendingCleanup(),
+		probeNames:     allow,
+		scale:          sc,
+		schedule:       schedule,
+		timezones:      timezones,
+		naming:         names,
+		stats:          newScenarioStats(),
 	}
-	return &app{
-		logger:          logger,
-		scrapeDuration:  scrapeDuration,
-		reloginDuration: time.Hour,
-		config:          cfg,
-		cl:              cl,
-	}, nil
+	if evidenceDir != "" {
+		a.evidence = &evidenceStore{dir: evidenceDir, maxRuns: max(evidenceMaxRuns, 1), maxBody: max(evidenceMaxBody, 0)}
+	}
+	if journeysFile != "" {
+		if a.journeys, err = loadJourneys(journeysFile); err != nil {
+			return nil, exitcode.Wrap(exitcode.Config, err)
+		}
+	}
+	a.initMetrics()
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "prober_client_relogins_total",
+		Help: "Total count of logins made again after the oncall session expired",
+	}, func() float64 { return float64(cl.Relogins()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "prober_client_breaker_state",
+		Help: "State of the circuit breaker of the oncall client: 0 closed, 1 open, 2 half-open",
+	}, func() float64 { return float64(cl.BreakerState()) })
+	prometheus.MustRegister(rateLimitedCollector{cl: cl}, a.stats)
+	return a, nil
 }
 
-func (a *app) login() error {
-	return a.cl.Login(context.Background())
+// initMetrics creates every scenario series for the configured teams so that
+// counters start at 0 instead of appearing mid-incident.
+func (a *app) initMetrics() {
+	for _, t := range a.config.Teams {
+		labels := prometheus.Labels{"team": t.Name}
+		createTeamScenarioTotal.With(labels)
+		createTeamScenarioSuccess.With(labels)
+		createUserScenarioTotal.With(labels)
+		createUserScenarioSuccess.With(labels)
+		addUserToTeamScenarioTotal.With(labels)
+		addUserToTeamScenarioSuccess.With(labels)
+		if probeOverride {
+			overrideScenarioTotal.With(labels)
+			overrideScenarioSuccess.With(labels)
+		}
+		for role := range t.ExpectOnCall {
+			rosterAssertionFailures.WithLabelValues(t.Name, role)
+		}
+	}
+	if probeUI {
+		for _, page := range uiPages() {
+			uiScenarioTotal.WithLabelValues(page)
+			uiScenarioSuccess.WithLabelValues(page)
+		}
+	}
+	if probeSecurity {
+		for _, h := range securityHeaders() {
+			securityMissingHeader.WithLabelValues(h)
+		}
+	}
+	for _, j := range a.journeys {
+		journeyTotal.WithLabelValues(j.Name)
+		journeySuccess.WithLabelValues(j.Name)
+	}
+	for _, tz := range a.timezones.Timezones {
+		timezoneScenarioTotal.WithLabelValues(tz)
+		timezoneScenarioSuccess.WithLabelValues(tz)
+	}
+	for scenario := range a.schedule.crons {
+		scenarioSkipped.WithLabelValues(scenario, "schedule")
+	}
 }
 
 func (a *app) worker(ctx context.Context) {
@@ -150,56 +494,266 @@ func (a *app) worker(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			a.runScenarios()
-		case <-time.After(a.reloginDuration):
-			a.login()
+			if a.elector != nil && !a.elector.IsLeader() {
+				a.logger.Debug().Msg("standby, skipping scenarios")
+				continue
+			}
+			a.runScenarios(ctx)
 		}
 	}
 }
 
-func (a *app) runScenarios() error {
-	stats, err := a.cl.CreateEntities(a.config)
-	defer a.cl.DeleteEntities(a.config)
-	if err != nil {
+func (a *app) runScenarios(ctx context.Context) error {
+	start := time.Now()
+	a.runID = start.UTC().Format(runIDLayout)
+	defer func() {
+		cycleDurationSeconds.WithLabelValues(a.scale.Profile).Set(time.Since(start).Seconds())
+	}()
+	results := make(cycleResults)
+	defer a.exportResult(results, start)
+	defer a.stats.observe(results, start)
+	defer a.writeSLA(ctx, results)
+	defer a.observeCalls(a.cl.CallCounts())
+
+	a.cycles++
+	c := cycle{seq: a.cycles, start: start}
+
+	var entitiesErr error
+	if a.schedule.allows(scenarioCreateTeam, start) {
+		teams := a.naming.strategy(scenarioCreateTeam)
+		cfg := a.naming.apply(a.config, teams, a.naming.strategy(scenarioCreateUser), c)
+		defer a.cleanup(cfg, !teams.stable())
+		entitiesErr = a.runEntityScenarios(ctx, cfg, results)
+	} else {
+		a.skipEntityScenarios()
+	}
+	if probeUI && a.schedule.allows(scenarioUI, start) {
+		a.probeUI(ctx, results)
+	}
+	if probeSecurity && a.schedule.allows(scenarioSecurity, start) {
+		a.probeSecurity(ctx, results)
+	}
+	if len(a.timezones.Timezones) > 0 && a.schedule.allows(scenarioTimezone, start) {
+		a.runTimezones(ctx, c, results)
+	}
+	if len(a.journeys) > 0 && a.schedule.allows(scenarioJourney, start) {
+		a.runJourneys(ctx)
+	}
+	return errors.Join(entitiesErr, results.failed())
+}
+
+// runEntityScenarios creates the entities of cfg, the config named for the cycle, and records
+// the scenarios of each team under its configured name. The entities are left for cleanup.
+func (a *app) runEntityScenarios(ctx context.Context, cfg oncall.Config, results cycleResults) error {
+	report, entitiesErr := a.cl.CreateEntities(ctx, cfg)
+	a.track(report)
+	if err := entitiesErr; err != nil {
 		a.logger.Warn().Err(err).Msg("entities error")
+		var multi *oncall.MultiError
+		if errors.As(err, &multi) {
+			for _, e := range multi.Errors {
+				entityErrors.WithLabelValues(e.Team, e.Kind, e.Op).Inc()
+			}
+		}
 	}
 
 	// teams
-	for _, tt := range a.config.Teams {
-		createTeamScenarioTotal.Inc()
-		teamStat, ok := stats[tt.Name]
-		if !ok {
-			createTeamScenarioSuccess.Add(0)
+	for i, tt := range cfg.Teams {
+		label := a.config.Teams[i].Name
+		labels := prometheus.Labels{"team": label}
+		var team *oncall.TeamReport
+		if report != nil {
+			team, _ = report.Team(tt.Name)
+		}
+		if team == nil {
+			createTeamScenarioTotal.With(labels).Inc()
+			results.record(scenarioCreateTeam, false)
 			continue
 		}
-		if teamStat.Response.StatusCode != 0 && teamStat.Response.StatusCode <= 201 {
-			createTeamScenarioDurationSeconds.Set(float64(teamStat.Response.ResponseTime.Seconds()))
-			createTeamScenarioSuccess.Inc()
-		} else {
-			createTeamScenarioSuccess.Add(0)
+		switch {
+		case a.throttled(scenarioCreateTeam, team.Create.Err, results):
+		case team.Create.Succeeded():
+			createTeamScenarioTotal.With(labels).Inc()
+			createTeamScenarioDurationSeconds.With(labels).Set(team.Create.Latency.Seconds())
+			createTeamScenarioSuccess.With(labels).Inc()
+			results.record(scenarioCreateTeam, true)
+		default:
+			createTeamScenarioTotal.With(labels).Inc()
+			a.logFailure(scenarioCreateTeam, team.Create.Response)
+			results.record(scenarioCreateTeam, false)
 		}
 
 		// users
 		for _, u := range tt.Users {
-			createUserScenarioTotal.Inc()
-			addUserToTeamScenarioTotal.Inc()
-
-			createRes, ok := teamStat.UserCreateResponses[u.Name]
-			if ok && createRes.StatusCode != 0 && createRes.StatusCode <= 201 {
-				createUserScenarioSuccess.Inc()
-				createUserScenarioDurationSeconds.Set(float64(createRes.ResponseTime.Seconds()))
-			} else {
-				createUserScenarioSuccess.Add(0)
+			user, ok := team.User(u.Name)
+			if !ok {
+				createUserScenarioTotal.With(labels).Inc()
+				addUserToTeamScenarioTotal.With(labels).Inc()
+				results.record(scenarioCreateUser, false)
+				results.record(scenarioAddUserToTeam, false)
+				continue
+			}
+
+			if !a.throttled(scenarioCreateUser, user.Create.Err, results) {
+				createUserScenarioTotal.With(labels).Inc()
+				if user.Create.Succeeded() {
+					createUserScenarioSuccess.With(labels).Inc()
+					createUserScenarioDurationSeconds.With(labels).Set(user.Create.Latency.Seconds())
+				} else {
+					a.logFailure(scenarioCreateUser, user.Create.Response)
+				}
+				results.record(scenarioCreateUser, user.Create.Succeeded())
 			}
 
-			addRes, ok := teamStat.UserAddToTeamResponses[u.Name]
-			if ok && addRes.StatusCode != 0 && addRes.StatusCode <= 201 {
-				addUserToTeamScenarioSuccess.Inc()
-				addUserToTeamScenarioDurationSeconds.Set(float64(addRes.ResponseTime.Seconds()))
-			} else {
-				addUserToTeamScenarioSuccess.Add(0)
+			if !a.throttled(scenarioAddUserToTeam, user.AddToTeam.Err, results) {
+				addUserToTeamScenarioTotal.With(labels).Inc()
+				if user.AddToTeam.Succeeded() {
+					addUserToTeamScenarioSuccess.With(labels).Inc()
+					addUserToTeamScenarioDurationSeconds.With(labels).Set(user.AddToTeam.Latency.Seconds())
+				} else {
+					a.logFailure(scenarioAddUserToTeam, user.AddToTeam.Response)
+				}
+				results.record(scenarioAddUserToTeam, user.AddToTeam.Succeeded())
 			}
 		}
+
+		if probeOverride {
+			a.probeOverride(ctx, tt, label, results)
+		}
+		a.assertOnCall(ctx, tt, label)
+	}
+	return entitiesErr
+}
+
+// skipEntityScenarios counts the scenarios depending on the entities as skipped by the schedule
+func (a *app) skipEntityScenarios() {
+	scenarios := []string{scenarioCreateUser, scenarioAddUserToTeam}
+	if probeOverride {
+		scenarios = append(scenarios, scenarioOverride)
+	}
+	for _, scenario := range scenarios {
+		scenarioSkipped.WithLabelValues(scenario, "schedule").Inc()
 	}
-	return nil
+}
+
+// maxLoggedBody is the maximum number of bytes of a response body logged by logFailure
+const maxLoggedBody = 512
+
+// logFailure logs what oncall answered to a failed scenario request and saves it as evidence
+func (a *app) logFailure(scenario string, r *oncall.Response[any]) {
+	if r == nil || r.StatusCode == 0 {
+		return
+	}
+	body := r.RawBody
+	if len(body) > maxLoggedBody {
+		body = body[:maxLoggedBody]
+	}
+	a.logger.Warn().
+		Str("scenario", scenario).
+		Str("run_id", a.runID).
+		Str("url", r.URL).
+		Int("status_code", r.StatusCode).
+		Str("content_type", r.Header.Get("Content-Type")).
+		Bytes("body", body).
+		Msg("unexpected response")
+	if a.evidence != nil {
+		if err := a.evidence.save(a.runID, scenario, r); err != nil {
+			a.logger.Error().Err(err).Str("scenario", scenario).Msg("failed to save evidence")
+		}
+	}
+}
+
+// probeOverride exercises the override endpoint: the second user of the team takes
+// the first hour of the earliest event of the first user. The metrics are labeled with label,
+// the configured name of the team.
+func (a *app) probeOverride(ctx context.Context, t oncall.Team, label string, results cycleResults) {
+	if len(t.Users) < 2 {
+		return
+	}
+	labels := prometheus.Labels{"team": label}
+	logger := a.logger.With().Str("team", t.Name).Str("scenario", scenarioOverride).Logger()
+
+	events, err := a.cl.GetEvents(ctx, oncall.EventFilter{Team: t.Name, User: t.Users[0].Name})
+	if a.throttled(scenarioOverride, err, results) {
+		return
+	}
+	if err != nil || len(events.Data) == 0 {
+		overrideScenarioTotal.With(labels).Inc()
+		logger.Warn().Err(err).Msg("no event to override")
+		results.record(scenarioOverride, false)
+		return
+	}
+	e := slices.MinFunc(events.Data, func(x, y oncall.Event) int { return cmp.Compare(x.Start, y.Start) })
+	start := time.Unix(e.Start, 0)
+	res, err := a.cl.OverrideEvents(ctx, oncall.OverrideRequest{
+		User:     t.Users[1].Name,
+		Start:    start,
+		End:      start.Add(time.Hour),
+		EventIDs: []int64{e.ID},
+	})
+	if a.throttled(scenarioOverride, err, results) {
+		return
+	}
+	overrideScenarioTotal.With(labels).Inc()
+	if err != nil {
+		logger.Warn().Err(err).Msg("override failed")
+		a.logFailure(scenarioOverride, res)
+		results.record(scenarioOverride, false)
+		return
+	}
+	overrideScenarioSuccess.With(labels).Inc()
+	overrideScenarioDurationSeconds.With(labels).Set(res.ResponseTime.Seconds())
+	results.record(scenarioOverride, true)
+}
+
+// assertOnCall checks that the users expected by the config are currently on call in the team,
+// counting failures under label, the configured name of the team
+func (a *app) assertOnCall(ctx context.Context, t oncall.Team, label string) {
+	if len(t.ExpectOnCall) == 0 {
+		return
+	}
+	res, err := a.cl.GetOnCall(ctx, t.Name)
+	if err != nil {
+		a.logger.Warn().Err(err).Str("team", t.Name).Msg("error fetching team summary")
+	}
+	for role, want := range t.ExpectOnCall {
+		var current []string
+		if res != nil {
+			current = res.Data[role]
+		}
+		if !slices.Contains(current, want) {
+			a.logger.Warn().
+				Str("team", t.Name).
+				Str("role", role).
+				Str("expected", want).
+				Strs("current", current).
+				Msg("roster assertion failed")
+			rosterAssertionFailures.WithLabelValues(label, role).Inc()
+		}
+	}
+}
+
+// observeCalls records the requests sent since the before snapshot, including cleanup
+func (a *app) observeCalls(before map[string]int64) {
+	for method, n := range a.cl.CallCounts() {
+		apiCallsPerCycle.WithLabelValues(method).Observe(float64(n - before[method]))
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsHandler serves the default registry, negotiating OpenMetrics when enabled
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics:                   openMetrics,
+			EnableOpenMetricsTextCreatedSamples: openMetrics,
+		}),
+	)
 }
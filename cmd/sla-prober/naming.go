@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+// cycle identifies a probe cycle to the name strategies
+type cycle struct {
+	// seq counts the cycles since the prober started, from 1
+	seq   int64
+	start time.Time
+}
+
+// nameStrategy names the probe entities of a cycle after their configured names. Stable names
+// let the probe run idempotently against entities that already exist, unique names make every
+// cycle exercise the create path of oncall.
+type nameStrategy interface {
+	name(base string, c cycle) string
+	// stable reports whether the names are the configured ones, entities named by other
+	// strategies are deleted after their cycle
+	stable() bool
+}
+
+// stableNames keeps the configured names
+type stableNames struct{}
+
+func (stableNames) name(base string, _ cycle) string { return base }
+func (stableNames) stable() bool                     { return true }
+
+// sequentialNames suffixes the names with the number of the cycle
+type sequentialNames struct{}
+
+func (sequentialNames) name(base string, c cycle) string {
+	return base + "-" + strconv.FormatInt(c.seq, 10)
+}
+func (sequentialNames) stable() bool { return false }
+
+// randomNames suffixes the names with random hex digits
+type randomNames struct{}
+
+func (randomNames) name(base string, _ cycle) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return base + "-" + hex.EncodeToString(b)
+}
+func (randomNames) stable() bool { return false }
+
+// bucketedNames suffixes the names with the start of the time bucket of the cycle, so that
+// entities left over by a failed cleanup are reused by the next cycles of the bucket instead
+// of piling up
+type bucketedNames struct {
+	bucket time.Duration
+}
+
+func (s bucketedNames) name(base string, c cycle) string {
+	return base + "-" + strconv.FormatInt(c.start.Truncate(s.bucket).Unix(), 10)
+}
+func (bucketedNames) stable() bool { return false }
+
+// namingConfig is the naming block of the probe config:
+//
+//	naming:
+//	  default: {strategy: stable}
+//	  scenarios:
+//	    create_team: {strategy: time_bucketed, bucket: 1h}
+//	    create_user: {strategy: random}
+//
+// create_team names the teams of the config, create_user their users and timezone the teams
+// and users of the timezone matrix.
+type namingConfig struct {
+	Default   strategyConfig            `yaml:"default"`
+	Scenarios map[string]strategyConfig `yaml:"scenarios"`
+}
+
+// strategyConfig selects a strategy: stable, sequential, random or time_bucketed
+type strategyConfig struct {
+	Strategy string `yaml:"strategy"`
+	// Bucket is the duration of the buckets of time_bucketed
+	Bucket time.Duration `yaml:"bucket"`
+}
+
+func (c strategyConfig) strategy() (nameStrategy, error) {
+	switch c.Strategy {
+	case "", "stable":
+		return stableNames{}, nil
+	case "sequential":
+		return sequentialNames{}, nil
+	case "random":
+		return randomNames{}, nil
+	case "time_bucketed":
+		if c.Bucket <= 0 {
+			return nil, fmt.Errorf("time_bucketed strategy needs a positive bucket")
+		}
+		return bucketedNames{bucket: c.Bucket}, nil
+	}
+	return nil, fmt.Errorf("unknown name strategy %q, expected stable, sequential, random or time_bucketed", c.Strategy)
+}
+
+// naming holds the name strategy of each scenario creating entities
+type naming struct {
+	fallback   nameStrategy
+	strategies map[string]nameStrategy
+}
+
+// loadNaming reads the naming block of the probe config, every name is stable without it
+func loadNaming(filename string) (naming, error) {
+	n := naming{fallback: stableNames{}, strategies: map[string]nameStrategy{}}
+	b, err := oncall.ReadConfig(filename, configOptions()...)
+	if err != nil {
+		return n, err
+	}
+	var cfg struct {
+		Naming *namingConfig `yaml:"naming"`
+	}
+	if err = yaml.Unmarshal(b, &cfg); err != nil || cfg.Naming == nil {
+		return n, err
+	}
+	if n.fallback, err = cfg.Naming.Default.strategy(); err != nil {
+		return n, fmt.Errorf("naming: %w", err)
+	}
+	for scenario, c := range cfg.Naming.Scenarios {
+		switch scenario {
+		case scenarioCreateTeam, scenarioCreateUser, scenarioTimezone:
+		default:
+			return n, fmt.Errorf("naming: unknown scenario %s, expected %s, %s or %s", scenario, scenarioCreateTeam, scenarioCreateUser, scenarioTimezone)
+		}
+		if n.strategies[scenario], err = c.strategy(); err != nil {
+			return n, fmt.Errorf("naming of %s: %w", scenario, err)
+		}
+	}
+	return n, nil
+}
+
+func (n naming) strategy(scenario string) nameStrategy {
+	if s, ok := n.strategies[scenario]; ok {
+		return s
+	}
+	return n.fallback
+}
+
+// apply returns cfg with the teams named by the teams strategy and the users by the users
+// strategy for the cycle c. The teams keep their order and references to users follow them.
+func (n naming) apply(cfg oncall.Config, teams, users nameStrategy, c cycle) oncall.Config {
+	if teams.stable() && users.stable() {
+		return cfg
+	}
+	// a user keeps a single name across the teams and references of the cycle
+	names := make(map[string]string)
+	rename := func(user string) string {
+		if _, ok := names[user]; !ok {
+			names[user] = users.name(user, c)
+		}
+		return names[user]
+	}
+	var out oncall.Config
+	for _, t := range cfg.Teams {
+		ct := t
+		ct.Name = teams.name(t.Name, c)
+		ct.Users = nil
+		for _, u := range t.Users {
+			u.Name = rename(u.Name)
+			ct.Users = append(ct.Users, u)
+		}
+		ct.Admins = nil
+		for _, a := range t.Admins {
+			ct.Admins = append(ct.Admins, rename(a))
+		}
+		ct.ExpectOnCall = make(map[string]string, len(t.ExpectOnCall))
+		for role, u := range t.ExpectOnCall {
+			ct.ExpectOnCall[role] = rename(u)
+		}
+		ct.Rosters = nil
+		for _, r := range t.Rosters {
+			cr := r
+			cr.Users = nil
+			for _, m := range r.Users {
+				m.Name = rename(m.Name)
+				cr.Users = append(cr.Users, m)
+			}
+			cr.Schedules = nil
+			for _, sc := range r.Schedules {
+				order := make([]string, len(sc.Order))
+				for k, u := range sc.Order {
+					order[k] = rename(u)
+				}
+				sc.Order = order
+				cr.Schedules = append(cr.Schedules, sc)
+			}
+			ct.Rosters = append(ct.Rosters, cr)
+		}
+		out.Teams = append(out.Teams, ct)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/probe"
+)
+
+// probeResult is the versioned result of the cycle started at start
+func (r cycleResults) probeResult(runID string, start time.Time, duration time.Duration) probe.ProbeResult {
+	res := probe.ProbeResult{
+		SchemaVersion:   probe.SchemaVersion,
+		RunID:           runID,
+		Start:           start.UTC(),
+		DurationSeconds: duration.Seconds(),
+		Scenarios:       []probe.ScenarioResult{},
+	}
+	res.Instance, _ = os.Hostname()
+	for scenario, s := range r {
+		res.Scenarios = append(res.Scenarios, probe.NewScenarioResult(scenario, s.total, s.success, s.throttled))
+	}
+	slices.SortFunc(res.Scenarios, func(x, y probe.ScenarioResult) int { return cmp.Compare(x.Name, y.Name) })
+	return res
+}
+
+// exportResult appends the result of the cycle to resultsFile as a JSON line
+func (a *app) exportResult(results cycleResults, start time.Time) {
+	if resultsFile == "" {
+		return
+	}
+	b, err := json.Marshal(results.probeResult(a.runID, start, time.Since(start)))
+	if err != nil {
+		a.logger.Error().Err(err).Msg("error encoding probe result")
+		return
+	}
+	f, err := os.OpenFile(resultsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		a.logger.Error().Err(err).Str("file", resultsFile).Msg("error opening results file")
+		return
+	}
+	defer f.Close()
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		a.logger.Error().Err(err).Str("file", resultsFile).Msg("error writing probe result")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+var (
+	scaleEntities = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_scale_entities",
+		Help: "Number of probe entities created each cycle by the scale profile, by kind",
+	}, []string{"profile", "kind"})
+	cycleDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_cycle_duration_seconds",
+		Help: "Duration of the last probe cycle, labeled by the scale profile",
+	}, []string{"profile"})
+)
+
+// scale multiplies the entities of the probe config, so light and heavy load profiles
+// can share a base config
+type scale struct {
+	// Profile labels the metrics derived from the scale
+	Profile string `yaml:"profile"`
+	// Teams is the number of copies of every team, Users the number of copies of every user in a team
+	Teams int `yaml:"teams"`
+	Users int `yaml:"users"`
+}
+
+// loadScale reads the scale block of the probe config, defaulting to a single copy of everything
+func loadScale(filename string) (scale, error) {
+	s := scale{Profile: "default", Teams: 1, Users: 1}
+	b, err := oncall.ReadConfig(filename, configOptions()...)
+	if err != nil {
+		return s, err
+	}
+	var cfg struct {
+		Scale *scale `yaml:"scale"`
+	}
+	if err = yaml.Unmarshal(b, &cfg); err != nil || cfg.Scale == nil {
+		return s, err
+	}
+	if cfg.Scale.Profile != "" {
+		s.Profile = cfg.Scale.Profile
+	}
+	s.Teams = max(cfg.Scale.Teams, 1)
+	s.Users = max(cfg.Scale.Users, 1)
+	return s, nil
+}
+
+// apply returns cfg with every team copied s.Teams times and every user of a team s.Users times.
+// The first copy keeps the original names, the others are suffixed with their copy numbers.
+// References to users (rosters, admins, expected on call users) follow the first copy of the user.
+func (s scale) apply(cfg oncall.Config) oncall.Config {
+	if s.Teams <= 1 && s.Users <= 1 {
+		return cfg
+	}
+	var out oncall.Config
+	for _, t := range cfg.Teams {
+		for i := 0; i < s.Teams; i++ {
+			out.Teams = append(out.Teams, s.copyTeam(t, i))
+		}
+	}
+	return out
+}
+
+func (s scale) copyTeam(t oncall.Team, i int) oncall.Team {
+	rename := func(user string, j int) string {
+		if i == 0 && j == 0 {
+			return user
+		}
+		return fmt.Sprintf("%s-%d-%d", user, i, j)
+	}
+	c := t
+	if i > 0 {
+		c.Name = fmt.Sprintf("%s-%d", t.Name, i)
+	}
+	c.Users = nil
+	for _, u := range t.Users {
+		for j := 0; j < s.Users; j++ {
+			cu := u
+			cu.Name = rename(u.Name, j)
+			c.Users = append(c.Users, cu)
+		}
+	}
+	c.Admins = nil
+	for _, a := range t.Admins {
+		c.Admins = append(c.Admins, rename(a, 0))
+	}
+	c.ExpectOnCall = make(map[string]string, len(t.ExpectOnCall))
+	for role, u := range t.ExpectOnCall {
+		c.ExpectOnCall[role] = rename(u, 0)
+	}
+	c.Rosters = nil
+	for _, r := range t.Rosters {
+		cr := r
+		cr.Users = nil
+		for _, m := range r.Users {
+			m.Name = rename(m.Name, 0)
+			cr.Users = append(cr.Users, m)
+		}
+		cr.Schedules = nil
+		for _, sc := range r.Schedules {
+			order := make([]string, len(sc.Order))
+			for k, u := range sc.Order {
+				order[k] = rename(u, 0)
+			}
+			sc.Order = order
+			cr.Schedules = append(cr.Schedules, sc)
+		}
+		c.Rosters = append(c.Rosters, cr)
+	}
+	return c
+}
+
+// observe exports the number of entities of the scaled config
+func (s scale) observe(cfg oncall.Config) {
+	var users int
+	for _, t := range cfg.Teams {
+		users += len(t.Users)
+	}
+	scaleEntities.WithLabelValues(s.Profile, "team").Set(float64(len(cfg.Teams)))
+	scaleEntities.WithLabelValues(s.Profile, "user").Set(float64(users))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+const scenarioJourney = "journey"
+
+var scenarioSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "prober_scenario_skipped_total",
+	Help: "Total count of scenario runs skipped, by scenario and reason",
+}, []string{"scenario", "reason"})
+
+// scenarioSchedule restricts scenarios to the times matching their cron expression, so that
+// heavyweight write scenarios can run during business hours only. Scenarios without an
+// expression run every cycle.
+//
+// create_team also schedules the scenarios depending on the created entities: create_user,
+// add_user_to_team, override and the on call assertions. The journeys are scheduled as journey
+// and the timezone matrix as timezone.
+type scenarioSchedule struct {
+	// Timezone is the location the expressions are evaluated in, UTC if empty
+	Timezone string `yaml:"timezone"`
+	// Scenarios maps a scenario to a cron expression: minute hour day-of-month month day-of-week
+	Scenarios map[string]string `yaml:"scenarios"`
+
+	location *time.Location
+	crons    map[string]cronSchedule
+	// checked is the time each scheduled scenario was last checked at
+	checked map[string]time.Time
+}
+
+// loadSchedule reads the schedule block of the probe config, every scenario runs every cycle
+// without it
+func loadSchedule(filename string) (scenarioSchedule, error) {
+	s := scenarioSchedule{location: time.UTC}
+	b, err := oncall.ReadConfig(filename, configOptions()...)
+	if err != nil {
+		return s, err
+	}
+	var cfg struct {
+		Schedule *scenarioSchedule `yaml:"schedule"`
+	}
+	if err = yaml.Unmarshal(b, &cfg); err != nil || cfg.Schedule == nil {
+		return s, err
+	}
+	s = *cfg.Schedule
+	s.location = time.UTC
+	if s.Timezone != "" {
+		if s.location, err = time.LoadLocation(s.Timezone); err != nil {
+			return s, fmt.Errorf("schedule: %w", err)
+		}
+	}
+	s.crons = make(map[string]cronSchedule, len(s.Scenarios))
+	s.checked = make(map[string]time.Time, len(s.Scenarios))
+	for scenario, expr := range s.Scenarios {
+		switch scenario {
+		case scenarioCreateTeam, scenarioUI, scenarioJourney, scenarioTimezone, scenarioSecurity:
+		default:
+			return s, fmt.Errorf("schedule: unknown scenario %s, expected %s, %s, %s, %s or %s", scenario, scenarioCreateTeam, scenarioUI, scenarioJourney, scenarioTimezone, scenarioSecurity)
+		}
+		if s.crons[scenario], err = parseCron(expr); err != nil {
+			return s, fmt.Errorf("schedule of %s: %w", scenario, err)
+		}
+	}
+	return s, nil
+}
+
+// maxScheduleGap bounds the minutes checked after a long pause of the prober
+const maxScheduleGap = 24 * time.Hour
+
+// allows reports whether scenario runs at t: whether a minute since the previous check of the
+// scenario matches its expression, so that cycles longer than a minute or not aligned to the
+// minute do not miss it and shorter ones run it once. The first check only looks at the
+// minute of t. A skipped run is counted with the schedule reason.
+func (s scenarioSchedule) allows(scenario string, t time.Time) bool {
+	c, ok := s.crons[scenario]
+	if !ok {
+		return true
+	}
+	// the minutes starting within (from, t] are checked
+	from := t.Truncate(time.Minute).Add(-time.Nanosecond)
+	if prev, found := s.checked[scenario]; found && prev.Before(t) {
+		from = prev
+		if earliest := t.Add(-maxScheduleGap); from.Before(earliest) {
+			from = earliest
+		}
+	}
+	s.checked[scenario] = t
+	for m := from.Truncate(time.Minute).Add(time.Minute); !m.After(t); m = m.Add(time.Minute) {
+		if c.matches(m.In(s.location)) {
+			return true
+		}
+	}
+	scenarioSkipped.WithLabelValues(scenario, "schedule").Inc()
+	return false
+}
+
+// cronSchedule is a parsed cron expression, each field is the set of its allowed values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for a * day field: with both day fields restricted,
+	// a day matching either of them matches, as in cron
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	min, max int
+	names    []string
+}
+
+var cronFields = [5]cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// parseCron parses the 5 fields of a cron expression. A field is a comma separated list of *,
+// values or ranges a-b, each optionally stepped with /n. Months and days of week can be named
+// by their first three letters, Sunday is either 0 or 7.
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := cronFields[i].parse(field)
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if stepped {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return i + f.min, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%q is not within %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// matches reports whether the minute of t is allowed
+func (c cronSchedule) matches(t time.Time) bool {
+	has := func(set uint64, v int) bool { return set&(1<<v) != 0 }
+	if !has(c.minute, t.Minute()) || !has(c.hour, t.Hour()) || !has(c.month, int(t.Month())) {
+		return false
+	}
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// targetGroup is an entry of the Prometheus HTTP service discovery response
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels,omitempty" yaml:"labels"`
+}
+
+// discovery serves the targets of this prober and of its siblings for Prometheus HTTP SD
+type discovery struct {
+	self targetGroup
+	// peersFile lists the target groups of sibling probers in the file_sd format, read on each request
+	peersFile string
+}
+
+// newDiscovery advertises address with the comma separated k=v labels
+func newDiscovery(address, labels, peersFile, profile string) (*discovery, error) {
+	if address == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		address = fmt.Sprintf("%s:%d", host, port)
+	}
+	d := &discovery{
+		self: targetGroup{
+			Targets: []string{address},
+			Labels: map[string]string{
+				"__metrics_path__": "/probe",
+				"oncall_url":       oncallURL,
+				"profile":          profile,
+			},
+		},
+		peersFile: peersFile,
+	}
+	for _, kv := range strings.Split(labels, ",") {
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid sd label %q, expected key=value", kv)
+		}
+		d.self.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return d, nil
+}
+
+func (d *discovery) groups() ([]targetGroup, error) {
+	groups := []targetGroup{d.self}
+	if d.peersFile == "" {
+		return groups, nil
+	}
+	b, err := os.ReadFile(d.peersFile)
+	if err != nil {
+		return nil, err
+	}
+	var peers []targetGroup
+	if err = yaml.Unmarshal(b, &peers); err != nil {
+		return nil, fmt.Errorf("%s: %w", d.peersFile, err)
+	}
+	return append(groups, peers...), nil
+}
+
+func (d *discovery) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	groups, err := d.groups()
+	if err != nil {
+		// Prometheus keeps the previous targets when discovery fails
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(groups)
+}
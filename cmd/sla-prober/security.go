@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+const scenarioSecurity = "security"
+
+var (
+	securityScenarioTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "prober_security_scenario_total",
+		Help: "Total count of runs of the security scenario",
+	})
+	securityScenarioSuccess = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "prober_security_scenario_success_total",
+		Help: "Total count of runs of the security scenario where the page had every expected header and a valid certificate",
+	})
+	securityMissingHeader = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_security_missing_header_total",
+		Help: "Total count of runs of the security scenario where the page was served without the header",
+	}, []string{"header"})
+	tlsCertExpirySeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oncall_tls_cert_expiry_seconds",
+		Help: "Seconds until the earliest expiry among the certificates of the chain served by oncall, negative once expired, absent over http",
+	}, []string{"host"})
+)
+
+// securityHeaders returns the headers listed in -security-headers
+func securityHeaders() []string {
+	var headers []string
+	for _, h := range strings.Split(securityHeaderList, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	return headers
+}
+
+// probeSecurity fetches the -security-path page of the web UI, checks that it is served with
+// the -security-headers and records when the certificate of oncall expires. The scenario
+// fails on a missing header, and while the certificate expires within -security-cert-min-validity.
+func (a *app) probeSecurity(ctx context.Context, results cycleResults) {
+	logger := a.logger.With().Str("scenario", scenarioSecurity).Str("page", securityPath).Logger()
+
+	res, err := a.cl.Raw(ctx, http.MethodGet, securityPath, nil)
+	if err == nil && res.StatusCode == http.StatusTooManyRequests {
+		err = fmt.Errorf("%s: %w", securityPath, oncall.ErrRateLimited)
+	}
+	if a.throttled(scenarioSecurity, err, results) {
+		return
+	}
+	securityScenarioTotal.Inc()
+	if err != nil {
+		logger.Warn().Err(err).Msg("fetching page failed")
+		results.record(scenarioSecurity, false)
+		return
+	}
+	ok := true
+	for _, h := range securityHeaders() {
+		if res.Header.Get(h) == "" {
+			logger.Warn().Str("header", h).Msg("security header missing")
+			securityMissingHeader.WithLabelValues(h).Inc()
+			ok = false
+		}
+	}
+	if res.TLS != nil && len(res.TLS.PeerCertificates) > 0 {
+		cert := res.TLS.PeerCertificates[0]
+		for _, c := range res.TLS.PeerCertificates[1:] {
+			if c.NotAfter.Before(cert.NotAfter) {
+				cert = c
+			}
+		}
+		validity := time.Until(cert.NotAfter)
+		host := res.TLS.ServerName
+		if u, err := url.Parse(res.URL); err == nil {
+			host = u.Hostname()
+		}
+		tlsCertExpirySeconds.WithLabelValues(host).Set(validity.Seconds())
+		if securityCertMinValidity > 0 && validity < securityCertMinValidity {
+			logger.Warn().
+				Str("subject", cert.Subject.String()).
+				Time("not_after", cert.NotAfter).
+				Msg("certificate expires soon")
+			ok = false
+		}
+	}
+	if ok {
+		securityScenarioSuccess.Inc()
+	}
+	results.record(scenarioSecurity, ok)
+}
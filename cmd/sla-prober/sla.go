@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lordvidex/oncall-go-client/internal/sla"
+)
+
+const (
+	scenarioCreateTeam    = "create_team"
+	scenarioCreateUser    = "create_user"
+	scenarioAddUserToTeam = "add_user_to_team"
+	scenarioOverride      = "override"
+	scenarioUI            = "ui"
+)
+
+// scenarioResult counts the runs of a scenario in a single probe cycle. Throttled runs are
+// not part of the total.
+type scenarioResult struct {
+	total     int
+	success   int
+	throttled int
+}
+
+// cycleResults holds the in-memory results of a probe cycle keyed by scenario
+type cycleResults map[string]*scenarioResult
+
+func (r cycleResults) record(scenario string, ok bool) {
+	res := r.result(scenario)
+	res.total++
+	if ok {
+		res.success++
+	}
+}
+
+// throttle records a run of scenario that oncall rate limited
+func (r cycleResults) throttle(scenario string) {
+	r.result(scenario).throttled++
+}
+
+func (r cycleResults) result(scenario string) *scenarioResult {
+	res, found := r[scenario]
+	if !found {
+		res = &scenarioResult{}
+		r[scenario] = res
+	}
+	return res
+}
+
+// failed returns an error naming the scenarios of the cycle with failures, nil if there are none
+func (r cycleResults) failed() error {
+	var failed []string
+	for scenario, res := range r {
+		if res.success < res.total {
+			failed = append(failed, scenario)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	slices.Sort(failed)
+	return fmt.Errorf("failed scenarios: %s", strings.Join(failed, ", "))
+}
+
+// openSLAStore connects the prober to the SLA store when local evaluation is enabled
+func (a *app) openSLAStore(ctx context.Context, databaseURL string) error {
+	if err := sla.Migrate(databaseURL); err != nil {
+		return err
+	}
+	store, err := sla.New(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	a.store = store
+	return nil
+}
+
+// writeSLA stores the success rate of every scenario of a cycle as an sla_record,
+// so small deployments get SLA reports without running Prometheus and the checker
+func (a *app) writeSLA(ctx context.Context, results cycleResults) {
+	if a.store == nil {
+		return
+	}
+	for scenario, res := range results {
+		if res.total == 0 {
+			if res.throttled > 0 {
+				a.logger.Info().Str("scenario", scenario).Msg("every run throttled, no sla record")
+			}
+			continue
+		}
+		value := float64(res.success) / float64(res.total)
+		metric := "prober:" + scenario + "_scenario:success_rate"
+		err := a.store.Insert(ctx, "prober_"+scenario, metric, slaObjective, value, value >= slaObjective)
+		if err != nil {
+			a.logger.Error().Err(err).Str("metric", metric).Msg("error inserting to db")
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	successStreakDesc = prometheus.NewDesc(
+		"prober_scenario_success_streak",
+		"Number of consecutive cycles the scenario succeeded in, 0 if it failed in the last one",
+		[]string{"scenario"}, nil,
+	)
+	failureStreakDesc = prometheus.NewDesc(
+		"prober_scenario_failure_streak",
+		"Number of consecutive cycles the scenario failed in, 0 if it succeeded in the last one",
+		[]string{"scenario"}, nil,
+	)
+	sinceFailureDesc = prometheus.NewDesc(
+		"prober_scenario_seconds_since_last_failure",
+		"Seconds since the start of the last cycle the scenario failed in, absent if it never failed",
+		[]string{"scenario"}, nil,
+	)
+	mtbfDesc = prometheus.NewDesc(
+		"prober_scenario_mtbf_seconds",
+		"Mean time between failures of the scenario: the seconds it was probed divided by its failure streaks, absent if it never failed",
+		[]string{"scenario"}, nil,
+	)
+)
+
+// streak is the history of a scenario across the cycles since the prober started
+type streak struct {
+	successes int
+	failures  int
+	// incidents counts the failure streaks, a scenario failing for several cycles in a row
+	// fails once
+	incidents   int
+	first       time.Time
+	lastFailure time.Time
+}
+
+// scenarioStats keeps the streaks of every scenario in memory, so that responders see on
+// /probe whether a failure is a blip or a trend without querying Prometheus
+type scenarioStats struct {
+	mu      sync.Mutex
+	streaks map[string]*streak
+	now     func() time.Time
+}
+
+func newScenarioStats() *scenarioStats {
+	return &scenarioStats{streaks: make(map[string]*streak), now: time.Now}
+}
+
+// observe updates the streaks with the results of the cycle started at start. A scenario
+// fails a cycle when any of its runs failed, scenarios without runs, skipped or fully
+// throttled, keep their streaks.
+func (s *scenarioStats) observe(results cycleResults, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for scenario, res := range results {
+		if res.total == 0 {
+			continue
+		}
+		st, found := s.streaks[scenario]
+		if !found {
+			st = &streak{first: start}
+			s.streaks[scenario] = st
+		}
+		if res.success == res.total {
+			st.successes++
+			st.failures = 0
+			continue
+		}
+		if st.failures == 0 {
+			st.incidents++
+		}
+		st.failures++
+		st.successes = 0
+		st.lastFailure = start
+	}
+}
+
+func (s *scenarioStats) Describe(ch chan<- *prometheus.Desc) {
+	ch <- successStreakDesc
+	ch <- failureStreakDesc
+	ch <- sinceFailureDesc
+	ch <- mtbfDesc
+}
+
+func (s *scenarioStats) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for scenario, st := range s.streaks {
+		ch <- prometheus.MustNewConstMetric(successStreakDesc, prometheus.GaugeValue, float64(st.successes), scenario)
+		ch <- prometheus.MustNewConstMetric(failureStreakDesc, prometheus.GaugeValue, float64(st.failures), scenario)
+		if st.incidents == 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(sinceFailureDesc, prometheus.GaugeValue, now.Sub(st.lastFailure).Seconds(), scenario)
+		ch <- prometheus.MustNewConstMetric(mtbfDesc, prometheus.GaugeValue, now.Sub(st.first).Seconds()/float64(st.incidents), scenario)
+	}
+}
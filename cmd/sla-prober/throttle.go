@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+// rateLimitedDesc counts the requests of the client answered with 429, see oncall.Client.RateLimited
+var rateLimitedDesc = prometheus.NewDesc(
+	"oncall_client_rate_limited_total",
+	"Total count of requests oncall answered with 429 Too Many Requests, by endpoint",
+	[]string{"endpoint"}, nil,
+)
+
+// rateLimitedCollector exports the rate limited counts of the client
+type rateLimitedCollector struct {
+	cl oncall.API
+}
+
+func (c rateLimitedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rateLimitedDesc
+}
+
+func (c rateLimitedCollector) Collect(ch chan<- prometheus.Metric) {
+	for endpoint, n := range c.cl.RateLimited() {
+		ch <- prometheus.MustNewConstMetric(rateLimitedDesc, prometheus.CounterValue, float64(n), endpoint)
+	}
+}
+
+// throttled reports whether err is oncall rate limiting the prober. A throttled run is
+// counted as skipped with the throttled reason instead of failed, so that the SLA does not
+// account for throttling the prober caused itself.
+func (a *app) throttled(scenario string, err error, results cycleResults) bool {
+	if !errors.Is(err, oncall.ErrRateLimited) {
+		return false
+	}
+	a.logger.Info().Err(err).Str("scenario", scenario).Msg("scenario throttled by oncall")
+	scenarioSkipped.WithLabelValues(scenario, "throttled").Inc()
+	results.throttle(scenario)
+	return true
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+const scenarioTimezone = "timezone"
+
+var (
+	timezoneScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_timezone_scenario_total",
+		Help: "Total count of runs of the timezone scenario, by scheduling timezone",
+	}, []string{"timezone"})
+	timezoneScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_timezone_scenario_success_total",
+		Help: "Total count of runs of the timezone scenario whose times round-tripped, by scheduling timezone",
+	}, []string{"timezone"})
+	timezoneFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_timezone_failures_total",
+		Help: "Total count of failed timezone checks, by scheduling timezone and check: create, team, day or dst",
+	}, []string{"timezone", "check"})
+)
+
+// timezoneMatrix creates a probe team per scheduling timezone every cycle and checks that oncall
+// returns the local day events of the team unchanged, tomorrow and on the next DST transition
+// day of the timezone, which lasts 23 or 25 hours.
+type timezoneMatrix struct {
+	// Timezones are the IANA scheduling timezones of the probe teams
+	Timezones []string `yaml:"timezones"`
+	// Prefix starts the names of the probe teams and users, it must match -delete-allow
+	Prefix string `yaml:"prefix"`
+	// Role is the role of the probe events
+	Role string `yaml:"role"`
+
+	locations map[string]*time.Location
+}
+
+// loadTimezoneMatrix reads the timezone_matrix block of the probe config, the scenario is
+// disabled without it
+func loadTimezoneMatrix(filename string) (timezoneMatrix, error) {
+	var m timezoneMatrix
+	b, err := oncall.ReadConfig(filename, configOptions()...)
+	if err != nil {
+		return m, err
+	}
+	var cfg struct {
+		Matrix *timezoneMatrix `yaml:"timezone_matrix"`
+	}
+	if err = yaml.Unmarshal(b, &cfg); err != nil || cfg.Matrix == nil {
+		return m, err
+	}
+	m = *cfg.Matrix
+	if m.Prefix == "" {
+		m.Prefix = "probe-tz"
+	}
+	if m.Role == "" {
+		m.Role = "primary"
+	}
+	m.locations = make(map[string]*time.Location, len(m.Timezones))
+	for _, tz := range m.Timezones {
+		if m.locations[tz], err = time.LoadLocation(tz); err != nil {
+			return m, fmt.Errorf("timezone_matrix: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// teamName is the name of the probe team of tz, e.g. probe-tz-america-new-york
+func (m timezoneMatrix) teamName(tz string) string {
+	return m.Prefix + "-" + strings.NewReplacer("/", "-", "_", "-").Replace(strings.ToLower(tz))
+}
+
+// config is the probe team of every timezone, each with a single user named after the team.
+// The names are those of the stable strategy.
+func (m timezoneMatrix) config() oncall.Config {
+	var cfg oncall.Config
+	for _, tz := range m.Timezones {
+		name := m.teamName(tz)
+		cfg.Teams = append(cfg.Teams, oncall.Team{
+			Name:               name,
+			SchedulingTimezone: tz,
+			Users:              []oncall.User{{Name: name}},
+		})
+	}
+	return cfg
+}
+
+// runTimezones runs the timezone scenario for every timezone of the matrix
+func (a *app) runTimezones(ctx context.Context, c cycle, results cycleResults) {
+	names := a.naming.strategy(scenarioTimezone)
+	cfg := a.naming.apply(a.timezones.config(), names, names, c)
+	report, err := a.cl.CreateEntities(ctx, cfg)
+	a.track(report)
+	defer a.cleanup(cfg, !names.stable())
+	if err != nil {
+		a.logger.Warn().Err(err).Str("scenario", scenarioTimezone).Msg("entities error")
+	}
+	now := time.Now()
+	for i, tz := range a.timezones.Timezones {
+		t := cfg.Teams[i]
+		ok, err := a.probeTimezone(ctx, t, report, now)
+		if a.throttled(scenarioTimezone, err, results) {
+			continue
+		}
+		timezoneScenarioTotal.WithLabelValues(tz).Inc()
+		if ok {
+			timezoneScenarioSuccess.WithLabelValues(tz).Inc()
+		}
+		results.record(scenarioTimezone, ok)
+	}
+}
+
+// probeTimezone checks that the team kept its scheduling timezone and that the events of
+// whole local days keep their times. The error is the first rate limited request, the run
+// being throttled rather than failed when no other check failed.
+func (a *app) probeTimezone(ctx context.Context, t oncall.Team, report *oncall.EntityReport, now time.Time) (bool, error) {
+	tz := t.SchedulingTimezone
+	logger := a.logger.With().Str("scenario", scenarioTimezone).Str("timezone", tz).Logger()
+	var (
+		failed  bool
+		limited error
+	)
+	fail := func(check string, err error) (bool, error) {
+		if !errors.Is(err, oncall.ErrRateLimited) {
+			logger.Warn().Err(err).Str("check", check).Msg("timezone check failed")
+			timezoneFailures.WithLabelValues(tz, check).Inc()
+			failed = true
+		} else if limited == nil {
+			limited = err
+		}
+		if failed {
+			return false, nil
+		}
+		return false, limited
+	}
+
+	var team *oncall.TeamReport
+	if report != nil {
+		team, _ = report.Team(t.Name)
+	}
+	if team == nil {
+		return fail("create", errors.New("team not created"))
+	}
+	if !team.Create.Succeeded() && team.Create.Outcome != oncall.OutcomeSkipped {
+		return fail("create", fmt.Errorf("team not created: %w", team.Create.Err))
+	}
+	u, found := team.User(t.Users[0].Name)
+	if !found {
+		return fail("create", errors.New("user not added to the team"))
+	}
+	if u.AddToTeam.Outcome == oncall.OutcomeFailed {
+		return fail("create", fmt.Errorf("user not added to the team: %w", u.AddToTeam.Err))
+	}
+
+	res, err := a.cl.GetTeam(ctx, t.Name)
+	if err != nil {
+		return fail("team", err)
+	}
+	if res.Data.SchedulingTimezone != tz {
+		return fail("team", fmt.Errorf("scheduling timezone is %q", res.Data.SchedulingTimezone))
+	}
+
+	loc := a.timezones.locations[tz]
+	if err = a.roundTripDay(ctx, t, localDay(now, loc, 1)); err != nil {
+		fail("day", err)
+	}
+	if day, found := nextTransition(now, loc); found {
+		if err = a.roundTripDay(ctx, t, day); err != nil {
+			fail("dst", err)
+		}
+	}
+	if failed {
+		return false, nil
+	}
+	return limited == nil, limited
+}
+
+// roundTripDay creates an event of the user of t from the local midnight day to the next one
+// and checks that oncall returns the same times, deleting the event afterwards
+func (a *app) roundTripDay(ctx context.Context, t oncall.Team, day time.Time) error {
+	start := day
+	end := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	res, err := a.cl.CreateLinkedEvents(ctx, []dto.ScheduleDTO{{
+		Username:      t.Users[0].Name,
+		Teamname:      t.Name,
+		Role:          a.timezones.Role,
+		StartTimeUnix: start.Unix(),
+		EndTimeUnix:   end.Unix(),
+	}})
+	if err != nil {
+		return err
+	}
+	if len(res.Data.EventIDs) != 1 {
+		return fmt.Errorf("created %d events instead of 1", len(res.Data.EventIDs))
+	}
+	id := res.Data.EventIDs[0]
+	defer func() {
+		if err := a.cl.DeleteEvent(context.Background(), id); err != nil {
+			a.logger.Warn().Err(err).Int64("event", id).Msg("failed to delete timezone probe event")
+		}
+	}()
+	e, err := a.cl.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.Data.Start != start.Unix() || e.Data.End != end.Unix() {
+		return fmt.Errorf("event of %s returned as %s - %s",
+			start.Format(time.RFC3339),
+			time.Unix(e.Data.Start, 0).In(day.Location()).Format(time.RFC3339),
+			time.Unix(e.Data.End, 0).In(day.Location()).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// localDay returns the local midnight days after the day of t in loc
+func localDay(t time.Time, loc *time.Location, days int) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, loc)
+}
+
+// nextTransition returns the local midnight of the next day within a year whose UTC offset
+// changes before its end, false for timezones without DST
+func nextTransition(t time.Time, loc *time.Location) (time.Time, bool) {
+	for i := 1; i <= 366; i++ {
+		day, next := localDay(t, loc, i), localDay(t, loc, i+1)
+		_, from := day.Zone()
+		_, to := next.Zone()
+		if from != to {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+var (
+	uiScenarioTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_ui_scenario_total",
+		Help: "Total count of runs of the web UI scenario, by page",
+	}, []string{"page"})
+	uiScenarioSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prober_ui_scenario_success_total",
+		Help: "Total count of runs of the web UI scenario where the page was served with its marker",
+	}, []string{"page"})
+	uiScenarioDurationSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prober_ui_scenario_duration_seconds",
+		Help: "Duration of the last fetch of a web UI page",
+	}, []string{"page"})
+)
+
+// uiPages returns the paths of the web UI pages listed in -ui-paths
+func uiPages() []string {
+	var pages []string
+	for _, p := range strings.Split(uiPaths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// probeUI fetches the pages of the web UI and checks that they are served with the
+// -ui-marker in their body. The UI is measured apart from the API scenarios since it can be
+// down while the API still answers.
+func (a *app) probeUI(ctx context.Context, results cycleResults) {
+	marker := []byte(strings.ToLower(uiMarker))
+	for _, page := range uiPages() {
+		labels := prometheus.Labels{"page": page}
+		logger := a.logger.With().Str("scenario", scenarioUI).Str("page", page).Logger()
+
+		res, err := a.cl.Raw(ctx, http.MethodGet, page, nil)
+		if err == nil && res.StatusCode == http.StatusTooManyRequests {
+			err = fmt.Errorf("%s: %w", page, oncall.ErrRateLimited)
+		}
+		if a.throttled(scenarioUI, err, results) {
+			continue
+		}
+		uiScenarioTotal.With(labels).Inc()
+		if err != nil {
+			logger.Warn().Err(err).Msg("fetching page failed")
+			results.record(scenarioUI, false)
+			continue
+		}
+		uiScenarioDurationSeconds.With(labels).Set(res.ResponseTime.Seconds())
+		if res.StatusCode != http.StatusOK {
+			logger.Warn().Int("status_code", res.StatusCode).Msg("unexpected status")
+			results.record(scenarioUI, false)
+			continue
+		}
+		if !bytes.Contains(bytes.ToLower(res.Data), marker) {
+			logger.Warn().Str("marker", uiMarker).Msg("marker not found in page")
+			results.record(scenarioUI, false)
+			continue
+		}
+		uiScenarioSuccess.With(labels).Inc()
+		results.record(scenarioUI, true)
+	}
+}
@@ -0,0 +1,122 @@
+// Package exitcode defines the exit codes shared by the commands, so that CI pipelines and
+// wrappers can branch on the kind of failure without parsing logs
+package exitcode
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+)
+
+const (
+	OK = 0
+	// Failure is any error not classified by the codes below
+	Failure = 1
+	// Config means the flags, the environment or a config file could not be read
+	Config = 2
+	// Auth means oncall rejected the credentials or the permissions of the command
+	Auth = 3
+	// Partial means some changes were applied and others failed
+	Partial = 4
+	// Unreachable means oncall, or another service the command relies on, could not be reached
+	Unreachable = 5
+	// Validation means the config was read but is not valid, or oncall rejected it as invalid
+	Validation = 6
+)
+
+var names = map[int]string{
+	OK:          "ok",
+	Failure:     "failure",
+	Config:      "config_error",
+	Auth:        "auth_failure",
+	Partial:     "partial_apply",
+	Unreachable: "unreachable",
+	Validation:  "validation_failure",
+}
+
+// Name is the error class of code, logged next to the error, e.g. "auth_failure"
+func Name(code int) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return names[Failure]
+}
+
+// Error sets the exit code of an error that cannot be classified from its cause,
+// e.g. a config file that does not parse
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap sets the exit code of err, nil stays nil
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the exit code of err. The failures of a *oncall.MultiError are Partial unless they
+// are all of the same class.
+func Of(err error) int {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var multi *oncall.MultiError
+	if errors.As(err, &multi) && len(multi.Errors) > 0 {
+		code := Of(multi.Errors[0].Err)
+		for _, entity := range multi.Errors[1:] {
+			if Of(entity.Err) != code {
+				return Partial
+			}
+		}
+		if code == Failure {
+			return Partial
+		}
+		return code
+	}
+
+	var (
+		apiErr *oncall.APIError
+		netErr net.Error
+	)
+	switch {
+	case errors.Is(err, oncall.ErrUnauthorized), errors.Is(err, oncall.ErrForbidden), errors.Is(err, oncall.ErrLoginFailed):
+		return Auth
+	case errors.Is(err, oncall.ErrInvalidRequest):
+		return Validation
+	case errors.Is(err, oncall.ErrCircuitOpen), errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return Unreachable
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.StatusCode == http.StatusBadRequest, apiErr.StatusCode == http.StatusUnprocessableEntity:
+			return Validation
+		case apiErr.StatusCode == http.StatusBadGateway, apiErr.StatusCode == http.StatusServiceUnavailable,
+			apiErr.StatusCode == http.StatusGatewayTimeout:
+			return Unreachable
+		}
+	}
+	return Failure
+}
+
+// Fatal logs err with its class and exits with its code
+func Fatal(logger zerolog.Logger, err error, msg string) {
+	code := Of(err)
+	logger.Error().Err(err).Str("class", Name(code)).Int("exit_code", code).Msg(msg)
+	os.Exit(code)
+}
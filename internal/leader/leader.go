@@ -0,0 +1,96 @@
+// Package leader elects a single active replica among processes sharing a postgres database
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// Elector holds a postgres session-level advisory lock while it is the leader.
+// The lock is released by postgres when the session ends, so a crashed leader
+// is replaced once its connection is closed.
+type Elector struct {
+	databaseURL string
+	key         int64
+	logger      zerolog.Logger
+
+	conn   *pgx.Conn
+	leader atomic.Bool
+}
+
+// New creates an elector competing for the advisory lock key. It is not the leader until Run acquires the lock.
+func New(databaseURL string, key int64, logger zerolog.Logger) *Elector {
+	return &Elector{databaseURL: databaseURL, key: key, logger: logger}
+}
+
+// IsLeader reports whether the elector currently holds the lock
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run tries to acquire the lock every interval, and to keep it once acquired, until ctx is done.
+// onChange is called whenever leadership is gained or lost.
+func (e *Elector) Run(ctx context.Context, interval time.Duration, onChange func(leader bool)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer e.close()
+	for {
+		leader := e.check(ctx)
+		if e.leader.Swap(leader) != leader {
+			e.logger.Info().Bool("leader", leader).Msg("leadership changed")
+			if onChange != nil {
+				onChange(leader)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check acquires the lock if needed and reports whether it is held
+func (e *Elector) check(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if e.conn == nil {
+		conn, err := pgx.Connect(ctx, e.databaseURL)
+		if err != nil {
+			e.logger.Warn().Err(err).Msg("error connecting to leader election database")
+			return false
+		}
+		e.conn = conn
+	}
+	if e.leader.Load() {
+		// the lock lives as long as the session, a healthy connection is enough
+		if err := e.conn.Ping(ctx); err != nil {
+			e.logger.Warn().Err(err).Msg("lost leader election session")
+			e.close()
+			return false
+		}
+		return true
+	}
+	var acquired bool
+	if err := e.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil {
+		e.logger.Warn().Err(err).Msg("error acquiring leader lock")
+		e.close()
+		return false
+	}
+	return acquired
+}
+
+func (e *Elector) close() {
+	if e.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = e.conn.Close(ctx)
+	e.conn = nil
+}
@@ -0,0 +1,75 @@
+// Package notify renders and delivers notifications about SLA breaches and on-call shifts
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Kind is the reason of a notification
+type Kind string
+
+const (
+	KindBreach     Kind = "breach"
+	KindHandoff    Kind = "handoff"
+	KindReminder   Kind = "reminder"
+	KindRegression Kind = "regression"
+)
+
+// Event is the data available to notification templates
+type Event struct {
+	Kind Kind
+	Time time.Time
+
+	// SLA fields, set for breaches and regressions
+	Alias  string
+	Metric string
+	SLO    float64
+	Value  float64
+	// Baseline is the value Value regressed from, set for regressions
+	Baseline float64
+
+	// on-call fields, set for handoffs and reminders. Team is also set for breaches of metrics
+	// owned by a team.
+	Team string
+	User string
+	Role string
+
+	// OnCall are the users on call for Team when a breach is notified
+	OnCall []Responder
+
+	// Labels carries operator defined metadata such as runbook links or owners
+	Labels map[string]string
+}
+
+// Responder is a user on call, with the contacts oncall knows
+type Responder struct {
+	User     string
+	FullName string
+	Role     string
+	Email    string
+	Phone    string
+	Slack    string
+}
+
+// Notifier delivers notifications to a channel
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// LogNotifier writes rendered notifications to a logger, using the templates of the "log" channel
+type LogNotifier struct {
+	L         zerolog.Logger
+	Templates *Templates
+}
+
+func (n *LogNotifier) Notify(_ context.Context, e Event) error {
+	msg, err := n.Templates.Render("log", e)
+	if err != nil {
+		return err
+	}
+	n.L.Warn().Str("kind", string(e.Kind)).Msg(msg)
+	return nil
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// defaultTemplates are used for kinds without an operator supplied template
+var defaultTemplates = map[Kind]string{
+	KindBreach:     `SLA breach on {{ .Alias }}: {{ .Value | printf "%.4g" }} against objective {{ .SLO | printf "%.4g" }} ({{ .Metric }}){{ with .OnCall }}, on call for {{ $.Team }}:{{ range $i, $r := . }}{{ if $i }},{{ end }} {{ $r.User }} ({{ $r.Role }}{{ with $r.Phone }}, {{ . }}{{ end }}){{ end }}{{ end }}`,
+	KindHandoff:    `Handoff for {{ .Team }}: {{ .User }} is now {{ .Role }}`,
+	KindReminder:   `Reminder: {{ .User }} is {{ .Role }} for {{ .Team }} from {{ .Time.Format "2006-01-02 15:04 MST" }}`,
+	KindRegression: `Latency regression on {{ .Alias }}: median {{ .Value | printf "%.4g" }} against {{ .Baseline | printf "%.4g" }} over the previous weeks ({{ .Metric }})`,
+}
+
+// Templates renders notification bodies per channel and kind
+type Templates struct {
+	byName map[string]*template.Template
+}
+
+// LoadTemplates parses every "<channel>.<kind>.tmpl" file of dir, e.g. "slack.breach.tmpl".
+// Templates have access to the sprig function library. An empty dir only provides the defaults.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{byName: make(map[string]*template.Template)}
+	for kind, text := range defaultTemplates {
+		tmpl, err := template.New(string(kind)).Funcs(sprig.TxtFuncMap()).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		t.byName[string(kind)] = tmpl
+	}
+	if dir == "" {
+		return t, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".tmpl")
+		if strings.Count(name, ".") != 1 {
+			return nil, fmt.Errorf("template %s: name must be <channel>.<kind>.tmpl", f)
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(name).Funcs(sprig.TxtFuncMap()).Parse(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", f, err)
+		}
+		t.byName[name] = tmpl
+	}
+	return t, nil
+}
+
+// Render executes the template of the channel for the kind of e, falling back to the default one
+func (t *Templates) Render(channel string, e Event) (string, error) {
+	tmpl, ok := t.byName[channel+"."+string(e.Kind)]
+	if !ok {
+		if tmpl, ok = t.byName[string(e.Kind)]; !ok {
+			return "", fmt.Errorf("no template for %s notifications", e.Kind)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
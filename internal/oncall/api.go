@@ -0,0 +1,78 @@
+package oncall
+
+import (
+	"context"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// API is the set of oncall operations implemented by *Client.
+// Consumers should depend on it so they can be tested with the mock package.
+type API interface {
+	Login(ctx context.Context) error
+	CallCounts() map[string]int64
+	Relogins() int64
+	Hedges() int64
+	RateLimited() map[string]int64
+	BreakerState() BreakerState
+	Raw(ctx context.Context, method, path string, body []byte) (*Response[[]byte], error)
+
+	CreateEntities(ctx context.Context, config Config, opts ...EntityOption) (*EntityReport, error)
+	DeleteEntities(ctx context.Context, config Config, opts DeleteOptions) (*DeleteReport, error)
+	Snapshot(ctx context.Context, opts SnapshotOptions) (*ServerState, error)
+	Sync(ctx context.Context, config Config, opts ...SyncOption) (*SyncReport, error)
+	ExportConfig(ctx context.Context, teams ...string) (Config, error)
+
+	CreateTeam(ctx context.Context, t Team, returnEarly bool) (*TeamReport, error)
+	UpdateTeam(ctx context.Context, name string, t Team) (*Response[any], error)
+	DeleteTeam(ctx context.Context, team string) error
+	GetTeams(ctx context.Context) (*Response[[]string], error)
+	ListTeams(ctx context.Context, filter TeamFilter) (*Response[[]string], error)
+	TeamsIterator(filter TeamFilter, pageSize int) *TeamsIterator
+	GetTeam(ctx context.Context, name string) (*Response[TeamRecord], error)
+	GetSummary(ctx context.Context, team string) (*Response[Summary], error)
+	GetOnCall(ctx context.Context, team string) (*Response[map[string][]string], error)
+	GetServices(ctx context.Context, team string) (*Response[[]string], error)
+	AddService(ctx context.Context, team, service string) (*Response[any], error)
+	DeleteService(ctx context.Context, team, service string) error
+	GetAdmins(ctx context.Context, team string) (*Response[[]string], error)
+	AddAdmin(ctx context.Context, team, user string) (*Response[any], error)
+	DeleteAdmin(ctx context.Context, team, user string) error
+
+	GetUsers(ctx context.Context, filter UserFilter) (*Response[[]UserRecord], error)
+	GetUser(ctx context.Context, name string) (*Response[UserRecord], error)
+	CreateUser(ctx context.Context, u User) (*Response[any], error)
+	UpdateUser(ctx context.Context, name string, u User) (*Response[any], error)
+	DeleteUser(ctx context.Context, name string) error
+	ReactivateUser(ctx context.Context, name string) (*Response[any], error)
+	GetNotifications(ctx context.Context, user string) (*Response[[]NotificationRecord], error)
+	CreateNotification(ctx context.Context, user, team string, n Notification) (*Response[any], error)
+	UpdateNotification(ctx context.Context, id int64, team string, n Notification) (*Response[any], error)
+	DeleteNotification(ctx context.Context, user string, id int64) error
+	AddUserToTeam(ctx context.Context, username, teamname string) (*Response[any], error)
+	DeleteUserFromTeam(ctx context.Context, user, team string) error
+
+	CreateRoster(ctx context.Context, team, name string) (*Response[any], error)
+	DeleteRoster(ctx context.Context, team, name string) error
+	AddUserToRoster(ctx context.Context, team, roster, user string, inRotation bool) (*Response[any], error)
+	RemoveUserFromRoster(ctx context.Context, team, roster, user string) error
+	SetRosterUserScheduling(ctx context.Context, team, roster, user string, inRotation bool) (*Response[any], error)
+	CreateRosterSchedule(ctx context.Context, team, roster string, s RosterSchedule) (*Response[int64], error)
+	SetScheduler(ctx context.Context, scheduleID int64, name string, order []string) (*Response[any], error)
+	PopulateSchedule(ctx context.Context, scheduleID int64, start time.Time) (*Response[any], error)
+
+	GetRoles(ctx context.Context) (*Response[[]RoleRecord], error)
+	Roles(ctx context.Context) ([]string, error)
+
+	CreateSchedule(ctx context.Context, username, teamname, tz string, schedule []Duty) error
+	GetEvents(ctx context.Context, filter EventFilter) (*Response[[]Event], error)
+	GetEvent(ctx context.Context, id int64) (*Response[Event], error)
+	CreateLinkedEvents(ctx context.Context, events []dto.ScheduleDTO) (*Response[LinkedEvents], error)
+	UpdateEvent(ctx context.Context, id int64, data dto.ScheduleDTO) (*Response[any], error)
+	DeleteEvent(ctx context.Context, id int64) error
+	SwapEvents(ctx context.Context, eventsA, eventsB []int64) (*Response[any], error)
+	OverrideEvents(ctx context.Context, r OverrideRequest) (*Response[any], error)
+}
+
+var _ API = (*Client)(nil)
@@ -0,0 +1,135 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting oncall while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of the circuit breaker of a client
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails requests fast until the cooldown elapses
+	BreakerOpen
+	// BreakerHalfOpen lets a single request through to probe whether oncall recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// breaker opens after threshold consecutive failures and probes oncall again after cooldown
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// WithCircuitBreaker fails requests with ErrCircuitOpen after threshold consecutive failures
+// (transport errors and 5xx responses, once retries are exhausted). After cooldown a single
+// request is let through: the circuit closes if it succeeds and opens again otherwise.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		if threshold <= 0 {
+			c.breaker = nil
+			return
+		}
+		c.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+	}
+}
+
+// BreakerState returns the state of the circuit breaker, always BreakerClosed without WithCircuitBreaker
+func (c *Client) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	if c.breaker.state == BreakerOpen && time.Since(c.breaker.openedAt) >= c.breaker.cooldown {
+		return BreakerHalfOpen
+	}
+	return c.breaker.state
+}
+
+// allow reports whether a request may be sent. In the half-open state only the probe may.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+	switch b.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// release lets another request probe oncall when the probe was canceled
+func (b *breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record updates the breaker with the outcome of an allowed request and returns the new state
+// when it changed
+func (b *breaker) record(failed bool) (BreakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	prev := b.state
+	b.probing = false
+	if !failed {
+		b.state, b.failures = BreakerClosed, 0
+		return b.state, prev != b.state
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state, b.openedAt = BreakerOpen, time.Now()
+	}
+	return b.state, prev != b.state
+}
+
+// doBreaker sends req unless the circuit is open, and records whether oncall failed it
+func (c *Client) doBreaker(req *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.doSession(req)
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := c.doSession(req)
+	if errors.Is(err, context.Canceled) {
+		// the caller gave up, this says nothing about oncall
+		c.breaker.release()
+		return res, err
+	}
+	if state, changed := c.breaker.record(isTransient(res, err)); changed {
+		c.logger.Warn().Str("state", state.String()).Str("url", req.URL.Path).Msg("circuit breaker changed state")
+	}
+	return res, err
+}
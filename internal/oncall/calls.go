@@ -0,0 +1,34 @@
+package oncall
+
+import "sync"
+
+// callCounter counts the HTTP requests sent to oncall by method
+type callCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *callCounter) inc(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[method]++
+}
+
+func (c *callCounter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		res[k] = v
+	}
+	return res
+}
+
+// CallCounts returns the number of HTTP requests sent by the client since it was created,
+// keyed by HTTP method. Retried attempts are counted individually.
+func (c *Client) CallCounts() map[string]int64 {
+	return c.calls.snapshot()
+}
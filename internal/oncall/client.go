@@ -1,21 +1,25 @@
 package oncall
 
 import (
-	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"os"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
-	"gopkg.in/yaml.v3"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
 )
@@ -25,15 +29,11 @@ const (
 	teamsEndpoint    = "/api/v0/teams/"
 	usersEndpoint    = "/api/v0/users/"
 	scheduleEndpoint = "/api/v0/events/"
+	// schedulesEndpoint serves the roster schedules, not to be confused with events
+	schedulesEndpoint = "/api/v0/schedules/"
 )
 
-var (
-	ErrLoginFailed     = errors.New("login failed")
-	ErrInvalidEndpoint = errors.New("invalid endpoint")
-	ErrInvalidRequest  = errors.New("invalid request")
-)
-
-var defaultTimeout = time.Second * 10
+const defaultTimeout = time.Second * 10
 
 // Client is the handler that makes request to oncall server for this client app
 type Client struct {
@@ -41,7 +41,32 @@ type Client struct {
 	logger    zerolog.Logger
 
 	httpClient *http.Client
-	csrfToken  string
+	// csrfToken holds the CSRF token string of the session, replaced by each login while
+	// requests are sent
+	csrfToken atomic.Value
+	csrf      csrfPolicy
+	retry     retryPolicy
+	hedge     hedgePolicy
+	timeout   time.Duration
+	calls     callCounter
+	guard     deleteGuard
+	appAuth   *appAuth
+	session   session
+	limiter   *tokenBucket
+	throttle  throttle
+	roles     roleCache
+	breaker   *breaker
+	hooks     hooks
+	slashes   SlashStyle
+	tracer    trace.Tracer
+	tls       *tls.Config
+	proxy     func(*http.Request) (*url.URL, error)
+	transport *http.Transport
+	dryRun    dryRun
+	// upsert makes CreateEntities update existing teams and users, see WithUpsert
+	upsert bool
+	// optErrs are the errors of the options, returned by New
+	optErrs []error
 }
 
 // Option is a callback for passing parameters to *Client
@@ -60,6 +85,34 @@ func WithLogger(l zerolog.Logger) Option {
 	}
 }
 
+// WithTimeout sets the timeout applied to each attempt of the requests made by the client, see
+// WithRetry. It defaults to 10s
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+type timeoutKey struct{}
+
+// WithRequestTimeout returns a context that overrides the client timeout of each attempt for calls
+// made with it. The deadline of ctx, if any, bounds all the attempts of a call.
+func WithRequestTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// withTimeout bounds ctx by the per-call timeout if one is set, or by the client timeout
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := c.timeout
+	if v, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		d = v
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 // New creates a new oncall Client and logs in the client. An error can also be returned.
 func New(opts ...Option) (*Client, error) {
 	// create jar to store cookoo
@@ -75,12 +128,20 @@ func New(opts ...Option) (*Client, error) {
 		httpClient: &http.Client{
 			Jar: cookieJar,
 		},
+		timeout: defaultTimeout,
+		csrf:    csrfPolicy{header: defaultCSRFHeader},
 	}
 	for _, opt := range opts {
 		opt(client)
 	}
+	if err = client.applyTransport(); err != nil {
+		return nil, err
+	}
 
-	// login the client
+	// login the client, applications sign each request instead
+	if client.appAuth != nil {
+		return client, nil
+	}
 	err = client.Login(context.Background())
 	if err != nil {
 		return nil, err
@@ -88,16 +149,17 @@ func New(opts ...Option) (*Client, error) {
 	return client, nil
 }
 
+// Login opens a session on the oncall server. It does nothing for clients using WithAppAuth.
 func (c *Client) Login(ctx context.Context) error {
 	logger := c.logger.With().Str("action", "login").Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, loginEndpoint)
+	if c.appAuth != nil {
+		return nil
+	}
+	endpoint, err := c.endpoint(loginEndpoint)
 	if err != nil {
 		return ErrInvalidEndpoint
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
-	defer cancel()
-
 	data := url.Values{}
 	data.Set("username", "root")
 	data.Set("password", "root")
@@ -107,67 +169,128 @@ func (c *Client) Login(ctx context.Context) error {
 		return ErrLoginFailed
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=UTF-8")
-	res, err := c.httpClient.Do(req)
+	res, err := c.do(req)
 	if err != nil {
 		logger.Error().Caller().Err(err).Send()
 		return ErrLoginFailed
 	}
 	defer res.Body.Close()
+	if err = checkResponse(res); err != nil {
+		logger.Error().Err(err).Send()
+		return errors.Join(ErrLoginFailed, err)
+	}
 
 	m := make(map[string]string)
 	json.NewDecoder(res.Body).Decode(&m)
 	logger.Info().Int("status_code", res.StatusCode).Interface("response", m).Send()
-	c.csrfToken = m["csrf_token"]
+	c.csrfToken.Store(m["csrf_token"])
+	c.session.logins.Add(1)
 	return nil
 }
 
-// LoadConfig reads a yaml file and creates the entities (teams, users and schedules) in this file
-func LoadConfig(filename string) (Config, error) {
+// LoadConfig reads a yaml, json or toml file, see WithFormat, and creates the entities (teams,
+// users and schedules) in this file.
+// Encrypted files are decrypted and environment variables expanded first, see ReadConfig.
+// The file is checked against the schema of Config before it is decoded: unknown keys,
+// missing required fields and misformatted dates, times and timezones are returned in a
+// *SchemaError locating each of them by line and column.
+//
+// filename can also be a directory of config files, e.g. one per team, whose teams are merged
+// in the order of the file names. A team defined by two files is a conflict returned in the
+// *SchemaError. The files of a directory are read in the format of their extension.
+func LoadConfig(filename string, opts ...LoadOption) (Config, error) {
 	var config Config
-	file, err := os.Open(filename)
-	if err != nil {
-		return config, err
-	}
-	defer file.Close()
-
-	err = yaml.NewDecoder(file).Decode(&config)
+	o := newLoadOptions(opts)
+	nodes, err := readConfigNodes(filename, o)
 	if err != nil {
 		return config, err
 	}
-	return config, err
-}
-
-// func (c *Client)
-
-func (c *Client) CreateEntities(config Config) (map[string]*TeamResponse, error) {
-	res := make(map[string]*TeamResponse)
-	var errs []error
-	for _, t := range config.Teams {
-		v, err := c.CreateTeam(t, false)
-		if err != nil {
-			errs = append(errs, err)
+	var errs []*FieldError
+	// defined locates the first definition of each team
+	defined := make(map[string]*FieldError)
+	for _, n := range nodes {
+		if len(n.root.Content) == 0 {
 			continue
 		}
-		res[t.Name] = v
+		check := schemaCheck{file: n, allowed: o.allowed}
+		check.walk(n.root.Content[0], reflect.TypeOf(config), "")
+		var c Config
+		if err = n.root.Decode(&c); err != nil {
+			check.errs = append(check.errs, &FieldError{File: n.file, Err: fmt.Errorf("%w: %w", ErrInvalidRequest, err)})
+		}
+		errs = append(errs, check.errs...)
+		for i, t := range c.Teams {
+			at := teamNode(n.root, i)
+			pos := &FieldError{File: n.fileOf(at), Line: at.Line, Column: at.Column, Path: "teams[" + strconv.Itoa(i) + "]"}
+			first, found := defined[t.Name]
+			switch {
+			case t.Name == "":
+			case !found:
+				defined[t.Name] = pos
+			case first.File != pos.File:
+				// teams repeated within a file are reported by Validate
+				pos.Err = fmt.Errorf("%w: team %q is already defined at %s:%d:%d", ErrInvalidRequest, t.Name, first.File, first.Line, first.Column)
+				errs = append(errs, pos)
+			}
+		}
+		config.Teams = append(config.Teams, c.Teams...)
 	}
-	var err error
 	if len(errs) > 0 {
-		err = errors.Join(errs...)
+		return config, &SchemaError{Errors: errs}
 	}
-	return res, err
+	return config, nil
 }
 
-func (c *Client) DeleteEntities(config Config) error {
-	for _, t := range config.Teams {
-		for _, u := range t.Users {
-			c.DeleteUserFromTeam(u.Name, t.Name)
-			c.DeleteUser(u.Name)
+// func (c *Client)
+
+// CreateEntities creates the teams of config with their members, duties, rosters, services
+// and admins. The report holds the outcome of each step, the error lists the failed ones.
+// Steps not started before ctx is done fail with its error.
+func (c *Client) CreateEntities(ctx context.Context, config Config, opts ...EntityOption) (*EntityReport, error) {
+	var o entityOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	report := &EntityReport{Teams: make([]*TeamReport, len(config.Teams))}
+	var (
+		wg sync.WaitGroup
+		s  = newSlots(o.concurrency)
+	)
+	for i, t := range config.Teams {
+		i, t := i, t
+		s.spawn(&wg, func() {
+			report.Teams[i], _ = c.createTeam(ctx, t, false, s)
+		})
+	}
+	wg.Wait()
+	return report, report.err()
+}
+
+// CreateSchedule creates the events of the duties of a user in a team. Duties that already exist
+// are skipped, duties with several roles create one event per role and consecutive days with
+// the same role are created at once as linked events. The days of the duties are those of tz,
+// the scheduling timezone of the team. An empty tz is read from oncall, UTC if it cannot be read.
+func (c *Client) CreateSchedule(ctx context.Context, username, teamname, tz string, schedule []Duty) error {
+	if tz == "" {
+		if res, err := c.GetTeam(ctx, teamname); err != nil {
+			c.logger.Warn().Err(err).Str("team", teamname).Msg("scheduling timezone unknown, duties are UTC days")
+		} else {
+			tz = res.Data.SchedulingTimezone
 		}
 	}
-	return nil
+	duties := c.createSchedule(ctx, username, teamname, tz, schedule)
+	var errs MultiError
+	for _, d := range duties {
+		if d.Outcome == OutcomeFailed {
+			errs.addDetail("create", "event", username, teamname, d.Role+" "+d.Date, d.Err)
+		}
+	}
+	return errs.Err()
 }
 
-func (c *Client) CreateSchedule(username, teamname string, schedule []Duty) error {
+// createSchedule creates the events of schedule as CreateSchedule does, in the scheduling
+// timezone tz, and reports each duty
+func (c *Client) createSchedule(ctx context.Context, username, teamname, tz string, schedule []Duty) []DutyReport {
 	logger := c.logger.With().
 		Caller().
 		Str("action", "create_schedule").
@@ -177,483 +300,401 @@ func (c *Client) CreateSchedule(username, teamname string, schedule []Duty) erro
 
 	logger.Debug().Msg("creating schedule")
 
-	var errs []error
-	for _, duty := range schedule {
-		err := c.addDayDuty(duty, username, teamname)
-		if err != nil {
-			errs = append(errs, err)
+	var (
+		reports []DutyReport
+		events  []dto.ScheduleDTO
+		// dates are the duty dates of the events by role and start
+		dates = make(map[eventKey]string)
+	)
+	for _, d := range schedule {
+		if _, err := d.Occurrences(); err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+			reports = append(reports, DutyReport{Role: d.Role, Date: d.Date, StepReport: batchStep(err)})
 		}
 	}
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	var roles []string
+	if len(schedule) > 0 {
+		roles = c.knownRoles(ctx)
 	}
-	return nil
+	for _, duty := range ExpandDuties(schedule) {
+		if roles != nil && !slices.Contains(roles, duty.Role) {
+			err := fmt.Errorf("%w: unknown role %q, oncall knows %s", ErrInvalidRequest, duty.Role, strings.Join(roles, ", "))
+			reports = append(reports, DutyReport{Role: duty.Role, Date: duty.Date, StepReport: batchStep(err)})
+			continue
+		}
+		data, err := c.dayDuty(ctx, duty, username, teamname, tz)
+		switch {
+		case err != nil:
+			reports = append(reports, DutyReport{Role: duty.Role, Date: duty.Date, StepReport: batchStep(err)})
+		case data == nil:
+			reports = append(reports, DutyReport{Role: duty.Role, Date: duty.Date, StepReport: StepReport{Outcome: OutcomeSkipped}})
+		default:
+			events = append(events, *data)
+			dates[eventKey{role: data.Role, start: data.StartTimeUnix}] = duty.Date
+		}
+	}
+
+	for _, run := range consecutiveRuns(events) {
+		var step StepReport
+		if len(run) == 1 {
+			step = newStep(c.createEvent(ctx, run[0]))
+		} else {
+			step = newStep(c.CreateLinkedEvents(ctx, run))
+		}
+		for _, e := range run {
+			date := dates[eventKey{role: e.Role, start: e.StartTimeUnix}]
+			reports = append(reports, DutyReport{Role: e.Role, Date: date, StepReport: step})
+		}
+	}
+	return reports
 }
 
-func (c *Client) addDayDuty(duty Duty, username, teamname string) error {
+// dayDuty converts a duty into the event to create. It returns nil if the duty already exists
+// and an error if it is invalid.
+func (c *Client) dayDuty(ctx context.Context, duty Duty, username, teamname, tz string) (*dto.ScheduleDTO, error) {
 	logger := c.logger.With().Str("action", "adding user duty").Logger()
 	if duty.Date == "" {
 		logger.Warn().
 			Interface("duty", duty).
 			Msg("empty date")
-		return nil
+		return nil, fmt.Errorf("%w: duty without date", ErrInvalidRequest)
 	}
 
-	endpoint, err := url.JoinPath(c.oncallURL, scheduleEndpoint)
-	if err != nil {
-		return ErrInvalidEndpoint
-	}
-
-	startTime, err := time.Parse("02/01/2006", duty.Date)
+	startTime, endTime, err := duty.Shift(tz)
 	if err != nil {
 		logger.Err(err).
 			Interface("duty", duty).
 			Msg("error parsing time")
-		return nil
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
-	endTime := startTime.Add(time.Hour * 24)
 
-	if c.existsDayDuty(username, teamname, startTime.Unix(), endTime.Unix(), duty.Role) {
+	if c.existsDayDuty(ctx, username, teamname, startTime, endTime, duty.Role) {
 		logger.Info().
 			Str("username", username).
 			Str("teamname", teamname).
 			Interface("duty", duty).
 			Msg("duty already exists")
-		return nil
+		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	data := dto.ScheduleDTO{
+	return &dto.ScheduleDTO{
 		Username:      username,
 		Teamname:      teamname,
 		Role:          duty.Role,
 		StartTimeUnix: startTime.Unix(),
 		EndTimeUnix:   endTime.Unix(),
-	}
-	b, _ := json.Marshal(data)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(b))
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return ErrInvalidRequest
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error creating event")
-		return err
-	}
-	defer res.Body.Close()
+	}, nil
+}
 
-	logger.Debug().
-		Int("status_code", res.StatusCode).Send()
-	if res.StatusCode != http.StatusCreated {
-		b, _ := io.ReadAll(res.Body)
-		logger.Warn().Bytes("data", b).Msg("status code is not 201")
+// consecutiveRuns groups events with the same role where each one starts when the previous one ends
+func consecutiveRuns(events []dto.ScheduleDTO) [][]dto.ScheduleDTO {
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b dto.ScheduleDTO) int {
+		if a.Role != b.Role {
+			return strings.Compare(a.Role, b.Role)
+		}
+		return cmp.Compare(a.StartTimeUnix, b.StartTimeUnix)
+	})
+
+	var runs [][]dto.ScheduleDTO
+	for i, e := range sorted {
+		if i > 0 {
+			prev := sorted[i-1]
+			if prev.Role == e.Role && prev.EndTimeUnix == e.StartTimeUnix {
+				runs[len(runs)-1] = append(runs[len(runs)-1], e)
+				continue
+			}
+		}
+		runs = append(runs, []dto.ScheduleDTO{e})
 	}
-	return nil
+	return runs
 }
 
-func (c *Client) existsDayDuty(username, teamname string, start, end int64, role string) bool {
-	endpoint, err := url.JoinPath(c.oncallURL, scheduleEndpoint)
+// createEvent creates a single event
+func (c *Client) createEvent(ctx context.Context, data dto.ScheduleDTO) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "adding user duty").Logger()
+	endpoint, err := c.endpoint(scheduleEndpoint)
 	if err != nil {
-		c.logger.Err(err).Caller().Msg("invalid endpoint")
-		return false
+		return nil, ErrInvalidEndpoint
 	}
-	req, _ := http.NewRequest(http.MethodGet, endpoint, nil)
-	q := req.URL.Query()
-	q.Add("user", username)
-	q.Add("team", teamname)
-	q.Add("start", strconv.FormatInt(start, 10))
-	q.Add("end", strconv.FormatInt(end, 10))
-	q.Add("role", role)
-
-	req.URL.RawQuery = q.Encode()
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, data)
+}
 
-	res, err := c.httpClient.Do(req)
+func (c *Client) existsDayDuty(ctx context.Context, username, teamname string, start, end time.Time, role string) bool {
+	res, err := c.GetEvents(ctx, EventFilter{
+		Team:  teamname,
+		User:  username,
+		Role:  role,
+		Start: start,
+		End:   end,
+	})
 	if err != nil {
 		c.logger.Err(err).Msg("error checking for day duty")
 		return false
 	}
-	defer res.Body.Close()
-	var items []interface{}
-	json.NewDecoder(res.Body).Decode(&items)
-	return len(items) > 0
+	return len(res.Data) > 0
 }
 
-func (c *Client) DeleteUser(name string) error {
+func (c *Client) DeleteUser(ctx context.Context, name string) error {
 	logger := c.logger.With().Str("user_name", name).Str("action", "delete_user").Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, usersEndpoint, name)
-	if err != nil {
-		return ErrInvalidEndpoint
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error creating delete request")
+	if err := c.guard.check("user", name); err != nil {
+		logger.Warn().Err(err).Send()
 		return err
 	}
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-	req.Header.Set("Content-Type", "application/json")
-	res, err := c.httpClient.Do(req)
+	endpoint, err := c.endpoint(usersEndpoint, name)
 	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error deleting user")
-		return err
+		return ErrInvalidEndpoint
 	}
-	defer res.Body.Close()
-
-	logger.Debug().Int("status_code", res.StatusCode).Send()
-	return nil
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
 }
 
 // CreateUser is a two-step HTTP request (POST) that first creates the username of the user
-// and sends a PUT request to add the user's data
-func (c *Client) CreateUser(u User) (*Response[any], error) {
+// and sends a PUT request to add the user's data (see UpdateUser)
+func (c *Client) CreateUser(ctx context.Context, u User) (*Response[any], error) {
 	logger := c.logger.With().Str("user", u.Name).Str("action", "create_user").Logger()
 	logger.Debug().Msgf("creating user")
-	endpoint, err := url.JoinPath(c.oncallURL, usersEndpoint)
+	endpoint, err := c.endpoint(usersEndpoint)
 	if err != nil {
 		return nil, ErrInvalidEndpoint
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
 
-	postData := map[string]interface{}{
-		"name": u.Name,
+	result, createErr := doJSON[any](ctx, c, logger, http.MethodPost, endpoint, map[string]string{"name": u.Name})
+	// an existing user is still updated below, but the conflict is reported to the caller
+	if createErr != nil && !errors.Is(createErr, ErrConflict) {
+		return result, createErr
 	}
-	b, _ := json.Marshal(postData)
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(b))
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-
-	result := Response[any]{}
-	startTime := time.Now()
-
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error creating user")
-		return nil, err
-	}
-	defer res.Body.Close()
 
-	// record metrics
-	result.ResponseTime = time.Since(startTime)
-	result.StatusCode = res.StatusCode
-
-	logger.Debug().
-		Int("status_code", res.StatusCode).Send()
-	if res.StatusCode != http.StatusCreated {
-		logger.Warn().Msg("status code is not 201")
+	// recreating a soft-deleted user conflicts, it is reactivated instead and reported
+	// as created, with the status of the reactivation
+	if createErr != nil {
+		if existing, err := c.GetUser(ctx, u.Name); err == nil && !existing.Data.Active {
+			reactivated, err := c.ReactivateUser(ctx, u.Name)
+			if err != nil {
+				return result, err
+			}
+			result.StatusCode = reactivated.StatusCode
+			result.ResponseTime += reactivated.ResponseTime
+			createErr = nil
+		}
 	}
 
 	// PUT data
-	logger.Debug().Msg("updating user data")
-	data := dto.UserCreateDTO{
-		Name:     u.Name,
-		FullName: u.FullName,
-		Contacts: dto.ContactsDTO{
-			Call:  u.PhoneNumber,
-			Email: u.Email,
-		},
+	if _, err = c.UpdateUser(ctx, u.Name, u); err != nil {
+		return result, err
 	}
-	b, _ = json.Marshal(data)
-	endpoint, err = url.JoinPath(endpoint, u.Name)
-	if err != nil {
-		return nil, ErrInvalidEndpoint
-	}
-	req, err = http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(b))
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-
-	res, err = c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error updating user data")
-		return nil, err
-	}
-	defer res.Body.Close()
-	logger.Debug().Int("status_code", res.StatusCode).Send()
-	return &result, nil
+	return result, createErr
 }
 
-type TeamResponse struct {
-	Response               *Response[any]
-	UserCreateResponses    map[string]*Response[any]
-	UserAddToTeamResponses map[string]*Response[any]
+// CreateTeam creates t with its members, their duties and notifications, its rosters, services
+// and admins. With returnEarly nothing but the team is attempted if its creation fails.
+func (c *Client) CreateTeam(ctx context.Context, t Team, returnEarly bool) (*TeamReport, error) {
+	report, _ := c.createTeam(ctx, t, returnEarly, nil)
+	return report, report.err()
 }
 
-func (c *Client) CreateTeam(t Team, returnEarly bool) (*TeamResponse, error) {
+// createTeam creates t as CreateTeam does, running the requests of the users of the team
+// concurrently within s
+func (c *Client) createTeam(ctx context.Context, t Team, returnEarly bool, s slots) (*TeamReport, error) {
 	logger := c.logger.With().Str("action", "create_team").Logger()
 	logger.Debug().Msgf("creating team: %s", t.Name)
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint)
-	if err != nil {
-		return nil, ErrInvalidEndpoint
+	report := &TeamReport{
+		Name:  t.Name,
+		Users: make([]*UserReport, len(t.Users)),
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	data := dto.TeamCreateDTO{
-		Name:                      t.Name,
-		Email:                     t.Email,
-		SchedulingTimezone:        t.SchedulingTimezone,
-		SlackChannel:              t.SlackChannel,
-		SlackChannelNotifications: t.SlackChannel + "-alert",
-	}
-	b, _ := json.Marshal(data)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(b))
+	endpoint, err := c.endpoint(teamsEndpoint)
 	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
+		report.Create = batchStep(ErrInvalidEndpoint)
+		return report, ErrInvalidEndpoint
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
 
-	result := TeamResponse{
-		Response:               &Response[any]{},
-		UserCreateResponses:    make(map[string]*Response[any]),
-		UserAddToTeamResponses: make(map[string]*Response[any]),
+	s.do(func() {
+		report.Create = newStep(doJSON[any](ctx, c, logger, http.MethodPost, endpoint, teamDTO(t)))
+		if c.upsert && report.Create.Outcome == OutcomeSkipped {
+			report.Create = c.upsertTeam(ctx, t)
+		}
+	})
+	// an existing team can still receive users
+	if report.Create.Outcome == OutcomeFailed && returnEarly {
+		report.Users = nil
+		return report, report.err()
 	}
 
-	startTime := time.Now()
+	var wg sync.WaitGroup
+	for i, u := range t.Users {
+		i, u := i, u
+		s.spawn(&wg, func() {
+			report.Users[i] = c.createMember(ctx, u, t.Name, t.SchedulingTimezone, s)
+		})
+	}
+	wg.Wait()
 
-	// perform request
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error creating team")
-		if !returnEarly {
-			goto USERS
+	s.do(func() {
+		if err = c.createRosters(ctx, t.Name, t.Rosters); err != nil {
+			logger.Warn().Err(err).Msg("error creating rosters")
 		}
-	}
-	defer res.Body.Close()
+		report.Rosters = batchStep(err)
+
+		var errs MultiError
+		for _, svc := range t.Services {
+			if _, err = c.AddService(ctx, t.Name, svc); err != nil && !errors.Is(err, ErrConflict) {
+				logger.Warn().Err(err).Str("service", svc).Msg("error adding service")
+				errs.Add("add_to_team", "service", svc, t.Name, err)
+			}
+		}
+		report.Services = batchStep(errs.Err())
+
+		errs = MultiError{}
+		for _, admin := range t.Admins {
+			if _, err = c.AddAdmin(ctx, t.Name, admin); err != nil && !errors.Is(err, ErrConflict) {
+				logger.Warn().Err(err).Str("admin", admin).Msg("error adding admin")
+				errs.Add("add_admin", "user", admin, t.Name, err)
+			}
+		}
+		report.Admins = batchStep(errs.Err())
+	})
+	return report, report.err()
+}
 
-	// record metrics
-	result.Response.ResponseTime = time.Since(startTime)
-	result.Response.StatusCode = res.StatusCode
-	logger.Debug().
-		Int("status_code", res.StatusCode).Send()
-
-	if res.StatusCode != http.StatusCreated {
-		logger.Warn().Msg("status code is not 201")
-	}
-USERS:
-	for _, u := range t.Users {
-		logger := logger.With().
-			Str("user_name", u.Name).
-			Str("team_name", t.Name).
-			Logger()
-		userResult, err := c.CreateUser(u)
-		if err != nil {
-			logger.Warn().Err(err).
-				Msg("error creating user")
-		} else {
-			result.UserCreateResponses[u.Name] = userResult
+// createMember creates u, adds it to team and creates its notifications and schedule, in that
+// order, taking a slot of s for each step. tz is the scheduling timezone of the team.
+func (c *Client) createMember(ctx context.Context, u User, team, tz string, s slots) *UserReport {
+	logger := c.logger.With().
+		Str("action", "create_team").
+		Str("user_name", u.Name).
+		Str("team_name", team).
+		Logger()
+	report := &UserReport{Name: u.Name}
+	s.do(func() {
+		if c.upsert {
+			report.Create = c.upsertUser(ctx, u)
+			return
 		}
-		userResult, err = c.AddUserToTeam(u.Name, t.Name)
-		if err != nil {
-			logger.Warn().Err(err).
-				Msg("error adding user to team")
-		} else {
-			result.UserAddToTeamResponses[u.Name] = userResult
+		// existing users are updated by CreateUser, a conflict is a skipped step
+		report.Create = newStep(c.CreateUser(ctx, u))
+	})
+	if err := report.Create.Err; err != nil {
+		logger.Warn().Err(err).
+			Msg("error creating user")
+	}
+	s.do(func() {
+		report.AddToTeam = newStep(c.AddUserToTeam(ctx, u.Name, team))
+	})
+	if err := report.AddToTeam.Err; err != nil {
+		logger.Warn().Err(err).
+			Msg("error adding user to team")
+	}
+	s.do(func() {
+		report.Notifications = StepReport{Outcome: OutcomeSkipped}
+		if len(u.Notifications) > 0 {
+			report.Notifications = batchStep(c.createNotifications(ctx, u.Name, team, u.Notifications))
 		}
-		err = c.CreateSchedule(u.Name, t.Name, u.Schedule)
-		if err != nil {
-			logger.Warn().Err(err).
+	})
+	if err := report.Notifications.Err; err != nil {
+		logger.Warn().Err(err).
+			Msg("error creating notifications")
+	}
+	s.do(func() {
+		report.Duties = c.createSchedule(ctx, u.Name, team, tz, u.Schedule)
+	})
+	for _, d := range report.Duties {
+		if d.Err != nil {
+			logger.Warn().Err(d.Err).
+				Str("role", d.Role).
+				Str("date", d.Date).
 				Msg("error creating event")
 		}
 	}
-	return &result, nil
+	return report
 }
 
-func (c *Client) DeleteTeam(team string) error {
+func (c *Client) DeleteTeam(ctx context.Context, team string) error {
 	logger := c.logger.With().Str("action", "delete_team").Str("team", team).Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint, team)
-	if err != nil {
-		return ErrInvalidEndpoint
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return ErrInvalidRequest
+	if err := c.guard.check("team", team); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
 	}
-	_, err = c.httpClient.Do(req)
+	endpoint, err := c.endpoint(teamsEndpoint, team)
 	if err != nil {
-		logger.Error().Err(err)
+		return ErrInvalidEndpoint
 	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
 	return err
 }
 
-func (c *Client) DeleteUserFromTeam(user, team string) error {
+func (c *Client) DeleteUserFromTeam(ctx context.Context, user, team string) error {
 	logger := c.logger.With().Str("action", "remove_user_from_team").Str("team", team).Str("user", user).Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint, team, "users", user)
-	if err != nil {
-		return ErrInvalidEndpoint
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return ErrInvalidRequest
+	if err := errors.Join(c.guard.check("team", team), c.guard.check("user", user)); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
 	}
-	res, err := c.httpClient.Do(req)
+	endpoint, err := c.endpoint(teamsEndpoint, team, "users", user)
 	if err != nil {
-		logger.Error().Err(err)
-		return err
+		return ErrInvalidEndpoint
 	}
-	logger.Debug().Int("status_code", res.StatusCode).Send()
-	return nil
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
 }
 
-func (c *Client) GetTeams() (*Response[[]string], error) {
-	logger := c.logger.With().Str("action", "get_teams").Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint)
-	if err != nil {
-		return nil, ErrInvalidEndpoint
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
+// GetTeams lists the names of every team, see ListTeams to filter them
+func (c *Client) GetTeams(ctx context.Context) (*Response[[]string], error) {
+	return c.ListTeams(ctx, TeamFilter{})
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
+// GetSummary returns the current and next shifts of a team with their users, roles and times
+func (c *Client) GetSummary(ctx context.Context, team string) (*Response[Summary], error) {
+	summary, err := c.summary(ctx, team)
+	if summary == nil {
+		return nil, err
 	}
-	result := Response[[]string]{
-		URLPath: req.URL.Path,
+	data := Summary{
+		Current: shiftsOf(summary.Data["current"]),
+		Next:    shiftsOf(summary.Data["next"]),
 	}
-	startTime := time.Now()
+	return withData(summary, data), err
+}
 
-	// perform request
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error fetching teams")
+// GetOnCall returns the names of the users currently on call in a team, keyed by role
+func (c *Client) GetOnCall(ctx context.Context, team string) (*Response[map[string][]string], error) {
+	summary, err := c.summary(ctx, team)
+	if summary == nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-
-	// record metrics
-	result.ResponseTime = time.Since(startTime)
-	result.StatusCode = res.StatusCode
-	logger.Debug().Int("status_code", res.StatusCode).Send()
+	data := make(map[string][]string)
+	for role, events := range summary.Data["current"] {
+		for _, e := range events {
+			data[role] = append(data[role], e.User)
+		}
+	}
+	return withData(summary, data), err
+}
 
-	if err = json.NewDecoder(res.Body).Decode(&result.Data); err != nil {
-		return nil, err
+func shiftsOf(roles map[string][]dto.SummaryEventDTO) map[string][]Shift {
+	shifts := make(map[string][]Shift, len(roles))
+	for role, events := range roles {
+		for _, e := range events {
+			shifts[role] = append(shifts[role], Shift(e))
+		}
 	}
-	return &result, nil
+	return shifts
 }
 
-func (c *Client) GetSummary(team string) (*Response[map[string]int], error) {
+// summary fetches the current and next shifts of a team
+func (c *Client) summary(ctx context.Context, team string) (*Response[dto.SummaryDTO], error) {
 	logger := c.logger.With().Str("action", "get current summary of roster").Logger()
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint, team, "summary")
+	endpoint, err := c.endpoint(teamsEndpoint, team, "summary")
 	if err != nil {
 		return nil, ErrInvalidEndpoint
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
-	}
-
-	result := Response[map[string]int]{
-		Data:    make(map[string]int),
-		URLPath: req.URL.Path,
-	}
-	startTime := time.Now()
-
-	// perform request
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error fetching summary")
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	// record metrics
-	result.ResponseTime = time.Since(startTime)
-	result.StatusCode = res.StatusCode
-	logger.Debug().Int("status_code", res.StatusCode).Send()
-
-	var response map[string]map[string][]any
-	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
-		return nil, err
-	}
-	if _, ok := response["current"]; ok {
-		currentSummary := response["current"]
-		for k, v := range currentSummary {
-			result.Data[k] = len(v)
-		}
-	}
-	return &result, nil
+	return doJSON[dto.SummaryDTO](ctx, c, logger, http.MethodGet, endpoint, nil)
 }
 
-func (c *Client) AddUserToTeam(username, teamname string) (*Response[any], error) {
+func (c *Client) AddUserToTeam(ctx context.Context, username, teamname string) (*Response[any], error) {
 	logger := c.logger.With().Str("action", "add_user_to_team").Logger()
 	logger.Debug().Msgf("adding user %s to team %s", username, teamname)
-	endpoint, err := url.JoinPath(c.oncallURL, teamsEndpoint, teamname, "users")
+	endpoint, err := c.endpoint(teamsEndpoint, teamname, "users")
 	if err != nil {
 		return nil, ErrInvalidEndpoint
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	data := map[string]interface{}{
-		"name": username,
-	}
-	b, _ := json.Marshal(data)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(b))
-	if err != nil {
-		logger.Error().Caller().Err(err).Send()
-		return nil, ErrInvalidRequest
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-CSRF-TOKEN", c.csrfToken)
-
-	result := Response[any]{}
-	startTime := time.Now()
-
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		logger.Error().Caller().Err(err).Msg("error adding user to team")
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	// record metrics
-	result.ResponseTime = time.Since(startTime)
-	result.StatusCode = res.StatusCode
-	logger.Debug().
-		Int("status_code", res.StatusCode).Send()
-	if res.StatusCode != http.StatusCreated {
-		logger.Warn().Msg("status code is not 201")
-	}
-	return &result, nil
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, map[string]string{"name": username})
 }
@@ -0,0 +1,54 @@
+package oncall
+
+import "sync"
+
+// EntityOption configures a call of CreateEntities
+type EntityOption func(*entityOptions)
+
+type entityOptions struct {
+	concurrency int
+}
+
+// WithConcurrency lets CreateEntities send up to n requests at once. Teams and their users
+// are then created in parallel, while each user is still created before being added to the
+// team and getting its notifications and schedule, and rosters wait for every member.
+// It defaults to 1, creating everything in the order of the config.
+func WithConcurrency(n int) EntityOption {
+	return func(o *entityOptions) {
+		o.concurrency = n
+	}
+}
+
+// slots bounds the number of tasks running at once. A nil slots runs tasks inline,
+// one after the other.
+type slots chan struct{}
+
+func newSlots(n int) slots {
+	if n <= 1 {
+		return nil
+	}
+	return make(slots, n)
+}
+
+// do runs f once a slot is free
+func (s slots) do(f func()) {
+	if s != nil {
+		s <- struct{}{}
+		defer func() { <-s }()
+	}
+	f()
+}
+
+// spawn runs f in its own goroutine tracked by wg, or inline if s is nil.
+// The goroutine does not hold a slot, f takes one with do for each of its requests.
+func (s slots) spawn(wg *sync.WaitGroup, f func()) {
+	if s == nil {
+		f()
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
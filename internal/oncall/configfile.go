@@ -0,0 +1,275 @@
+package oncall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lordvidex/oncall-go-client/internal/sops"
+)
+
+// Formats of the config files, see WithFormat
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// WithFormat reads the config file as format, yaml, json or toml, instead of the format of
+// its extension. An empty format keeps the extension.
+func WithFormat(format string) LoadOption {
+	return func(o *loadOptions) {
+		o.format = format
+	}
+}
+
+// configFormat returns the format of filename: format if set, else json for .json files,
+// toml for .toml files and yaml for the others
+func configFormat(filename, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".json":
+			return FormatJSON, nil
+		case ".toml":
+			return FormatTOML, nil
+		}
+		return FormatYAML, nil
+	}
+	switch format = strings.ToLower(format); format {
+	case FormatYAML, FormatJSON, FormatTOML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown config format %q, expected yaml, json or toml", format)
+}
+
+// ReadConfig returns the yaml of a config file, decrypted when it is encrypted with age or
+// by SOPS with age keys, rendered with the values of WithValues, and with the environment
+// variables its values reference expanded.
+// Encrypted files are detected by their age header or their sops metadata, the keys are
+// read from SOPS_AGE_KEY or SOPS_AGE_KEY_FILE. json and toml files are converted to yaml.
+//
+// filename can be a directory, whose config files are merged: their teams are concatenated
+// and any other top level key can only be set by one of them.
+func ReadConfig(filename string, opts ...LoadOption) ([]byte, error) {
+	nodes, err := readConfigNodes(filename, newLoadOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	root := nodes[0].root
+	if len(nodes) > 1 {
+		if root, err = mergeConfigNodes(nodes); err != nil {
+			return nil, err
+		}
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(root)
+}
+
+// configExtensions are the extensions of the config files read from a directory
+var configExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true, ".toml": true}
+
+// configNode is a parsed config file
+type configNode struct {
+	file string
+	root *yaml.Node
+	// files locates the nodes read from the files it includes, see resolveIncludes
+	files map[*yaml.Node]string
+}
+
+// fileOf returns the file n was read from
+func (c configNode) fileOf(n *yaml.Node) string {
+	if f, found := c.files[n]; found {
+		return f
+	}
+	return c.file
+}
+
+// readConfigNodes reads the config file path, or the config files of the directory path in
+// the order of their names, each in the format of its extension. Hidden files and
+// subdirectories are skipped. The includes of the files are resolved.
+func readConfigNodes(path string, o loadOptions) ([]configNode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		root, err := readConfigNode(path, o)
+		if err != nil {
+			return nil, err
+		}
+		files, err := resolveIncludes(root, path, o)
+		if err != nil {
+			return nil, err
+		}
+		return []configNode{{file: path, root: root, files: files}}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		nodes []configNode
+		errs  []*FieldError
+	)
+	// the files of a directory are read in the format of their extension
+	o.format = ""
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !configExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		file := filepath.Join(path, e.Name())
+		root, err := readConfigNode(file, o)
+		var files map[*yaml.Node]string
+		if err == nil {
+			files, err = resolveIncludes(root, file, o)
+		}
+		var schema *SchemaError
+		if errors.As(err, &schema) {
+			errs = append(errs, schema.Errors...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, configNode{file: file, root: root, files: files})
+	}
+	if len(errs) > 0 {
+		return nil, &SchemaError{Errors: errs}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%s: no yaml, json or toml config file", path)
+	}
+	return nodes, nil
+}
+
+// mergeConfigNodes merges the top level mappings of nodes: the teams lists are concatenated,
+// other keys set by several files are returned in a *SchemaError
+func mergeConfigNodes(nodes []configNode) (*yaml.Node, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	defined := make(map[string]string)
+	var errs []*FieldError
+	for _, n := range nodes {
+		if len(n.root.Content) == 0 {
+			continue
+		}
+		m := n.root.Content[0]
+		if m.Kind != yaml.MappingNode {
+			errs = append(errs, &FieldError{File: n.file, Line: m.Line, Column: m.Column, Err: fmt.Errorf("%w: expected a mapping", ErrInvalidRequest)})
+			continue
+		}
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			k, v := m.Content[i], m.Content[i+1]
+			existing := lookup(merged, k.Value)
+			switch {
+			case existing == nil:
+				if k.Value == "teams" && v.Kind == yaml.SequenceNode {
+					v = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: slices.Clone(v.Content)}
+				}
+				merged.Content = append(merged.Content, k, v)
+				defined[k.Value] = n.file
+			case k.Value == "teams" && existing.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode:
+				existing.Content = append(existing.Content, v.Content...)
+			default:
+				errs = append(errs, &FieldError{File: n.fileOf(k), Line: k.Line, Column: k.Column, Path: k.Value,
+					Err: fmt.Errorf("%w: %s is already set in %s", ErrInvalidRequest, k.Value, defined[k.Value])})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &SchemaError{Errors: errs}
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{merged}}, nil
+}
+
+// teamNode returns the node locating the team i of the config file root, its name if it has one
+func teamNode(root *yaml.Node, i int) *yaml.Node {
+	m := root.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return m
+	}
+	teams := lookup(m, "teams")
+	if teams == nil || teams.Kind != yaml.SequenceNode || i >= len(teams.Content) {
+		return m
+	}
+	team := teams.Content[i]
+	if team.Kind == yaml.MappingNode {
+		if name := lookup(team, "name"); name != nil {
+			return name
+		}
+	}
+	return team
+}
+
+func readConfigNode(filename string, o loadOptions) (*yaml.Node, error) {
+	format, err := configFormat(filename, o.format)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var ids []sops.Identity
+	identities := func() ([]sops.Identity, error) {
+		if ids == nil {
+			ids, err = sops.LoadIdentities()
+		}
+		return ids, err
+	}
+	if sops.IsAge(b) {
+		if _, err = identities(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if b, err = sops.DecryptAge(b, ids); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	if o.values != nil {
+		if b, err = renderConfig(b, filename, o.values); err != nil {
+			return nil, err
+		}
+	}
+	root, err := parseConfig(b, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if sops.IsEncrypted(root) {
+		if _, err = identities(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if err = sops.DecryptNode(root, ids); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	if err = expandEnv(root, filename); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// parseConfig parses the nodes of a config file. JSON is a subset of yaml, it is parsed as
+// yaml once checked, keeping the lines of its values.
+func parseConfig(b []byte, format string) (*yaml.Node, error) {
+	switch format {
+	case FormatTOML:
+		return parseTOML(b)
+	case FormatJSON:
+		if !json.Valid(b) {
+			var v any
+			return nil, fmt.Errorf("invalid json: %w", json.Unmarshal(b, &v))
+		}
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
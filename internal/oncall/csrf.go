@@ -0,0 +1,43 @@
+package oncall
+
+import "net/http"
+
+// defaultCSRFHeader is the header oncall reads the CSRF token from
+const defaultCSRFHeader = "X-CSRF-TOKEN"
+
+// csrfPolicy describes how the CSRF token obtained at login is sent
+type csrfPolicy struct {
+	header      string
+	allRequests bool
+}
+
+// WithCSRFHeader changes the name of the header carrying the CSRF token, for deployments
+// whose middleware expects a different one
+func WithCSRFHeader(name string) Option {
+	return func(c *Client) {
+		c.csrf.header = name
+	}
+}
+
+// WithCSRFOnAllRequests sends the CSRF token on every request, GETs included.
+// By default only state-changing methods carry it.
+func WithCSRFOnAllRequests() Option {
+	return func(c *Client) {
+		c.csrf.allRequests = true
+	}
+}
+
+// setCSRF attaches the CSRF token to req according to the client policy
+func (c *Client) setCSRF(req *http.Request) {
+	token, _ := c.csrfToken.Load().(string)
+	if token == "" {
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if !c.csrf.allRequests {
+			return
+		}
+	}
+	req.Header.Set(c.csrf.header, token)
+}
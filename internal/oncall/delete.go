@@ -0,0 +1,107 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// DeleteOptions selects what DeleteEntities keeps
+type DeleteOptions struct {
+	// KeepTeams leaves the teams in place, only their configured members are removed
+	KeepTeams bool
+	// KeepUsers leaves the users in place, only their memberships and events are removed
+	KeepUsers bool
+}
+
+// DeleteResult is the outcome of the deletion of an entity
+type DeleteResult struct {
+	Kind    string  `json:"kind"`
+	Name    string  `json:"name"`
+	Team    string  `json:"team,omitempty"`
+	Outcome Outcome `json:"outcome"`
+	Err     error   `json:"-"`
+}
+
+// DeleteReport is the outcome of DeleteEntities, in the order entities were deleted
+type DeleteReport struct {
+	Results []DeleteResult `json:"results"`
+}
+
+func (r *DeleteReport) add(kind, name, team string, err error) {
+	res := DeleteResult{Kind: kind, Name: name, Team: team, Outcome: OutcomeDeleted, Err: err}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		res.Outcome, res.Err = OutcomeSkipped, nil
+	case err != nil:
+		res.Outcome = OutcomeFailed
+	}
+	r.Results = append(r.Results, res)
+}
+
+func (r *DeleteReport) keep(kind, name, team string) {
+	r.Results = append(r.Results, DeleteResult{Kind: kind, Name: name, Team: team, Outcome: OutcomeSkipped})
+}
+
+// Failures lists the failed deletions as entity errors, as returned by DeleteEntities
+func (r *DeleteReport) Failures() []*EntityError {
+	var errs MultiError
+	for _, res := range r.Results {
+		if res.Outcome == OutcomeFailed {
+			errs.Add("delete", res.Kind, res.Name, res.Team, res.Err)
+		}
+	}
+	return errs.Errors
+}
+
+// DeleteEntities deletes what CreateEntities created from config, in an order oncall accepts:
+// the events of the configured users in each team, their memberships, the users, then the teams.
+// Entities that are already gone are skipped. The report holds the outcome of each deletion,
+// the error lists the failed ones.
+func (c *Client) DeleteEntities(ctx context.Context, config Config, opts DeleteOptions) (*DeleteReport, error) {
+	report := &DeleteReport{}
+
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			events, err := c.GetEvents(ctx, EventFilter{Team: t.Name, User: u.Name})
+			if err != nil {
+				report.add("event", "", t.Name, err)
+				continue
+			}
+			for _, e := range events.Data {
+				report.add("event", strconv.FormatInt(e.ID, 10), t.Name, c.DeleteEvent(ctx, e.ID))
+			}
+		}
+	}
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			report.add("team_user", u.Name, t.Name, c.DeleteUserFromTeam(ctx, u.Name, t.Name))
+		}
+	}
+	deleted := make(map[string]bool)
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			if deleted[u.Name] {
+				continue
+			}
+			deleted[u.Name] = true
+			if opts.KeepUsers {
+				report.keep("user", u.Name, "")
+				continue
+			}
+			report.add("user", u.Name, "", c.DeleteUser(ctx, u.Name))
+		}
+	}
+	for _, t := range config.Teams {
+		if opts.KeepTeams {
+			report.keep("team", t.Name, t.Name)
+			continue
+		}
+		report.add("team", t.Name, t.Name, c.DeleteTeam(ctx, t.Name))
+	}
+
+	if f := report.Failures(); len(f) > 0 {
+		return report, &MultiError{Errors: f}
+	}
+	return report, nil
+}
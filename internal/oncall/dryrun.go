@@ -0,0 +1,77 @@
+package oncall
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PlannedRequest is a mutating request a dry run client did not send
+type PlannedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Body is the payload of the request, usually json
+	Body []byte `json:"body,omitempty"`
+}
+
+// dryRun records the mutating requests withheld by a client created WithDryRun
+type dryRun struct {
+	enabled bool
+	mu      sync.Mutex
+	planned []PlannedRequest
+}
+
+// WithDryRun makes every mutating request (POST, PUT, PATCH and DELETE) be logged and recorded
+// instead of being sent, see PlannedRequests. It is answered with a 200 and a null body, so
+// callers proceed as if it succeeded. GETs and the login are still sent.
+func WithDryRun(enabled bool) Option {
+	return func(c *Client) {
+		c.dryRun.enabled = enabled
+	}
+}
+
+// PlannedRequests returns the requests withheld by WithDryRun, in the order they were made
+func (c *Client) PlannedRequests() []PlannedRequest {
+	c.dryRun.mu.Lock()
+	defer c.dryRun.mu.Unlock()
+	return append([]PlannedRequest(nil), c.dryRun.planned...)
+}
+
+// withhold records req and answers it if the client is a dry run and req mutates oncall
+func (c *Client) withhold(req *http.Request) (*http.Response, bool, error) {
+	if !c.dryRun.enabled || !mutates(req) {
+		return nil, false, nil
+	}
+	body, err := RequestBody(req)
+	if err != nil {
+		return nil, true, err
+	}
+	planned := PlannedRequest{Method: req.Method, URL: req.URL.String(), Body: body}
+	c.dryRun.mu.Lock()
+	c.dryRun.planned = append(c.dryRun.planned, planned)
+	c.dryRun.mu.Unlock()
+	c.logger.Info().
+		Str("method", planned.Method).
+		Str("url", planned.URL).
+		Bytes("body", body).
+		Msg("dry run, request not sent")
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/json"}, "X-Dry-Run": {"true"}},
+		Body:       io.NopCloser(strings.NewReader("null")),
+		Request:    req,
+	}, true, nil
+}
+
+func mutates(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !strings.HasSuffix(req.URL.Path, loginEndpoint)
+}
@@ -6,6 +6,11 @@ type TeamCreateDTO struct {
 	SchedulingTimezone        string `json:"scheduling_timezone,omitempty"`
 	SlackChannel              string `json:"slack_channel,omitempty"`
 	SlackChannelNotifications string `json:"slack_channel_notifications,omitempty"`
+	OverridePhoneNumber       string `json:"override_phone_number,omitempty"`
+	IrisPlan                  string `json:"iris_plan,omitempty"`
+	IrisEnabled               *bool  `json:"iris_enabled,omitempty"`
+	Description               string `json:"description,omitempty"`
+	APIManagedRoster          *bool  `json:"api_managed_roster,omitempty"`
 }
 
 type UserCreateDTO struct {
@@ -14,6 +19,7 @@ type UserCreateDTO struct {
 	Contacts ContactsDTO `json:"contacts,omitempty"`
 	TimeZone string      `json:"time_zone,omitempty"`
 	PhotoURL string      `json:"photo_url,omitempty"`
+	Active   *bool       `json:"active,omitempty"`
 }
 
 type ContactsDTO struct {
@@ -30,3 +36,84 @@ type ScheduleDTO struct {
 	StartTimeUnix int64  `json:"start,omitempty"`
 	EndTimeUnix   int64  `json:"end,omitempty"`
 }
+
+// SummaryDTO is the response of the team summary endpoint, keyed by "current" or "next", then by role
+type SummaryDTO map[string]map[string][]SummaryEventDTO
+
+type SummaryEventDTO struct {
+	User     string `json:"user"`
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+}
+
+type RosterCreateDTO struct {
+	Name string `json:"name"`
+}
+
+type RosterUserDTO struct {
+	Name       string `json:"name,omitempty"`
+	InRotation bool   `json:"in_rotation"`
+}
+
+type ScheduleCreateDTO struct {
+	Role                  string             `json:"role"`
+	AutoPopulateThreshold int                `json:"auto_populate_threshold"`
+	AdvancedMode          int                `json:"advanced_mode"`
+	Events                []ScheduleEventDTO `json:"events"`
+	Scheduler             SchedulerDTO       `json:"scheduler"`
+}
+
+type ScheduleEventDTO struct {
+	Start    int64 `json:"start"`
+	Duration int64 `json:"duration"`
+}
+
+// SchedulerDTO selects the algorithm populating a schedule. Data is the user order of round-robin.
+type SchedulerDTO struct {
+	Name string   `json:"name"`
+	Data []string `json:"data"`
+}
+
+type SchedulerUpdateDTO struct {
+	Scheduler SchedulerDTO `json:"scheduler"`
+}
+
+type PopulateDTO struct {
+	Start int64 `json:"start"`
+}
+
+type SwapDTO struct {
+	Events [2]SwapEventDTO `json:"events"`
+}
+
+// SwapEventDTO is a side of a swap. ID is an event id, or a link id when Linked is set.
+type SwapEventDTO struct {
+	ID     any  `json:"id"`
+	Linked bool `json:"linked"`
+}
+
+type OverrideDTO struct {
+	Start    int64   `json:"start"`
+	End      int64   `json:"end"`
+	User     string  `json:"user"`
+	EventIDs []int64 `json:"event_ids"`
+}
+
+type ServiceDTO struct {
+	Name string `json:"name"`
+}
+
+type AdminDTO struct {
+	Name string `json:"name"`
+}
+
+type NotificationDTO struct {
+	Team           string   `json:"team"`
+	Roles          []string `json:"roles"`
+	Mode           string   `json:"mode"`
+	Type           string   `json:"type"`
+	TimeBefore     int64    `json:"time_before"`
+	OnlyIfInvolved *bool    `json:"only_if_involved,omitempty"`
+}
@@ -0,0 +1,65 @@
+package oncall
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SlashStyle decides whether the paths of requests sent to oncall end with a slash.
+// oncall serves its API with and without the trailing slash, but proxies in front of it
+// may redirect or reject one of the forms.
+type SlashStyle int
+
+const (
+	// SlashCanonical sends collection endpoints such as /api/v0/teams/ with a trailing
+	// slash and every other path without, as the client always did
+	SlashCanonical SlashStyle = iota
+	// SlashNever strips the trailing slash of every path
+	SlashNever
+	// SlashAlways adds a trailing slash to every path
+	SlashAlways
+)
+
+// WithTrailingSlash forces the trailing slash style of every request, including Raw ones.
+// The login form is always posted to /login. It defaults to SlashCanonical.
+func WithTrailingSlash(style SlashStyle) Option {
+	return func(c *Client) {
+		c.slashes = style
+	}
+}
+
+// endpoint joins path and the segments elem to the oncall URL and applies the trailing slash
+// style of the client. path is an escaped URL path, the segments are names escaped here, so
+// that a team such as "a/b" stays a single segment. It is the only place where request URLs
+// are built.
+func (c *Client) endpoint(path string, elem ...string) (string, error) {
+	u, err := url.Parse(c.oncallURL)
+	if err != nil {
+		return "", err
+	}
+	segments := []string{path}
+	for _, e := range elem {
+		segments = append(segments, url.PathEscape(e))
+	}
+	u = u.JoinPath(segments...)
+	style := c.slashes
+	if path == loginEndpoint && len(elem) == 0 {
+		style = SlashCanonical
+	}
+	escaped := u.EscapedPath()
+	switch style {
+	case SlashNever:
+		if escaped != "/" {
+			escaped = strings.TrimRight(escaped, "/")
+		}
+	case SlashAlways:
+		if !strings.HasSuffix(escaped, "/") {
+			escaped += "/"
+		}
+	}
+	if u.Path, err = url.PathUnescape(escaped); err != nil {
+		return "", err
+	}
+	u.RawPath = escaped
+	return u.String(), nil
+}
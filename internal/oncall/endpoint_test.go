@@ -0,0 +1,54 @@
+package oncall
+
+import "testing"
+
+func TestEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		base  string
+		style SlashStyle
+		elem  []string
+		want  string
+	}{
+		{"canonical collection", "http://oncall", SlashCanonical, []string{teamsEndpoint}, "http://oncall/api/v0/teams/"},
+		{"canonical entity", "http://oncall", SlashCanonical, []string{teamsEndpoint, "infra"}, "http://oncall/api/v0/teams/infra"},
+		{"canonical nested", "http://oncall", SlashCanonical, []string{teamsEndpoint, "infra", "users"}, "http://oncall/api/v0/teams/infra/users"},
+		{"never collection", "http://oncall", SlashNever, []string{teamsEndpoint}, "http://oncall/api/v0/teams"},
+		{"never entity", "http://oncall", SlashNever, []string{teamsEndpoint, "infra"}, "http://oncall/api/v0/teams/infra"},
+		{"never root", "http://oncall", SlashNever, []string{"/"}, "http://oncall/"},
+		{"always collection", "http://oncall", SlashAlways, []string{teamsEndpoint}, "http://oncall/api/v0/teams/"},
+		{"always entity", "http://oncall", SlashAlways, []string{teamsEndpoint, "infra"}, "http://oncall/api/v0/teams/infra/"},
+		{"login canonical", "http://oncall", SlashCanonical, []string{loginEndpoint}, "http://oncall/login"},
+		{"login never", "http://oncall", SlashNever, []string{loginEndpoint}, "http://oncall/login"},
+		{"login always", "http://oncall", SlashAlways, []string{loginEndpoint}, "http://oncall/login"},
+		{"path prefix", "https://ops.example.com/oncall", SlashCanonical, []string{teamsEndpoint, "infra"}, "https://ops.example.com/oncall/api/v0/teams/infra"},
+		{"path prefix with slash", "https://ops.example.com/oncall/", SlashCanonical, []string{usersEndpoint}, "https://ops.example.com/oncall/api/v0/users/"},
+		{"path prefix never", "https://ops.example.com/oncall/", SlashNever, []string{usersEndpoint}, "https://ops.example.com/oncall/api/v0/users"},
+		{"path prefix always", "https://ops.example.com/oncall", SlashAlways, []string{teamsEndpoint, "infra", "summary"}, "https://ops.example.com/oncall/api/v0/teams/infra/summary/"},
+		{"path prefix login", "https://ops.example.com/oncall", SlashAlways, []string{loginEndpoint}, "https://ops.example.com/oncall/login"},
+		{"escaped space", "http://oncall", SlashCanonical, []string{teamsEndpoint, "site reliability"}, "http://oncall/api/v0/teams/site%20reliability"},
+		{"escaped question mark", "http://oncall", SlashCanonical, []string{teamsEndpoint, "what?", "users"}, "http://oncall/api/v0/teams/what%3F/users"},
+		{"escaped percent", "http://oncall", SlashNever, []string{teamsEndpoint, "100%"}, "http://oncall/api/v0/teams/100%25"},
+		{"escaped slash", "http://oncall", SlashCanonical, []string{teamsEndpoint, "a/b", "users", "c d"}, "http://oncall/api/v0/teams/a%2Fb/users/c%20d"},
+		{"escaped path", "http://oncall", SlashNever, []string{"/api/v0/teams/site%20reliability/"}, "http://oncall/api/v0/teams/site%20reliability"},
+		{"escaped unicode", "http://oncall", SlashAlways, []string{teamsEndpoint, "дежурные"}, "http://oncall/api/v0/teams/%D0%B4%D0%B5%D0%B6%D1%83%D1%80%D0%BD%D1%8B%D0%B5/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{oncallURL: tc.base, slashes: tc.style}
+			got, err := c.endpoint(tc.elem[0], tc.elem[1:]...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("endpoint(%q) = %s, want %s", tc.elem, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEndpointInvalidURL(t *testing.T) {
+	c := &Client{oncallURL: "http://oncall:port"}
+	if _, err := c.endpoint(teamsEndpoint); err == nil {
+		t.Error("expected an error for an invalid oncall URL")
+	}
+}
@@ -1,6 +1,12 @@
 package oncall
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -9,24 +15,229 @@ type Config struct {
 }
 
 type Team struct {
-	Name               string `yaml:"name"`
-	SchedulingTimezone string `yaml:"scheduling_timezone"`
-	Email              string `yaml:"email"`
-	SlackChannel       string `yaml:"slack_channel"`
-	Users              []User `yaml:"users"`
+	Name                string `yaml:"name,omitempty"`
+	SchedulingTimezone  string `yaml:"scheduling_timezone,omitempty"`
+	Email               string `yaml:"email,omitempty"`
+	SlackChannel        string `yaml:"slack_channel,omitempty"`
+	OverridePhoneNumber string `yaml:"override_phone_number,omitempty"`
+	IrisPlan            string `yaml:"iris_plan,omitempty"`
+	// IrisEnabled and APIManagedRoster are left as they are on oncall when not set
+	IrisEnabled      *bool  `yaml:"iris_enabled,omitempty"`
+	Description      string `yaml:"description,omitempty"`
+	APIManagedRoster *bool  `yaml:"api_managed_roster,omitempty"`
+	Users            []User `yaml:"users,omitempty"`
+	// Rosters are created after the users, their members must be users of the team
+	Rosters []Roster `yaml:"rosters,omitempty"`
+	// Services are the services owned by the team, used by alert routing
+	Services []string `yaml:"services,omitempty"`
+	// Admins are users granted admin rights on the team, in addition to the root user
+	Admins []string `yaml:"admins,omitempty"`
+	// ExpectOnCall maps a role to the user that should be on call once schedules are created.
+	// It is only asserted by the prober.
+	ExpectOnCall map[string]string `yaml:"expect_on_call,omitempty"`
 }
 
 type User struct {
-	Name        string `yaml:"name"`
-	FullName    string `yaml:"full_name"`
-	PhoneNumber string `yaml:"phone_number"`
-	Email       string `yaml:"email"`
-	Schedule    []Duty `yaml:"duty"`
+	Name        string `yaml:"name,omitempty"`
+	FullName    string `yaml:"full_name,omitempty"`
+	PhoneNumber string `yaml:"phone_number,omitempty"`
+	Email       string `yaml:"email,omitempty"`
+	Schedule    []Duty `yaml:"duty,omitempty"`
+	// Notifications are the reminders of the user, created in the team the user is configured in
+	Notifications []Notification `yaml:"notifications,omitempty"`
 }
 
+// Notification is a rule of how a user is notified of shifts of some roles in a team
+type Notification struct {
+	// Roles are the roles the rule applies to, e.g. "primary"
+	Roles []string `yaml:"roles,omitempty"`
+	// Mode is the contact mode: "email", "sms", "call" or "slack"
+	Mode string `yaml:"mode,omitempty"`
+	// Type is "oncall_reminder" (default) or "offcall_reminder"
+	Type string `yaml:"type,omitempty"`
+	// TimeBefore is how long before the start (or end) of the shift the user is notified
+	TimeBefore     time.Duration `yaml:"time_before,omitempty"`
+	OnlyIfInvolved *bool         `yaml:"only_if_involved,omitempty"`
+}
+
+// Roster is a group of team members that schedulers rotate through
+type Roster struct {
+	Name      string           `yaml:"name,omitempty"`
+	Users     []RosterMember   `yaml:"users,omitempty"`
+	Schedules []RosterSchedule `yaml:"schedules,omitempty"`
+}
+
+// RosterSchedule is a schedule of a roster, populated by a scheduler with events of its members
+type RosterSchedule struct {
+	Role string `yaml:"role,omitempty"`
+	// Scheduler is "default", "round-robin" or "no-skip-matching". It defaults to "default".
+	Scheduler string `yaml:"scheduler,omitempty"`
+	// Order is the user rotation of the round-robin scheduler
+	Order []string `yaml:"order,omitempty"`
+	// AutoPopulateThreshold is the number of days oncall keeps the schedule populated ahead
+	AutoPopulateThreshold int  `yaml:"auto_populate_threshold,omitempty"`
+	AdvancedMode          bool `yaml:"advanced_mode,omitempty"`
+	// Events are the weekly shifts of the schedule
+	Events []ScheduleEvent `yaml:"events,omitempty"`
+	// Populate fills the schedule from now on once it is created
+	Populate bool `yaml:"populate,omitempty"`
+}
+
+// RosterMember is a user of a roster. Members are in rotation unless InRotation is false.
+type RosterMember struct {
+	Name       string `yaml:"name,omitempty"`
+	InRotation *bool  `yaml:"in_rotation,omitempty"`
+}
+
+// Duty is a day of duty of a user. A user holding several roles on the same day lists
+// them in Roles instead of repeating the duty, Role and Roles can be combined.
+//
+// The duty lasts the whole local day of the team unless it sets shift times, see Shift:
+//
+//   - date: "02/10/2023"
+//     role: primary
+//     start_time: "08:00"
+//     end_time: "20:00"
+//
+// A duty recurs from Date with one of Every, Repeat or WeeklyRotation, see Occurrences:
+//
+//   - date: "02/10/2023"
+//     role: primary
+//     every: monday,thursday
+//     until: "31/12/2023"
+//   - date: "03/10/2023"
+//     role: secondary
+//     repeat: {interval: 7d, count: 12}
 type Duty struct {
-	Date string `yaml:"date"`
-	Role string `yaml:"role"`
+	Date  string   `yaml:"date,omitempty"`
+	Role  string   `yaml:"role,omitempty"`
+	Roles []string `yaml:"roles,omitempty"`
+	// StartTime is the local time of Date the shift starts at, formatted as DutyTimeLayout
+	StartTime string `yaml:"start_time,omitempty"`
+	// EndTime is the local time the shift ends at, on the next day if it is not after StartTime.
+	// It excludes Duration.
+	EndTime  string        `yaml:"end_time,omitempty"`
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// Timezone is the IANA location of the day and times of the duty, the scheduling timezone
+	// of the team by default
+	Timezone string `yaml:"timezone,omitempty"`
+	// Every lists the weekdays the duty recurs on, comma separated, or day for every day.
+	// It needs Until.
+	Every          string          `yaml:"every,omitempty"`
+	Repeat         *Repeat         `yaml:"repeat,omitempty"`
+	WeeklyRotation *WeeklyRotation `yaml:"weekly_rotation,omitempty"`
+	// Until is the last day a recurring duty can fall on, inclusive
+	Until string `yaml:"until,omitempty"`
+}
+
+// Shift returns the start and end of the duty in its Timezone, or tz, the scheduling timezone
+// of its team, when it has none: from StartTime, midnight by default, to EndTime or after
+// Duration, the same time of the next day by default. Without any timezone the duty is in UTC.
+func (d Duty) Shift(tz string) (start, end time.Time, err error) {
+	if d.Timezone != "" {
+		tz = d.Timezone
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return start, end, fmt.Errorf("unknown timezone %q", tz)
+	}
+	day, err := time.ParseInLocation(DutyDateLayout, d.Date, loc)
+	if err != nil {
+		return start, end, fmt.Errorf("date %q is not formatted as dd/mm/yyyy", d.Date)
+	}
+	start = day
+	if d.StartTime != "" {
+		h, m, err := parseDutyTime(d.StartTime)
+		if err != nil {
+			return start, end, fmt.Errorf("start_time %q is not formatted as hh:mm", d.StartTime)
+		}
+		start = time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc)
+	}
+	switch {
+	case d.EndTime != "" && d.Duration != 0:
+		return start, end, errors.New("only one of end_time and duration can be set")
+	case d.EndTime != "":
+		h, m, err := parseDutyTime(d.EndTime)
+		if err != nil {
+			return start, end, fmt.Errorf("end_time %q is not formatted as hh:mm", d.EndTime)
+		}
+		if end = time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc); !end.After(start) {
+			end = time.Date(day.Year(), day.Month(), day.Day()+1, h, m, 0, 0, loc)
+		}
+	case d.Duration < 0:
+		return start, end, fmt.Errorf("duration %s is negative", d.Duration)
+	case d.Duration > 0:
+		end = start.Add(d.Duration)
+	default:
+		// local days last 23 or 25 hours on DST transitions
+		end = start.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// parseDutyTime returns the hour and minute of s, formatted as DutyTimeLayout
+func parseDutyTime(s string) (int, int, error) {
+	t, err := time.Parse(DutyTimeLayout, s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// locations caches the locations loaded by loadLocation
+var locations sync.Map
+
+// loadLocation loads the IANA location name, UTC if it is empty
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Expand returns one duty per distinct role of d, each with only Role and the shift set
+func (d Duty) Expand() []Duty {
+	roles := d.Roles
+	if d.Role != "" {
+		roles = append([]string{d.Role}, roles...)
+	}
+	var res []Duty
+	for i, role := range roles {
+		if slices.Contains(roles[:i], role) {
+			continue
+		}
+		res = append(res, Duty{
+			Date:      d.Date,
+			Role:      role,
+			StartTime: d.StartTime,
+			EndTime:   d.EndTime,
+			Duration:  d.Duration,
+			Timezone:  d.Timezone,
+		})
+	}
+	return res
+}
+
+// ExpandDuties expands each duty of schedule into its occurrences, then into its roles, see
+// Duty.Occurrences and Duty.Expand. Duties with an invalid recurrence rule are left out,
+// Config.Validate reports them.
+func ExpandDuties(schedule []Duty) []Duty {
+	var res []Duty
+	for _, d := range schedule {
+		occurrences, _ := d.Occurrences()
+		for _, o := range occurrences {
+			res = append(res, o.Expand()...)
+		}
+	}
+	return res
 }
 
 // Response helps to record the time taken for a request
@@ -36,4 +247,212 @@ type Response[T any] struct {
 	URLPath      string
 	ResponseTime time.Duration
 	StatusCode   int
+	// URL is the final URL of the request, after redirects
+	URL string
+	// Header holds the response headers
+	Header http.Header
+	// TLS is the state of the connection the response was received on, nil over http
+	TLS *tls.ConnectionState
+	// RawBody holds the first 64KiB of the response body, whatever the status code
+	RawBody []byte
+	// Method, RequestHeader and RequestBody describe the request sent. RequestHeader holds the
+	// credentials of the session and must be redacted before the request is shown anywhere.
+	Method        string
+	RequestHeader http.Header
+	RequestBody   []byte
+}
+
+// UserRecord is a user as stored by the oncall server
+type UserRecord struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	TimeZone string   `json:"time_zone"`
+	PhotoURL string   `json:"photo_url"`
+	Active   bool     `json:"active"`
+	God      bool     `json:"god"`
+	Contacts Contacts `json:"contacts"`
+}
+
+// Contacts are the modes a user can be reached with
+type Contacts struct {
+	Call  string `json:"call"`
+	Email string `json:"email"`
+	SMS   string `json:"sms"`
+	Slack string `json:"slack"`
+}
+
+// UserFilter narrows down the users returned by GetUsers. Zero fields are ignored.
+type UserFilter struct {
+	Name string
+	// NameContains matches a part of the user name
+	NameContains string
+	Team         string
+	Active       *bool
+}
+
+// TeamFilter narrows down the teams listed by ListTeams and TeamsIterator. Zero fields are ignored.
+type TeamFilter struct {
+	// Name matches the team name exactly
+	Name string
+	// NameContains, NamePrefix and NameSuffix match a part of the team name
+	NameContains string
+	NamePrefix   string
+	NameSuffix   string
+	Active       *bool
+}
+
+// TeamRecord is a team as stored by the oncall server, including its members,
+// admins, rosters and services
+type TeamRecord struct {
+	ID                        int64                   `json:"id"`
+	Name                      string                  `json:"name"`
+	Email                     string                  `json:"email"`
+	SlackChannel              string                  `json:"slack_channel"`
+	SlackChannelNotifications string                  `json:"slack_channel_notifications"`
+	SchedulingTimezone        string                  `json:"scheduling_timezone"`
+	OverridePhoneNumber       string                  `json:"override_phone_number"`
+	IrisPlan                  string                  `json:"iris_plan"`
+	IrisEnabled               bool                    `json:"iris_enabled"`
+	Description               string                  `json:"description"`
+	APIManagedRoster          bool                    `json:"api_managed_roster"`
+	Users                     map[string]UserRecord   `json:"users"`
+	Admins                    []UserRecord            `json:"admins"`
+	Services                  []string                `json:"services"`
+	Rosters                   map[string]RosterRecord `json:"rosters"`
+}
+
+// RosterRecord is a roster of a team with its members and schedules
+type RosterRecord struct {
+	ID        int64            `json:"id"`
+	Users     []RosterUser     `json:"users"`
+	Schedules []ScheduleRecord `json:"schedules"`
+}
+
+// RosterUser is a member of a roster
+type RosterUser struct {
+	Name       string `json:"name"`
+	InRotation bool   `json:"in_rotation"`
+}
+
+// ScheduleRecord is a schedule attached to a roster
+type ScheduleRecord struct {
+	ID                    int64           `json:"id"`
+	Role                  string          `json:"role"`
+	Roster                string          `json:"roster"`
+	AutoPopulateThreshold int             `json:"auto_populate_threshold"`
+	Events                []ScheduleEvent `json:"events"`
+}
+
+// ScheduleEvent is a shift of a schedule. Start is in seconds from the start of the week
+// (Sunday 00:00 in the team timezone) and Duration in seconds.
+type ScheduleEvent struct {
+	Start    int64 `json:"start"    yaml:"start"`
+	Duration int64 `json:"duration" yaml:"duration"`
+}
+
+// Event is a shift of a user in a team as stored by the oncall server
+type Event struct {
+	ID         int64  `json:"id"`
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+	User       string `json:"user"`
+	FullName   string `json:"full_name"`
+	Team       string `json:"team"`
+	Role       string `json:"role"`
+	ScheduleID *int64 `json:"schedule_id"`
+	LinkID     string `json:"link_id"`
+	Note       string `json:"note"`
+}
+
+// Shift is a current or next shift of a user, as listed by the summary of a team
+type Shift struct {
+	User     string `json:"user"`
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+}
+
+// Summary is the current and next shifts of a team with their users, keyed by role
+type Summary struct {
+	Current map[string][]Shift `json:"current"`
+	Next    map[string][]Shift `json:"next"`
+}
+
+// Counts returns the number of users currently on call, keyed by role
+func (s Summary) Counts() map[string]int {
+	counts := make(map[string]int, len(s.Current))
+	for role, shifts := range s.Current {
+		counts[role] = len(shifts)
+	}
+	return counts
+}
+
+// OnCall returns the names of the users currently on call for role
+func (s Summary) OnCall(role string) []string {
+	var users []string
+	for _, shift := range s.Current[role] {
+		users = append(users, shift.User)
+	}
+	return users
+}
+
+// Rotation returns when the users on call for role change next: the end of the earliest
+// current shift, or the start of the earliest next shift when nobody is on call. It is
+// false when the role has no shift.
+func (s Summary) Rotation(role string) (time.Time, bool) {
+	var at int64
+	for _, shift := range s.Current[role] {
+		if at == 0 || shift.End < at {
+			at = shift.End
+		}
+	}
+	if at == 0 {
+		for _, shift := range s.Next[role] {
+			if at == 0 || shift.Start < at {
+				at = shift.Start
+			}
+		}
+	}
+	if at == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(at, 0), true
+}
+
+// EventFilter narrows down the events returned by GetEvents. Zero fields are ignored.
+// Start and End select the events lying within [Start, End].
+type EventFilter struct {
+	Team  string
+	User  string
+	Role  string
+	Start time.Time
+	End   time.Time
+}
+
+// LinkedEvents is the result of creating linked events
+type LinkedEvents struct {
+	LinkID   string  `json:"link_id"`
+	EventIDs []int64 `json:"event_ids"`
+}
+
+// OverrideRequest hands the part of events EventIDs within [Start, End) over to User.
+// oncall splits the events around the override.
+type OverrideRequest struct {
+	User     string
+	Start    time.Time
+	End      time.Time
+	EventIDs []int64
+}
+
+// NotificationRecord is a notification rule as stored by the oncall server
+type NotificationRecord struct {
+	ID             int64    `json:"id"`
+	Team           string   `json:"team"`
+	Roles          []string `json:"roles"`
+	Mode           string   `json:"mode"`
+	Type           string   `json:"type"`
+	TimeBefore     int64    `json:"time_before"`
+	OnlyIfInvolved *bool    `json:"only_if_involved"`
 }
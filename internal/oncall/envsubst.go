@@ -0,0 +1,96 @@
+package oncall
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandEnv replaces in place the ${VAR} and ${VAR:-default} references of the scalar values
+// under n with the environment, default being used when VAR is unset or empty. $${ is a
+// literal ${. A plain scalar is resolved again once expanded, so that ${ENABLED:-false} is a
+// boolean. Every reference to an unset variable without default is returned in a *SchemaError.
+func expandEnv(n *yaml.Node, filename string) error {
+	e := envExpansion{file: filename, lookup: os.LookupEnv}
+	e.walk(n, "")
+	if len(e.errs) > 0 {
+		return &SchemaError{Errors: e.errs}
+	}
+	return nil
+}
+
+type envExpansion struct {
+	file   string
+	lookup func(string) (string, bool)
+	errs   []*FieldError
+}
+
+func (e *envExpansion) walk(n *yaml.Node, path string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			e.walk(c, path)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			e.walk(c, path+"["+strconv.Itoa(i)+"]")
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			e.walk(n.Content[i+1], fieldPath(path, n.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return
+		}
+		value, err := e.expand(n.Value)
+		if err != nil {
+			e.errs = append(e.errs, &FieldError{File: e.file, Line: n.Line, Column: n.Column, Path: path, Err: err})
+			return
+		}
+		n.Value = value
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+}
+
+func (e *envExpansion) expand(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i])
+			b.WriteString("{")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unterminated ${ in %q", ErrInvalidRequest, s)
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if name == "" {
+			return "", fmt.Errorf("%w: empty environment variable reference", ErrInvalidRequest)
+		}
+		value, set := e.lookup(name)
+		switch {
+		case value != "":
+		case hasDefault:
+			value = def
+		case !set:
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrInvalidRequest, name)
+		}
+		b.WriteString(value)
+	}
+}
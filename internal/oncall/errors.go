@@ -0,0 +1,166 @@
+package oncall
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLoginFailed     = errors.New("login failed")
+	ErrInvalidEndpoint = errors.New("invalid endpoint")
+	ErrInvalidRequest  = errors.New("invalid request")
+
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrRateLimited matches the requests oncall answered with 429 Too Many Requests
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// maxErrorBody is the maximum number of bytes of a failed response kept in APIError
+const maxErrorBody = 4 << 10
+
+// APIError is returned when oncall answers a request with a non-2xx status code.
+// It matches ErrNotFound, ErrConflict, ErrUnauthorized, ErrForbidden and ErrRateLimited with
+// errors.Is depending on the status code.
+type APIError struct {
+	StatusCode int
+	Endpoint   string
+	Body       string
+	// RetryAfter is the delay of the Retry-After header of a 429 or 503 response, 0 without it
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("oncall %s: status %d", e.Endpoint, e.StatusCode)
+	}
+	return fmt.Sprintf("oncall %s: status %d: %s", e.Endpoint, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == statusAuthenticationTimeout
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConflict:
+		// oncall reports duplicate entities as 422 with an "already exists" description
+		return e.StatusCode == http.StatusConflict ||
+			(e.StatusCode == http.StatusUnprocessableEntity && strings.Contains(e.Body, "already exists"))
+	}
+	return false
+}
+
+// checkResponse returns an *APIError if res does not have a 2xx status code.
+// The body is consumed in that case.
+func checkResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	return &APIError{
+		StatusCode: res.StatusCode,
+		Endpoint:   res.Request.URL.Path,
+		Body:       strings.TrimSpace(string(b)),
+		RetryAfter: retryAfter(res, time.Now()),
+	}
+}
+
+// EntityError is the failure of an operation on a single entity
+type EntityError struct {
+	// Op is the failed operation, e.g. "create" or "add_to_team"
+	Op string
+	// Kind is the kind of entity: "team", "user", "event", "roster", "schedule", "service" or "notification"
+	Kind string
+	Name string
+	// Team is the team the entity belongs to, if any
+	Team string
+	// Detail identifies the entity further when Name is not enough, e.g. the date of an event
+	Detail string
+	Err    error
+}
+
+func (e *EntityError) Error() string {
+	s := e.Op + " " + e.Kind + " " + strconv.Quote(e.Name)
+	if e.Team != "" && e.Kind != "team" {
+		s += " in team " + strconv.Quote(e.Team)
+	}
+	if e.Detail != "" {
+		s += " (" + e.Detail + ")"
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+func (e *EntityError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Op     string `json:"op"`
+		Kind   string `json:"kind"`
+		Name   string `json:"name"`
+		Team   string `json:"team,omitempty"`
+		Detail string `json:"detail,omitempty"`
+		Error  string `json:"error"`
+	}{e.Op, e.Kind, e.Name, e.Team, e.Detail, e.Err.Error()})
+}
+
+// MultiError collects the failures of a batch of operations such as CreateEntities.
+// errors.Is and errors.As look into every entry.
+type MultiError struct {
+	Errors []*EntityError `json:"errors"`
+}
+
+// Add records err as the failure of op on an entity. Entries of a *MultiError err are merged as is.
+func (m *MultiError) Add(op, kind, name, team string, err error) {
+	m.addDetail(op, kind, name, team, "", err)
+}
+
+func (m *MultiError) addDetail(op, kind, name, team, detail string, err error) {
+	if err == nil {
+		return
+	}
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		m.Errors = append(m.Errors, multi.Errors...)
+		return
+	}
+	m.Errors = append(m.Errors, &EntityError{Op: op, Kind: kind, Name: name, Team: team, Detail: detail, Err: err})
+}
+
+// Err returns m if it has entries and nil otherwise
+func (m *MultiError) Err() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.Errors))
+	for i, e := range m.Errors {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = e
+	}
+	return errs
+}
@@ -0,0 +1,154 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// GetEvents lists the events matching filter
+func (c *Client) GetEvents(ctx context.Context, filter EventFilter) (*Response[[]Event], error) {
+	logger := c.logger.With().Str("action", "get_events").Logger()
+	endpoint, err := c.endpoint(scheduleEndpoint)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	q := url.Values{}
+	if filter.Team != "" {
+		q.Set("team", filter.Team)
+	}
+	if filter.User != "" {
+		q.Set("user", filter.User)
+	}
+	if filter.Role != "" {
+		q.Set("role", filter.Role)
+	}
+	if !filter.Start.IsZero() {
+		q.Set("start__ge", strconv.FormatInt(filter.Start.Unix(), 10))
+	}
+	if !filter.End.IsZero() {
+		q.Set("end__le", strconv.FormatInt(filter.End.Unix(), 10))
+	}
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	return doJSON[[]Event](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// UpdateEvent changes the fields of event id that are set in data
+func (c *Client) UpdateEvent(ctx context.Context, id int64, data dto.ScheduleDTO) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "update_event").Int64("event", id).Logger()
+	endpoint, err := c.endpoint(scheduleEndpoint, strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, data)
+}
+
+// DeleteEvent removes event id. With delete protection, the team and user of the event are
+// fetched first and must be allowed.
+func (c *Client) DeleteEvent(ctx context.Context, id int64) error {
+	logger := c.logger.With().Str("action", "delete_event").Int64("event", id).Logger()
+	if c.guard.enabled {
+		event, err := c.GetEvent(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = errors.Join(c.guard.check("team", event.Data.Team), c.guard.check("user", event.Data.User)); err != nil {
+			logger.Warn().Err(err).Send()
+			return err
+		}
+	}
+	endpoint, err := c.endpoint(scheduleEndpoint, strconv.FormatInt(id, 10))
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// CreateLinkedEvents creates events of a single user in one request through /api/v0/events/link.
+// oncall links them so they can later be swapped or edited together.
+func (c *Client) CreateLinkedEvents(ctx context.Context, events []dto.ScheduleDTO) (*Response[LinkedEvents], error) {
+	logger := c.logger.With().Str("action", "create_linked_events").Int("events", len(events)).Logger()
+	endpoint, err := c.endpoint(scheduleEndpoint, "link")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[LinkedEvents](ctx, c, logger, http.MethodPost, endpoint, events)
+}
+
+// GetEvent returns a single event by id
+func (c *Client) GetEvent(ctx context.Context, id int64) (*Response[Event], error) {
+	logger := c.logger.With().Str("action", "get_event").Int64("event", id).Logger()
+	endpoint, err := c.endpoint(scheduleEndpoint, strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[Event](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// SwapEvents trades the events of eventsA with those of eventsB through /api/v0/events/swap:
+// the users of both sides are exchanged. A side of several events must be linked events.
+func (c *Client) SwapEvents(ctx context.Context, eventsA, eventsB []int64) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "swap_events").Logger()
+	var data dto.SwapDTO
+	for i, ids := range [2][]int64{eventsA, eventsB} {
+		side, err := c.swapSide(ctx, ids)
+		if err != nil {
+			logger.Warn().Err(err).Ints64("events", ids).Send()
+			return nil, err
+		}
+		data.Events[i] = side
+	}
+	endpoint, err := c.endpoint(scheduleEndpoint, "swap")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, data)
+}
+
+// swapSide identifies a side of a swap, by event id or by the link shared by all ids
+func (c *Client) swapSide(ctx context.Context, ids []int64) (dto.SwapEventDTO, error) {
+	switch len(ids) {
+	case 0:
+		return dto.SwapEventDTO{}, fmt.Errorf("%w: no events to swap", ErrInvalidRequest)
+	case 1:
+		return dto.SwapEventDTO{ID: ids[0]}, nil
+	}
+	var linkID string
+	for _, id := range ids {
+		res, err := c.GetEvent(ctx, id)
+		if err != nil {
+			return dto.SwapEventDTO{}, err
+		}
+		if res.Data.LinkID == "" || (linkID != "" && res.Data.LinkID != linkID) {
+			return dto.SwapEventDTO{}, fmt.Errorf("%w: events %v are not linked together", ErrInvalidRequest, ids)
+		}
+		linkID = res.Data.LinkID
+	}
+	return dto.SwapEventDTO{ID: linkID, Linked: true}, nil
+}
+
+// OverrideEvents lets a user cover part of the events of someone else through /api/v0/events/override
+func (c *Client) OverrideEvents(ctx context.Context, r OverrideRequest) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "override_events").Str("user", r.User).Ints64("events", r.EventIDs).Logger()
+	if len(r.EventIDs) == 0 || !r.End.After(r.Start) {
+		return nil, fmt.Errorf("%w: override needs events and a non empty time range", ErrInvalidRequest)
+	}
+	endpoint, err := c.endpoint(scheduleEndpoint, "override")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.OverrideDTO{
+		Start:    r.Start.Unix(),
+		End:      r.End.Unix(),
+		User:     r.User,
+		EventIDs: r.EventIDs,
+	})
+}
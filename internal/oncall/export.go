@@ -0,0 +1,180 @@
+package oncall
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ExportConfig reads teams from oncall, every team if none is given, into a Config that
+// LoadConfig and CreateEntities accept: the teams with their members, contacts, notifications,
+// rosters, services and admins, and the upcoming events of the members as duties.
+//
+// Only events not created by a roster schedule are exported, covering whole days or shorter
+// than a day and written to the minute, as duties are days or shifts. Days are those of the
+// scheduling timezone of the team. The scheduler of the roster schedules is not exported, oncall does not
+// list it with the team. The returned *MultiError lists the entities that could not be read.
+func (c *Client) ExportConfig(ctx context.Context, teams ...string) (Config, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	state, err := c.Snapshot(ctx, SnapshotOptions{Teams: teams, Events: true, EventsStart: today})
+	if state == nil {
+		return Config{}, err
+	}
+	var errs MultiError
+	var multi *MultiError
+	if errors.As(err, &multi) {
+		errs.Errors = append(errs.Errors, multi.Errors...)
+	} else if err != nil {
+		return Config{}, err
+	}
+
+	notifications := make(map[string][]NotificationRecord)
+	var config Config
+	for _, name := range sortedKeys(state.Teams) {
+		record := state.Teams[name]
+		t := Team{
+			Name:                record.Name,
+			SchedulingTimezone:  record.SchedulingTimezone,
+			Email:               record.Email,
+			SlackChannel:        record.SlackChannel,
+			OverridePhoneNumber: record.OverridePhoneNumber,
+			IrisPlan:            record.IrisPlan,
+			Description:         record.Description,
+			Services:            record.Services,
+		}
+		if record.IrisEnabled {
+			t.IrisEnabled = &record.IrisEnabled
+		}
+		if record.APIManagedRoster {
+			t.APIManagedRoster = &record.APIManagedRoster
+		}
+		for _, a := range record.Admins {
+			t.Admins = append(t.Admins, a.Name)
+		}
+		loc, err := loadLocation(record.SchedulingTimezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		duties := exportDuties(state.Events[name], loc)
+		for _, username := range sortedKeys(record.Users) {
+			u := record.Users[username]
+			if full, ok := state.Users[username]; ok {
+				u = full
+			}
+			if _, ok := notifications[username]; !ok {
+				res, err := c.GetNotifications(ctx, username)
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					errs.Add("export", "notification", username, name, err)
+				}
+				notifications[username] = nil
+				if res != nil {
+					notifications[username] = res.Data
+				}
+			}
+			t.Users = append(t.Users, User{
+				Name:          u.Name,
+				FullName:      u.FullName,
+				PhoneNumber:   u.Contacts.Call,
+				Email:         u.Contacts.Email,
+				Schedule:      duties[username],
+				Notifications: exportNotifications(notifications[username], name),
+			})
+		}
+		for _, rosterName := range sortedKeys(record.Rosters) {
+			t.Rosters = append(t.Rosters, exportRoster(rosterName, record.Rosters[rosterName]))
+		}
+		config.Teams = append(config.Teams, t)
+	}
+	return config, errs.Err()
+}
+
+// exportDuties converts the events of a team into the duties of each user: events of whole
+// days of loc into one duty per day, shorter events into shifts, a duty listing the roles of the
+// day or shift
+func exportDuties(events []Event, loc *time.Location) map[string][]Duty {
+	const day = int64(24 * time.Hour / time.Second)
+	midnight := func(t time.Time) bool {
+		return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+	}
+	slices.SortFunc(events, func(a, b Event) int { return cmp.Compare(a.Start, b.Start) })
+	duties := make(map[string][]Duty)
+	add := func(e Event, d Duty) {
+		user := duties[e.User]
+		i := slices.IndexFunc(user, func(o Duty) bool {
+			return o.Date == d.Date && o.StartTime == d.StartTime && o.EndTime == d.EndTime
+		})
+		if i < 0 {
+			duties[e.User] = append(user, d)
+			return
+		}
+		if user[i].Role != e.Role && !slices.Contains(user[i].Roles, e.Role) {
+			user[i].Roles = append(user[i].Roles, e.Role)
+		}
+	}
+	for _, e := range events {
+		if e.ScheduleID != nil || e.End <= e.Start {
+			continue
+		}
+		start, end := time.Unix(e.Start, 0).In(loc), time.Unix(e.End, 0).In(loc)
+		if midnight(start) && midnight(end) {
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				add(e, Duty{Date: d.Format(DutyDateLayout), Role: e.Role})
+			}
+			continue
+		}
+		// shifts are written to the minute and end within a day
+		if e.Start%60 != 0 || e.End%60 != 0 || e.End-e.Start >= day {
+			continue
+		}
+		add(e, Duty{
+			Date:      start.Format(DutyDateLayout),
+			Role:      e.Role,
+			StartTime: start.Format(DutyTimeLayout),
+			EndTime:   end.Format(DutyTimeLayout),
+		})
+	}
+	return duties
+}
+
+// exportNotifications converts the notifications of a user in team
+func exportNotifications(records []NotificationRecord, team string) []Notification {
+	var res []Notification
+	for _, r := range records {
+		if r.Team != team {
+			continue
+		}
+		n := Notification{
+			Roles:          r.Roles,
+			Mode:           r.Mode,
+			TimeBefore:     time.Duration(r.TimeBefore) * time.Second,
+			OnlyIfInvolved: r.OnlyIfInvolved,
+		}
+		if r.Type != defaultNotificationType {
+			n.Type = r.Type
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+func exportRoster(name string, record RosterRecord) Roster {
+	r := Roster{Name: name}
+	for _, u := range record.Users {
+		m := RosterMember{Name: u.Name}
+		if !u.InRotation {
+			inRotation := false
+			m.InRotation = &inRotation
+		}
+		r.Users = append(r.Users, m)
+	}
+	for _, s := range record.Schedules {
+		r.Schedules = append(r.Schedules, RosterSchedule{
+			Role:                  s.Role,
+			AutoPopulateThreshold: s.AutoPopulateThreshold,
+			Events:                s.Events,
+		})
+	}
+	return r
+}
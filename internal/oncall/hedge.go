@@ -0,0 +1,131 @@
+package oncall
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// hedgePolicy sends extra attempts of the read requests slow to answer, see WithHedging
+type hedgePolicy struct {
+	delay time.Duration
+	// max is the number of attempts sent in addition to the first one
+	max int
+	// hedges counts the extra attempts sent
+	hedges atomic.Int64
+}
+
+// WithHedging sends another attempt of a GET or HEAD request that oncall has not answered
+// within delay, up to max attempts in addition to the first one, each delay after the last.
+// The first response wins and the other attempts are cancelled, which cuts the tail latency
+// of reads behind a load balancer with a slow replica. A failed attempt is hedged at once.
+// Mutating requests are never hedged.
+func WithHedging(delay time.Duration, max int) Option {
+	return func(c *Client) {
+		c.hedge.delay = delay
+		c.hedge.max = max
+	}
+}
+
+// Hedges returns the number of extra attempts sent by WithHedging
+func (c *Client) Hedges() int64 {
+	return c.hedge.hedges.Load()
+}
+
+func (p *hedgePolicy) allows(method string) bool {
+	return p.max > 0 && p.delay > 0 && (method == http.MethodGet || method == http.MethodHead)
+}
+
+type hedgeResult struct {
+	attempt int
+	res     *http.Response
+	err     error
+}
+
+// sendHedged sends req, hedged when the policy allows its method
+func (c *Client) sendHedged(req *http.Request) (*http.Response, error) {
+	if !c.hedge.allows(req.Method) {
+		return c.send(req)
+	}
+	ctx := req.Context()
+	results := make(chan hedgeResult, c.hedge.max+1)
+	var cancels []context.CancelFunc
+	launch := func() {
+		actx, cancel := context.WithCancel(ctx)
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		r := req.Clone(actx)
+		go func() {
+			res, err := c.send(r)
+			results <- hedgeResult{attempt: attempt, res: res, err: err}
+		}()
+	}
+	launch()
+	pending := 1
+	timer := time.NewTimer(c.hedge.delay)
+	defer timer.Stop()
+	hedge := func() {
+		c.hedge.hedges.Add(1)
+		c.logger.Debug().
+			Str("method", req.Method).
+			Str("url", req.URL.String()).
+			Int("attempt", len(cancels)+1).
+			Msg("hedging request")
+		launch()
+		pending++
+		timer.Reset(c.hedge.delay)
+	}
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) <= c.hedge.max {
+				hedge()
+			}
+		case r := <-results:
+			pending--
+			if r.err != nil && (pending > 0 || len(cancels) <= c.hedge.max) {
+				cancels[r.attempt]()
+				if pending == 0 {
+					hedge()
+				}
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != r.attempt {
+					cancel()
+				}
+			}
+			go closeLosers(results, pending)
+			if r.res == nil {
+				cancels[r.attempt]()
+				return nil, r.err
+			}
+			// the context of the winner lives until its body is closed
+			r.res.Body = &cancelBody{ReadCloser: r.res.Body, cancel: cancels[r.attempt]}
+			return r.res, nil
+		}
+	}
+}
+
+// closeLosers closes the responses of the n attempts still running once they are cancelled
+func closeLosers(results <-chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.res != nil {
+			r.res.Body.Close()
+		}
+	}
+}
+
+// cancelBody cancels the context of its request once closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
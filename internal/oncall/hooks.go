@@ -0,0 +1,55 @@
+package oncall
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// hooks are the interceptors run around each request sent to oncall
+type hooks struct {
+	request  []func(*http.Request)
+	response []func(*http.Response, time.Duration)
+	// signers run after the request hooks, so that the headers they set are signed
+	signers []RequestSigner
+}
+
+// WithRequestHook calls fn with every request right before it is sent, including retries,
+// logins and replays. fn may set headers, it must not consume the body.
+// Hooks run in the order they were added.
+func WithRequestHook(fn func(*http.Request)) Option {
+	return func(c *Client) {
+		c.hooks.request = append(c.hooks.request, fn)
+	}
+}
+
+// WithResponseHook calls fn with every response received from oncall and the time it took.
+// Requests that failed without a response are not reported. fn must not consume the body.
+// Hooks run in the order they were added.
+func WithResponseHook(fn func(*http.Response, time.Duration)) Option {
+	return func(c *Client) {
+		c.hooks.response = append(c.hooks.response, fn)
+	}
+}
+
+// roundTrip sends req through the http client, running the hooks around it
+func (h *hooks) roundTrip(cl *http.Client, req *http.Request) (*http.Response, error) {
+	for _, fn := range h.request {
+		fn(req)
+	}
+	for _, s := range h.signers {
+		if err := s.Sign(req); err != nil {
+			return nil, fmt.Errorf("signing request: %w", err)
+		}
+	}
+	start := time.Now()
+	res, err := cl.Do(req)
+	if err != nil {
+		return res, err
+	}
+	d := time.Since(start)
+	for _, fn := range h.response {
+		fn(res, d)
+	}
+	return res, nil
+}
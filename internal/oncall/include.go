@@ -0,0 +1,159 @@
+package oncall
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the key of the list items replaced by the content of other config files
+const includeKey = "include"
+
+// includes replaces the items of the lists of a config file that include other files, e.g.
+//
+//	teams:
+//	  - include: teams/*.yaml
+//	  - name: payments
+//	    users:
+//	      - include: shared/users.yaml
+//
+// by the items of the included files: the items of a file holding a list, or the file itself
+// when it holds a single item. The paths are globs relative to the directory of the including
+// file, the files are read in the format of their extension and can include other files.
+type includes struct {
+	// files maps the nodes read from included files to their file
+	files map[*yaml.Node]string
+	// stack are the files being included, the first one being the root config file
+	stack []string
+	// opts read the included files, in the format of their extension
+	opts loadOptions
+	errs []*FieldError
+}
+
+// resolveIncludes resolves in place the includes of the config file root read from file. The
+// returned map locates the included nodes, the errors are returned in a *SchemaError.
+func resolveIncludes(root *yaml.Node, file string, o loadOptions) (map[*yaml.Node]string, error) {
+	o.format = ""
+	inc := includes{files: make(map[*yaml.Node]string), stack: []string{filepath.Clean(file)}, opts: o}
+	inc.walk(root, file)
+	if len(inc.errs) > 0 {
+		return nil, &SchemaError{Errors: inc.errs}
+	}
+	return inc.files, nil
+}
+
+func (inc *includes) add(file string, n *yaml.Node, format string, args ...any) {
+	inc.errs = append(inc.errs, &FieldError{
+		File:   file,
+		Line:   n.Line,
+		Column: n.Column,
+		Path:   includeKey,
+		Err:    fmt.Errorf("%w: "+format, append([]any{ErrInvalidRequest}, args...)...),
+	})
+}
+
+func (inc *includes) walk(n *yaml.Node, file string) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			inc.walk(c, file)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			inc.walk(n.Content[i], file)
+		}
+	case yaml.SequenceNode:
+		content := make([]*yaml.Node, 0, len(n.Content))
+		for _, c := range n.Content {
+			paths, ok := includePaths(c)
+			if !ok {
+				inc.walk(c, file)
+				content = append(content, c)
+				continue
+			}
+			for _, p := range paths {
+				content = append(content, inc.include(file, p)...)
+			}
+		}
+		n.Content = content
+	}
+}
+
+// includePaths returns the paths of an item made of the include key only
+func includePaths(n *yaml.Node) ([]*yaml.Node, bool) {
+	if n.Kind != yaml.MappingNode || len(n.Content) != 2 || n.Content[0].Value != includeKey {
+		return nil, false
+	}
+	v := n.Content[1]
+	if v.Kind == yaml.SequenceNode {
+		return v.Content, true
+	}
+	return []*yaml.Node{v}, true
+}
+
+// include returns the items of the files matching the glob of the path node p of file
+func (inc *includes) include(file string, p *yaml.Node) []*yaml.Node {
+	if p.Kind != yaml.ScalarNode || strings.TrimSpace(p.Value) == "" {
+		inc.add(file, p, "expected the path of a config file")
+		return nil
+	}
+	pattern := p.Value
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(file), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		inc.add(file, p, "%v", err)
+		return nil
+	}
+	if len(matches) == 0 {
+		inc.add(file, p, "no config file matches %s", p.Value)
+		return nil
+	}
+	var items []*yaml.Node
+	for _, m := range matches {
+		if i := slices.Index(inc.stack, m); i >= 0 {
+			inc.add(file, p, "include cycle %s", strings.Join(append(inc.stack[i:], m), " -> "))
+			continue
+		}
+		root, err := readConfigNode(m, inc.opts)
+		var schema *SchemaError
+		if errors.As(err, &schema) {
+			inc.errs = append(inc.errs, schema.Errors...)
+			continue
+		}
+		if err != nil {
+			inc.add(file, p, "%v", err)
+			continue
+		}
+		if len(root.Content) == 0 {
+			continue
+		}
+		inc.stack = append(inc.stack, m)
+		inc.walk(root, m)
+		inc.stack = inc.stack[:len(inc.stack)-1]
+		doc := root.Content[0]
+		locate(doc, m, inc.files)
+		if doc.Kind == yaml.SequenceNode {
+			items = append(items, doc.Content...)
+		} else {
+			items = append(items, doc)
+		}
+	}
+	return items
+}
+
+// locate maps n and the nodes under it to file, keeping the files of nested includes
+func locate(n *yaml.Node, file string, files map[*yaml.Node]string) {
+	if _, found := files[n]; found {
+		return
+	}
+	files[n] = file
+	for _, c := range n.Content {
+		locate(c, file, files)
+	}
+}
@@ -0,0 +1,477 @@
+// Package mock provides an in-memory oncall.API for tests that must not reach an oncall server
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall"
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// Client implements oncall.API by calling the matching Func field.
+// Methods whose Func is nil return zero values, with empty responses and reports instead of
+// nil pointers so that callers can read them.
+type Client struct {
+	LoginFunc        func(ctx context.Context) error
+	CallCountsFunc   func() map[string]int64
+	ReloginsFunc     func() int64
+	HedgesFunc       func() int64
+	RateLimitedFunc  func() map[string]int64
+	BreakerStateFunc func() oncall.BreakerState
+	RawFunc          func(ctx context.Context, method, path string, body []byte) (*oncall.Response[[]byte], error)
+
+	CreateEntitiesFunc func(ctx context.Context, config oncall.Config, opts ...oncall.EntityOption) (*oncall.EntityReport, error)
+	DeleteEntitiesFunc func(ctx context.Context, config oncall.Config, opts oncall.DeleteOptions) (*oncall.DeleteReport, error)
+	SnapshotFunc       func(ctx context.Context, opts oncall.SnapshotOptions) (*oncall.ServerState, error)
+	SyncFunc           func(ctx context.Context, config oncall.Config, opts ...oncall.SyncOption) (*oncall.SyncReport, error)
+	ExportConfigFunc   func(ctx context.Context, teams ...string) (oncall.Config, error)
+
+	CreateTeamFunc    func(ctx context.Context, t oncall.Team, returnEarly bool) (*oncall.TeamReport, error)
+	UpdateTeamFunc    func(ctx context.Context, name string, t oncall.Team) (*oncall.Response[any], error)
+	DeleteTeamFunc    func(ctx context.Context, team string) error
+	GetTeamsFunc      func(ctx context.Context) (*oncall.Response[[]string], error)
+	ListTeamsFunc     func(ctx context.Context, filter oncall.TeamFilter) (*oncall.Response[[]string], error)
+	GetTeamFunc       func(ctx context.Context, name string) (*oncall.Response[oncall.TeamRecord], error)
+	GetSummaryFunc    func(ctx context.Context, team string) (*oncall.Response[oncall.Summary], error)
+	GetOnCallFunc     func(ctx context.Context, team string) (*oncall.Response[map[string][]string], error)
+	GetServicesFunc   func(ctx context.Context, team string) (*oncall.Response[[]string], error)
+	AddServiceFunc    func(ctx context.Context, team, service string) (*oncall.Response[any], error)
+	DeleteServiceFunc func(ctx context.Context, team, service string) error
+	GetAdminsFunc     func(ctx context.Context, team string) (*oncall.Response[[]string], error)
+	AddAdminFunc      func(ctx context.Context, team, user string) (*oncall.Response[any], error)
+	DeleteAdminFunc   func(ctx context.Context, team, user string) error
+
+	GetUsersFunc           func(ctx context.Context, filter oncall.UserFilter) (*oncall.Response[[]oncall.UserRecord], error)
+	GetUserFunc            func(ctx context.Context, name string) (*oncall.Response[oncall.UserRecord], error)
+	CreateUserFunc         func(ctx context.Context, u oncall.User) (*oncall.Response[any], error)
+	UpdateUserFunc         func(ctx context.Context, name string, u oncall.User) (*oncall.Response[any], error)
+	DeleteUserFunc         func(ctx context.Context, name string) error
+	ReactivateUserFunc     func(ctx context.Context, name string) (*oncall.Response[any], error)
+	GetNotificationsFunc   func(ctx context.Context, user string) (*oncall.Response[[]oncall.NotificationRecord], error)
+	CreateNotificationFunc func(ctx context.Context, user, team string, n oncall.Notification) (*oncall.Response[any], error)
+	UpdateNotificationFunc func(ctx context.Context, id int64, team string, n oncall.Notification) (*oncall.Response[any], error)
+	DeleteNotificationFunc func(ctx context.Context, user string, id int64) error
+	AddUserToTeamFunc      func(ctx context.Context, username, teamname string) (*oncall.Response[any], error)
+	DeleteUserFromTeamFunc func(ctx context.Context, user, team string) error
+
+	CreateRosterFunc            func(ctx context.Context, team, name string) (*oncall.Response[any], error)
+	DeleteRosterFunc            func(ctx context.Context, team, name string) error
+	AddUserToRosterFunc         func(ctx context.Context, team, roster, user string, inRotation bool) (*oncall.Response[any], error)
+	RemoveUserFromRosterFunc    func(ctx context.Context, team, roster, user string) error
+	SetRosterUserSchedulingFunc func(ctx context.Context, team, roster, user string, inRotation bool) (*oncall.Response[any], error)
+	CreateRosterScheduleFunc    func(ctx context.Context, team, roster string, s oncall.RosterSchedule) (*oncall.Response[int64], error)
+	SetSchedulerFunc            func(ctx context.Context, scheduleID int64, name string, order []string) (*oncall.Response[any], error)
+	PopulateScheduleFunc        func(ctx context.Context, scheduleID int64, start time.Time) (*oncall.Response[any], error)
+
+	GetRolesFunc func(ctx context.Context) (*oncall.Response[[]oncall.RoleRecord], error)
+	RolesFunc    func(ctx context.Context) ([]string, error)
+
+	CreateScheduleFunc     func(ctx context.Context, username, teamname, tz string, schedule []oncall.Duty) error
+	GetEventsFunc          func(ctx context.Context, filter oncall.EventFilter) (*oncall.Response[[]oncall.Event], error)
+	CreateLinkedEventsFunc func(ctx context.Context, events []dto.ScheduleDTO) (*oncall.Response[oncall.LinkedEvents], error)
+	UpdateEventFunc        func(ctx context.Context, id int64, data dto.ScheduleDTO) (*oncall.Response[any], error)
+	DeleteEventFunc        func(ctx context.Context, id int64) error
+	GetEventFunc           func(ctx context.Context, id int64) (*oncall.Response[oncall.Event], error)
+	SwapEventsFunc         func(ctx context.Context, eventsA, eventsB []int64) (*oncall.Response[any], error)
+	OverrideEventsFunc     func(ctx context.Context, r oncall.OverrideRequest) (*oncall.Response[any], error)
+}
+
+var _ oncall.API = (*Client)(nil)
+
+func (c *Client) Login(ctx context.Context) error {
+	if c.LoginFunc == nil {
+		return nil
+	}
+	return c.LoginFunc(ctx)
+}
+
+func (c *Client) CallCounts() map[string]int64 {
+	if c.CallCountsFunc == nil {
+		return nil
+	}
+	return c.CallCountsFunc()
+}
+
+func (c *Client) RateLimited() map[string]int64 {
+	if c.RateLimitedFunc == nil {
+		return nil
+	}
+	return c.RateLimitedFunc()
+}
+
+func (c *Client) Relogins() int64 {
+	if c.ReloginsFunc == nil {
+		return 0
+	}
+	return c.ReloginsFunc()
+}
+
+func (c *Client) Hedges() int64 {
+	if c.HedgesFunc == nil {
+		return 0
+	}
+	return c.HedgesFunc()
+}
+
+func (c *Client) BreakerState() oncall.BreakerState {
+	if c.BreakerStateFunc == nil {
+		return oncall.BreakerClosed
+	}
+	return c.BreakerStateFunc()
+}
+
+func (c *Client) Raw(ctx context.Context, method, path string, body []byte) (*oncall.Response[[]byte], error) {
+	if c.RawFunc == nil {
+		return &oncall.Response[[]byte]{}, nil
+	}
+	return c.RawFunc(ctx, method, path, body)
+}
+
+func (c *Client) CreateEntities(ctx context.Context, config oncall.Config, opts ...oncall.EntityOption) (*oncall.EntityReport, error) {
+	if c.CreateEntitiesFunc == nil {
+		return &oncall.EntityReport{}, nil
+	}
+	return c.CreateEntitiesFunc(ctx, config, opts...)
+}
+
+func (c *Client) DeleteEntities(ctx context.Context, config oncall.Config, opts oncall.DeleteOptions) (*oncall.DeleteReport, error) {
+	if c.DeleteEntitiesFunc == nil {
+		return &oncall.DeleteReport{}, nil
+	}
+	return c.DeleteEntitiesFunc(ctx, config, opts)
+}
+
+func (c *Client) Snapshot(ctx context.Context, opts oncall.SnapshotOptions) (*oncall.ServerState, error) {
+	if c.SnapshotFunc == nil {
+		return &oncall.ServerState{}, nil
+	}
+	return c.SnapshotFunc(ctx, opts)
+}
+
+func (c *Client) Sync(ctx context.Context, config oncall.Config, opts ...oncall.SyncOption) (*oncall.SyncReport, error) {
+	if c.SyncFunc == nil {
+		return &oncall.SyncReport{}, nil
+	}
+	return c.SyncFunc(ctx, config, opts...)
+}
+
+func (c *Client) ExportConfig(ctx context.Context, teams ...string) (oncall.Config, error) {
+	if c.ExportConfigFunc == nil {
+		return oncall.Config{}, nil
+	}
+	return c.ExportConfigFunc(ctx, teams...)
+}
+
+func (c *Client) CreateTeam(ctx context.Context, t oncall.Team, returnEarly bool) (*oncall.TeamReport, error) {
+	if c.CreateTeamFunc == nil {
+		return &oncall.TeamReport{}, nil
+	}
+	return c.CreateTeamFunc(ctx, t, returnEarly)
+}
+
+func (c *Client) UpdateTeam(ctx context.Context, name string, t oncall.Team) (*oncall.Response[any], error) {
+	if c.UpdateTeamFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.UpdateTeamFunc(ctx, name, t)
+}
+
+func (c *Client) DeleteTeam(ctx context.Context, team string) error {
+	if c.DeleteTeamFunc == nil {
+		return nil
+	}
+	return c.DeleteTeamFunc(ctx, team)
+}
+
+func (c *Client) GetTeams(ctx context.Context) (*oncall.Response[[]string], error) {
+	if c.GetTeamsFunc == nil {
+		return &oncall.Response[[]string]{}, nil
+	}
+	return c.GetTeamsFunc(ctx)
+}
+
+func (c *Client) ListTeams(ctx context.Context, filter oncall.TeamFilter) (*oncall.Response[[]string], error) {
+	if c.ListTeamsFunc == nil {
+		return &oncall.Response[[]string]{}, nil
+	}
+	return c.ListTeamsFunc(ctx, filter)
+}
+
+// TeamsIterator pages the teams returned by ListTeamsFunc
+func (c *Client) TeamsIterator(filter oncall.TeamFilter, pageSize int) *oncall.TeamsIterator {
+	return oncall.NewTeamsIterator(c, filter, pageSize)
+}
+
+func (c *Client) GetTeam(ctx context.Context, name string) (*oncall.Response[oncall.TeamRecord], error) {
+	if c.GetTeamFunc == nil {
+		return &oncall.Response[oncall.TeamRecord]{}, nil
+	}
+	return c.GetTeamFunc(ctx, name)
+}
+
+func (c *Client) GetSummary(ctx context.Context, team string) (*oncall.Response[oncall.Summary], error) {
+	if c.GetSummaryFunc == nil {
+		return &oncall.Response[oncall.Summary]{}, nil
+	}
+	return c.GetSummaryFunc(ctx, team)
+}
+
+func (c *Client) GetUsers(ctx context.Context, filter oncall.UserFilter) (*oncall.Response[[]oncall.UserRecord], error) {
+	if c.GetUsersFunc == nil {
+		return &oncall.Response[[]oncall.UserRecord]{}, nil
+	}
+	return c.GetUsersFunc(ctx, filter)
+}
+
+func (c *Client) GetUser(ctx context.Context, name string) (*oncall.Response[oncall.UserRecord], error) {
+	if c.GetUserFunc == nil {
+		return &oncall.Response[oncall.UserRecord]{}, nil
+	}
+	return c.GetUserFunc(ctx, name)
+}
+
+func (c *Client) GetOnCall(ctx context.Context, team string) (*oncall.Response[map[string][]string], error) {
+	if c.GetOnCallFunc == nil {
+		return &oncall.Response[map[string][]string]{}, nil
+	}
+	return c.GetOnCallFunc(ctx, team)
+}
+
+func (c *Client) CreateUser(ctx context.Context, u oncall.User) (*oncall.Response[any], error) {
+	if c.CreateUserFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.CreateUserFunc(ctx, u)
+}
+
+func (c *Client) UpdateUser(ctx context.Context, name string, u oncall.User) (*oncall.Response[any], error) {
+	if c.UpdateUserFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.UpdateUserFunc(ctx, name, u)
+}
+
+func (c *Client) DeleteUser(ctx context.Context, name string) error {
+	if c.DeleteUserFunc == nil {
+		return nil
+	}
+	return c.DeleteUserFunc(ctx, name)
+}
+
+func (c *Client) AddUserToTeam(ctx context.Context, username, teamname string) (*oncall.Response[any], error) {
+	if c.AddUserToTeamFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.AddUserToTeamFunc(ctx, username, teamname)
+}
+
+func (c *Client) DeleteUserFromTeam(ctx context.Context, user, team string) error {
+	if c.DeleteUserFromTeamFunc == nil {
+		return nil
+	}
+	return c.DeleteUserFromTeamFunc(ctx, user, team)
+}
+
+func (c *Client) CreateRoster(ctx context.Context, team, name string) (*oncall.Response[any], error) {
+	if c.CreateRosterFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.CreateRosterFunc(ctx, team, name)
+}
+
+func (c *Client) DeleteRoster(ctx context.Context, team, name string) error {
+	if c.DeleteRosterFunc == nil {
+		return nil
+	}
+	return c.DeleteRosterFunc(ctx, team, name)
+}
+
+func (c *Client) AddUserToRoster(ctx context.Context, team, roster, user string, inRotation bool) (*oncall.Response[any], error) {
+	if c.AddUserToRosterFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.AddUserToRosterFunc(ctx, team, roster, user, inRotation)
+}
+
+func (c *Client) RemoveUserFromRoster(ctx context.Context, team, roster, user string) error {
+	if c.RemoveUserFromRosterFunc == nil {
+		return nil
+	}
+	return c.RemoveUserFromRosterFunc(ctx, team, roster, user)
+}
+
+func (c *Client) SetRosterUserScheduling(ctx context.Context, team, roster, user string, inRotation bool) (*oncall.Response[any], error) {
+	if c.SetRosterUserSchedulingFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.SetRosterUserSchedulingFunc(ctx, team, roster, user, inRotation)
+}
+
+func (c *Client) CreateRosterSchedule(ctx context.Context, team, roster string, s oncall.RosterSchedule) (*oncall.Response[int64], error) {
+	if c.CreateRosterScheduleFunc == nil {
+		return &oncall.Response[int64]{}, nil
+	}
+	return c.CreateRosterScheduleFunc(ctx, team, roster, s)
+}
+
+func (c *Client) SetScheduler(ctx context.Context, scheduleID int64, name string, order []string) (*oncall.Response[any], error) {
+	if c.SetSchedulerFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.SetSchedulerFunc(ctx, scheduleID, name, order)
+}
+
+func (c *Client) PopulateSchedule(ctx context.Context, scheduleID int64, start time.Time) (*oncall.Response[any], error) {
+	if c.PopulateScheduleFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.PopulateScheduleFunc(ctx, scheduleID, start)
+}
+
+func (c *Client) GetRoles(ctx context.Context) (*oncall.Response[[]oncall.RoleRecord], error) {
+	if c.GetRolesFunc == nil {
+		return &oncall.Response[[]oncall.RoleRecord]{}, nil
+	}
+	return c.GetRolesFunc(ctx)
+}
+
+func (c *Client) Roles(ctx context.Context) ([]string, error) {
+	if c.RolesFunc == nil {
+		return nil, nil
+	}
+	return c.RolesFunc(ctx)
+}
+
+func (c *Client) CreateSchedule(ctx context.Context, username, teamname, tz string, schedule []oncall.Duty) error {
+	if c.CreateScheduleFunc == nil {
+		return nil
+	}
+	return c.CreateScheduleFunc(ctx, username, teamname, tz, schedule)
+}
+
+func (c *Client) GetEvents(ctx context.Context, filter oncall.EventFilter) (*oncall.Response[[]oncall.Event], error) {
+	if c.GetEventsFunc == nil {
+		return &oncall.Response[[]oncall.Event]{}, nil
+	}
+	return c.GetEventsFunc(ctx, filter)
+}
+
+func (c *Client) CreateLinkedEvents(ctx context.Context, events []dto.ScheduleDTO) (*oncall.Response[oncall.LinkedEvents], error) {
+	if c.CreateLinkedEventsFunc == nil {
+		return &oncall.Response[oncall.LinkedEvents]{}, nil
+	}
+	return c.CreateLinkedEventsFunc(ctx, events)
+}
+
+func (c *Client) UpdateEvent(ctx context.Context, id int64, data dto.ScheduleDTO) (*oncall.Response[any], error) {
+	if c.UpdateEventFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.UpdateEventFunc(ctx, id, data)
+}
+
+func (c *Client) DeleteEvent(ctx context.Context, id int64) error {
+	if c.DeleteEventFunc == nil {
+		return nil
+	}
+	return c.DeleteEventFunc(ctx, id)
+}
+
+func (c *Client) GetEvent(ctx context.Context, id int64) (*oncall.Response[oncall.Event], error) {
+	if c.GetEventFunc == nil {
+		return &oncall.Response[oncall.Event]{}, nil
+	}
+	return c.GetEventFunc(ctx, id)
+}
+
+func (c *Client) SwapEvents(ctx context.Context, eventsA, eventsB []int64) (*oncall.Response[any], error) {
+	if c.SwapEventsFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.SwapEventsFunc(ctx, eventsA, eventsB)
+}
+
+func (c *Client) OverrideEvents(ctx context.Context, r oncall.OverrideRequest) (*oncall.Response[any], error) {
+	if c.OverrideEventsFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.OverrideEventsFunc(ctx, r)
+}
+
+func (c *Client) ReactivateUser(ctx context.Context, name string) (*oncall.Response[any], error) {
+	if c.ReactivateUserFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.ReactivateUserFunc(ctx, name)
+}
+
+func (c *Client) GetServices(ctx context.Context, team string) (*oncall.Response[[]string], error) {
+	if c.GetServicesFunc == nil {
+		return &oncall.Response[[]string]{}, nil
+	}
+	return c.GetServicesFunc(ctx, team)
+}
+
+func (c *Client) AddService(ctx context.Context, team, service string) (*oncall.Response[any], error) {
+	if c.AddServiceFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.AddServiceFunc(ctx, team, service)
+}
+
+func (c *Client) DeleteService(ctx context.Context, team, service string) error {
+	if c.DeleteServiceFunc == nil {
+		return nil
+	}
+	return c.DeleteServiceFunc(ctx, team, service)
+}
+
+func (c *Client) GetAdmins(ctx context.Context, team string) (*oncall.Response[[]string], error) {
+	if c.GetAdminsFunc == nil {
+		return &oncall.Response[[]string]{}, nil
+	}
+	return c.GetAdminsFunc(ctx, team)
+}
+
+func (c *Client) AddAdmin(ctx context.Context, team, user string) (*oncall.Response[any], error) {
+	if c.AddAdminFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.AddAdminFunc(ctx, team, user)
+}
+
+func (c *Client) DeleteAdmin(ctx context.Context, team, user string) error {
+	if c.DeleteAdminFunc == nil {
+		return nil
+	}
+	return c.DeleteAdminFunc(ctx, team, user)
+}
+
+func (c *Client) GetNotifications(ctx context.Context, user string) (*oncall.Response[[]oncall.NotificationRecord], error) {
+	if c.GetNotificationsFunc == nil {
+		return &oncall.Response[[]oncall.NotificationRecord]{}, nil
+	}
+	return c.GetNotificationsFunc(ctx, user)
+}
+
+func (c *Client) CreateNotification(ctx context.Context, user, team string, n oncall.Notification) (*oncall.Response[any], error) {
+	if c.CreateNotificationFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.CreateNotificationFunc(ctx, user, team, n)
+}
+
+func (c *Client) UpdateNotification(ctx context.Context, id int64, team string, n oncall.Notification) (*oncall.Response[any], error) {
+	if c.UpdateNotificationFunc == nil {
+		return &oncall.Response[any]{}, nil
+	}
+	return c.UpdateNotificationFunc(ctx, id, team, n)
+}
+
+func (c *Client) DeleteNotification(ctx context.Context, user string, id int64) error {
+	if c.DeleteNotificationFunc == nil {
+		return nil
+	}
+	return c.DeleteNotificationFunc(ctx, user, id)
+}
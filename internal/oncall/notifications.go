@@ -0,0 +1,128 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+const (
+	notificationsEndpoint   = "/api/v0/notifications/"
+	defaultNotificationType = "oncall_reminder"
+)
+
+// notificationDTO converts a configured notification of a user in team into the payload of the notifications endpoints
+func notificationDTO(team string, n Notification) dto.NotificationDTO {
+	data := dto.NotificationDTO{
+		Team:           team,
+		Roles:          n.Roles,
+		Mode:           n.Mode,
+		Type:           n.Type,
+		TimeBefore:     int64(n.TimeBefore.Seconds()),
+		OnlyIfInvolved: n.OnlyIfInvolved,
+	}
+	if data.Type == "" {
+		data.Type = defaultNotificationType
+	}
+	if data.Roles == nil {
+		data.Roles = []string{}
+	}
+	return data
+}
+
+// GetNotifications lists the notification rules of a user
+func (c *Client) GetNotifications(ctx context.Context, user string) (*Response[[]NotificationRecord], error) {
+	logger := c.logger.With().Str("action", "get_notifications").Str("user", user).Logger()
+	endpoint, err := c.endpoint(usersEndpoint, user, "notifications")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[[]NotificationRecord](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// CreateNotification adds a notification rule to a user for the shifts of a team
+func (c *Client) CreateNotification(ctx context.Context, user, team string, n Notification) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "create_notification").Str("user", user).Str("team", team).Logger()
+	endpoint, err := c.endpoint(usersEndpoint, user, "notifications")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, notificationDTO(team, n))
+}
+
+// UpdateNotification replaces the notification rule id
+func (c *Client) UpdateNotification(ctx context.Context, id int64, team string, n Notification) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "update_notification").Int64("notification", id).Logger()
+	endpoint, err := c.endpoint(notificationsEndpoint, strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, notificationDTO(team, n))
+}
+
+// DeleteNotification deletes the notification rule id of user. With delete protection, the rule
+// must be one of user and both user and the team of the rule must be allowed.
+func (c *Client) DeleteNotification(ctx context.Context, user string, id int64) error {
+	logger := c.logger.With().Str("action", "delete_notification").Str("user", user).Int64("notification", id).Logger()
+	if c.guard.enabled {
+		existing, err := c.GetNotifications(ctx, user)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(existing.Data, func(r NotificationRecord) bool { return r.ID == id })
+		if i < 0 {
+			return fmt.Errorf("%w: notification %d of user %q", ErrNotFound, id, user)
+		}
+		if err = errors.Join(c.guard.check("user", user), c.guard.check("team", existing.Data[i].Team)); err != nil {
+			logger.Warn().Err(err).Send()
+			return err
+		}
+	}
+	endpoint, err := c.endpoint(notificationsEndpoint, strconv.FormatInt(id, 10))
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// createNotifications creates the configured notifications of a user in team that do not exist yet
+func (c *Client) createNotifications(ctx context.Context, user, team string, notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	existing, err := c.GetNotifications(ctx, user)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var errs MultiError
+	for _, n := range notifications {
+		if existing != nil && hasNotification(existing.Data, team, n) {
+			continue
+		}
+		want := notificationDTO(team, n)
+		_, err := c.CreateNotification(ctx, user, team, n)
+		errs.addDetail("create", "notification", user, team, want.Mode+" "+want.Type, err)
+	}
+	return errs.Err()
+}
+
+// hasNotification reports whether records hold the notification n of team
+func hasNotification(records []NotificationRecord, team string, n Notification) bool {
+	want := notificationDTO(team, n)
+	return slices.ContainsFunc(records, func(r NotificationRecord) bool {
+		return r.Team == want.Team && r.Mode == want.Mode && r.Type == want.Type &&
+			r.TimeBefore == want.TimeBefore && slices.Equal(sorted(r.Roles), sorted(want.Roles))
+	})
+}
+
+func sorted(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
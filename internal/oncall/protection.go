@@ -0,0 +1,104 @@
+package oncall
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrProtected is matched by *ProtectedError
+var ErrProtected = errors.New("protected entity")
+
+// defaultDeleteAllow is the allowlist used by WithDeleteProtection when no pattern is given
+var defaultDeleteAllow = regexp.MustCompile(`^probe`)
+
+// ProtectedError is returned by destructive methods called on a name the delete guard does not allow
+type ProtectedError struct {
+	Kind string
+	Name string
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("refusing to delete %s %q: not allowed by delete protection", e.Kind, e.Name)
+}
+
+func (e *ProtectedError) Is(target error) bool {
+	return target == ErrProtected
+}
+
+// deleteGuard restricts the team and user names destructive methods may touch
+type deleteGuard struct {
+	enabled bool
+	allow   []*regexp.Regexp
+	deny    []*regexp.Regexp
+}
+
+// WithDeleteProtection only lets destructive methods touch teams and users matching one of allow.
+// Without patterns, only names starting with "probe" are allowed.
+func WithDeleteProtection(allow ...*regexp.Regexp) Option {
+	return func(c *Client) {
+		c.guard.enabled = true
+		if len(allow) == 0 {
+			allow = []*regexp.Regexp{defaultDeleteAllow}
+		}
+		c.guard.allow = allow
+	}
+}
+
+// WithDeleteDenylist forbids destructive methods on teams and users matching one of deny,
+// even if they are allowed by WithDeleteProtection
+func WithDeleteDenylist(deny ...*regexp.Regexp) Option {
+	return func(c *Client) {
+		c.guard.enabled = true
+		c.guard.deny = append(c.guard.deny, deny...)
+	}
+}
+
+// check returns a *ProtectedError if name of the given kind may not be deleted
+func (g deleteGuard) check(kind, name string) error {
+	if !g.enabled {
+		return nil
+	}
+	for _, re := range g.deny {
+		if re.MatchString(name) {
+			return &ProtectedError{Kind: kind, Name: name}
+		}
+	}
+	if len(g.allow) == 0 {
+		return nil
+	}
+	for _, re := range g.allow {
+		if re.MatchString(name) {
+			return nil
+		}
+	}
+	return &ProtectedError{Kind: kind, Name: name}
+}
+
+// checkPath checks the teams and users named by an API path, e.g. /api/v0/teams/a/users/b.
+// Paths that name neither are refused, the guard cannot tell what they delete.
+func (g deleteGuard) checkPath(path string) error {
+	if !g.enabled {
+		return nil
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var errs []error
+	for i := 0; i+1 < len(segments); i++ {
+		kind := strings.TrimSuffix(segments[i], "s")
+		if kind != "team" && kind != "user" {
+			continue
+		}
+		name, err := url.PathUnescape(segments[i+1])
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+		errs = append(errs, g.check(kind, name))
+		i++
+	}
+	if errs == nil {
+		return &ProtectedError{Kind: "path", Name: path}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package oncall
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Raw sends a request to an arbitrary path of the oncall server with the session of the client
+// and returns the response body. path may carry a query string. Unlike the other methods,
+// non-2xx responses are not errors, callers inspect StatusCode themselves. With delete
+// protection, a DELETE must target a team or user the guard allows.
+func (c *Client) Raw(ctx context.Context, method, path string, body []byte) (*Response[[]byte], error) {
+	logger := c.logger.With().Str("action", "raw").Str("method", method).Str("path", path).Logger()
+	path, query, _ := strings.Cut(path, "?")
+	if method == http.MethodDelete {
+		if err := c.guard.checkPath(path); err != nil {
+			logger.Warn().Err(err).Send()
+			return nil, err
+		}
+	}
+	endpoint, err := c.endpoint(path)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	if query != "" {
+		endpoint += "?" + query
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		logger.Error().Caller().Err(err).Send()
+		return nil, ErrInvalidRequest
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	result := Response[[]byte]{
+		URLPath:     req.URL.Path,
+		RequestBody: body,
+	}
+	startTime := time.Now()
+
+	res, err := c.do(req)
+	if err != nil {
+		logger.Error().Caller().Err(err).Send()
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// record metrics
+	result.capture(res)
+	result.Data, err = io.ReadAll(res.Body)
+	result.ResponseTime = time.Since(startTime)
+	logger.Debug().Int("status_code", res.StatusCode).Send()
+	if err != nil {
+		return &result, err
+	}
+	return &result, nil
+}
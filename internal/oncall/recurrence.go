@@ -0,0 +1,197 @@
+package oncall
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxOccurrences bounds the number of days a recurring duty expands to
+const maxOccurrences = 3660
+
+const dutyDay = 24 * time.Hour
+
+// Interval is a whole number of days, written in yaml as 7d, 2w or a Go duration that is a
+// multiple of 24h
+type Interval int
+
+func (i *Interval) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	days, err := parseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = Interval(days)
+	return nil
+}
+
+func (i Interval) MarshalYAML() (any, error) {
+	return strconv.Itoa(int(i)) + "d", nil
+}
+
+func parseInterval(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if v, err := strconv.Atoi(n); err == nil {
+				return v * days, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d%dutyDay != 0 {
+		return 0, fmt.Errorf("interval %q must be a number of days, e.g. 7d or 2w", s)
+	}
+	return int(d / dutyDay), nil
+}
+
+// Repeat repeats a duty every Interval, Count times or until Duty.Until
+type Repeat struct {
+	Interval Interval `yaml:"interval"`
+	Count    int      `yaml:"count,omitempty"`
+}
+
+// WeeklyRotation holds a duty for the 7 days starting at Duty.Date, then again every Every
+// weeks, e.g. every 3 weeks for a rotation of 3 users. It recurs Count times or until Duty.Until.
+type WeeklyRotation struct {
+	Every int `yaml:"every,omitempty"`
+	Count int `yaml:"count,omitempty"`
+}
+
+// recurs reports whether d has a recurrence rule
+func (d Duty) recurs() bool {
+	return d.Every != "" || d.Repeat != nil || d.WeeklyRotation != nil
+}
+
+// Occurrences returns the duty of each day d recurs on, without recurrence rule, in date order. A duty without recurrence rule is its own single occurrence.
+func (d Duty) Occurrences() ([]Duty, error) {
+	if !d.recurs() {
+		if d.Until != "" {
+			return nil, errors.New("until without every, repeat or weekly_rotation")
+		}
+		return []Duty{d}, nil
+	}
+	rules := 0
+	for _, set := range []bool{d.Every != "", d.Repeat != nil, d.WeeklyRotation != nil} {
+		if set {
+			rules++
+		}
+	}
+	if rules > 1 {
+		return nil, errors.New("only one of every, repeat and weekly_rotation can be set")
+	}
+	start, err := time.Parse(DutyDateLayout, d.Date)
+	if err != nil {
+		return nil, fmt.Errorf("date %q is not formatted as dd/mm/yyyy", d.Date)
+	}
+	var until time.Time
+	if d.Until != "" {
+		if until, err = time.Parse(DutyDateLayout, d.Until); err != nil {
+			return nil, fmt.Errorf("until %q is not formatted as dd/mm/yyyy", d.Until)
+		}
+		if until.Before(start) {
+			return nil, fmt.Errorf("until %s is before the date %s", d.Until, d.Date)
+		}
+	}
+
+	var days []time.Time
+	add := func(t time.Time) bool {
+		if !until.IsZero() && t.After(until) {
+			return false
+		}
+		days = append(days, t)
+		return true
+	}
+	switch {
+	case d.Every != "":
+		if until.IsZero() {
+			return nil, errors.New("every needs until")
+		}
+		weekdays, err := parseWeekdays(d.Every)
+		if err != nil {
+			return nil, err
+		}
+		for t := start; !t.After(until) && len(days) <= maxOccurrences; t = t.Add(dutyDay) {
+			if weekdays[t.Weekday()] {
+				days = append(days, t)
+			}
+		}
+	case d.Repeat != nil:
+		if d.Repeat.Interval <= 0 {
+			return nil, errors.New("repeat needs a positive interval")
+		}
+		if d.Repeat.Count <= 0 && until.IsZero() {
+			return nil, errors.New("repeat needs a count or until")
+		}
+		for i := 0; (d.Repeat.Count <= 0 || i < d.Repeat.Count) && len(days) <= maxOccurrences; i++ {
+			if !add(start.Add(time.Duration(i*int(d.Repeat.Interval)) * dutyDay)) {
+				break
+			}
+		}
+	case d.WeeklyRotation != nil:
+		r := *d.WeeklyRotation
+		if r.Every <= 0 {
+			r.Every = 1
+		}
+		if r.Count <= 0 && until.IsZero() {
+			return nil, errors.New("weekly_rotation needs a count or until")
+		}
+	weeks:
+		for i := 0; (r.Count <= 0 || i < r.Count) && len(days) <= maxOccurrences; i++ {
+			first := start.Add(time.Duration(i*r.Every*7) * dutyDay)
+			for j := 0; j < 7; j++ {
+				if !add(first.Add(time.Duration(j) * dutyDay)) {
+					break weeks
+				}
+			}
+		}
+	}
+	if len(days) > maxOccurrences {
+		return nil, fmt.Errorf("recurs on more than %d days", maxOccurrences)
+	}
+	res := make([]Duty, len(days))
+	for i, t := range days {
+		res[i] = Duty{
+			Date:      t.Format(DutyDateLayout),
+			Role:      d.Role,
+			Roles:     d.Roles,
+			StartTime: d.StartTime,
+			EndTime:   d.EndTime,
+			Duration:  d.Duration,
+			Timezone:  d.Timezone,
+		}
+	}
+	return res, nil
+}
+
+// parseWeekdays parses a comma separated list of weekdays, or day for every day
+func parseWeekdays(s string) (map[time.Weekday]bool, error) {
+	weekdays := make(map[time.Weekday]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "day" {
+			for wd := time.Sunday; wd <= time.Saturday; wd++ {
+				weekdays[wd] = true
+			}
+			continue
+		}
+		found := false
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			full := strings.ToLower(wd.String())
+			if name == full || name == full[:3] {
+				weekdays[wd], found = true, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("every %q is not a weekday", name)
+		}
+	}
+	return weekdays, nil
+}
@@ -0,0 +1,197 @@
+package oncall
+
+import (
+	"errors"
+	"time"
+)
+
+// Outcome is the result of a step of CreateEntities or DeleteEntities
+type Outcome string
+
+const (
+	// OutcomeCreated means the entity was created, or updated for users
+	OutcomeCreated Outcome = "created"
+	// OutcomeSkipped means the entity already existed, or was already gone or kept when deleting,
+	// or there was nothing to do
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeUpdated means WithUpsert changed the fields of an existing entity
+	OutcomeUpdated Outcome = "updated"
+	// OutcomeUnchanged means WithUpsert found an existing entity matching the config
+	OutcomeUnchanged Outcome = "unchanged"
+	// OutcomeDeleted means DeleteEntities deleted the entity
+	OutcomeDeleted Outcome = "deleted"
+	// OutcomeFailed means the step failed, see StepReport.Err
+	OutcomeFailed Outcome = "failed"
+)
+
+// StepReport is the outcome of a single step of CreateEntities
+type StepReport struct {
+	Outcome    Outcome       `json:"outcome"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency,omitempty"`
+	Err        error         `json:"-"`
+	// Response is the response of the step, nil if no request was answered
+	Response *Response[any] `json:"-"`
+}
+
+// newStep reports a request answered with res and err. Conflicts are skipped steps.
+func newStep[T any](res *Response[T], err error) StepReport {
+	step := StepReport{Outcome: OutcomeCreated, Err: err}
+	switch {
+	case errors.Is(err, ErrConflict):
+		step.Outcome = OutcomeSkipped
+	case err != nil:
+		step.Outcome = OutcomeFailed
+	}
+	if res != nil {
+		step.StatusCode = res.StatusCode
+		step.Latency = res.ResponseTime
+		step.Response = withData[T, any](res, res.Data)
+	}
+	return step
+}
+
+// batchStep reports a step grouping several requests, failed if err is not nil
+func batchStep(err error) StepReport {
+	if err != nil {
+		return StepReport{Outcome: OutcomeFailed, Err: err}
+	}
+	return StepReport{Outcome: OutcomeCreated}
+}
+
+// Succeeded reports whether the step created the entity, or updated or left it unchanged with
+// WithUpsert
+func (s StepReport) Succeeded() bool {
+	switch s.Outcome {
+	case OutcomeCreated, OutcomeUpdated, OutcomeUnchanged:
+		return true
+	}
+	return false
+}
+
+// EntityReport is the outcome of CreateEntities, with the teams in the order of the config
+type EntityReport struct {
+	Teams []*TeamReport `json:"teams"`
+}
+
+// Team returns the report of the team name
+func (r *EntityReport) Team(name string) (*TeamReport, bool) {
+	for _, t := range r.Teams {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Failures lists the failed steps of every team as entity errors, as returned by CreateEntities
+func (r *EntityReport) Failures() []*EntityError {
+	var errs MultiError
+	for _, t := range r.Teams {
+		t.failures(&errs)
+	}
+	return errs.Errors
+}
+
+// Succeeded reports whether any team or user was created, updated or left unchanged
+func (r *EntityReport) Succeeded() bool {
+	for _, t := range r.Teams {
+		if t.Create.Succeeded() {
+			return true
+		}
+		for _, u := range t.Users {
+			if u.Create.Succeeded() || u.AddToTeam.Succeeded() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// err returns the failures as a *MultiError, nil if there are none
+func (r *EntityReport) err() error {
+	if f := r.Failures(); len(f) > 0 {
+		return &MultiError{Errors: f}
+	}
+	return nil
+}
+
+// TeamReport is the outcome of the creation of a team and its members
+type TeamReport struct {
+	Name   string     `json:"name"`
+	Create StepReport `json:"create"`
+	// Users are in the order of the team config
+	Users    []*UserReport `json:"users"`
+	Rosters  StepReport    `json:"rosters"`
+	Services StepReport    `json:"services"`
+	Admins   StepReport    `json:"admins"`
+}
+
+// User returns the report of the member name
+func (t *TeamReport) User(name string) (*UserReport, bool) {
+	for _, u := range t.Users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// Failures lists the failed steps of the team as entity errors
+func (t *TeamReport) Failures() []*EntityError {
+	var errs MultiError
+	t.failures(&errs)
+	return errs.Errors
+}
+
+func (t *TeamReport) err() error {
+	if f := t.Failures(); len(f) > 0 {
+		return &MultiError{Errors: f}
+	}
+	return nil
+}
+
+func (t *TeamReport) failures(errs *MultiError) {
+	if t.Create.Outcome == OutcomeFailed {
+		errs.Add("create", "team", t.Name, t.Name, t.Create.Err)
+	}
+	for _, u := range t.Users {
+		if u.Create.Outcome == OutcomeFailed {
+			errs.Add("create", "user", u.Name, t.Name, u.Create.Err)
+		}
+		if u.AddToTeam.Outcome == OutcomeFailed {
+			errs.Add("add_to_team", "user", u.Name, t.Name, u.AddToTeam.Err)
+		}
+		if u.Notifications.Outcome == OutcomeFailed {
+			errs.Add("create", "notification", u.Name, t.Name, u.Notifications.Err)
+		}
+		for _, d := range u.Duties {
+			if d.Outcome == OutcomeFailed {
+				errs.addDetail("create", "event", u.Name, t.Name, d.Role+" "+d.Date, d.Err)
+			}
+		}
+	}
+	for _, step := range []StepReport{t.Rosters, t.Services, t.Admins} {
+		if step.Outcome == OutcomeFailed {
+			// these steps hold the *MultiError of their requests, merged as is
+			errs.Add("create", "team", t.Name, t.Name, step.Err)
+		}
+	}
+}
+
+// UserReport is the outcome of the creation of a member of a team
+type UserReport struct {
+	Name          string       `json:"name"`
+	Create        StepReport   `json:"create"`
+	AddToTeam     StepReport   `json:"add_to_team"`
+	Notifications StepReport   `json:"notifications"`
+	Duties        []DutyReport `json:"duties"`
+}
+
+// DutyReport is the outcome of a duty of a user for a role. The duties of a linked run share
+// the step of the request creating them.
+type DutyReport struct {
+	Role string `json:"role"`
+	Date string `json:"date"`
+	StepReport
+}
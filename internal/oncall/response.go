@@ -0,0 +1,109 @@
+package oncall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxRawBody is the maximum number of bytes of a response kept in Response.RawBody
+const maxRawBody = 64 << 10
+
+// capture records the status, headers, TLS state, final URL and the start of the body of res,
+// and the method and headers of its request. The body of res can still be read in full afterwards.
+func (r *Response[T]) capture(res *http.Response) {
+	r.StatusCode = res.StatusCode
+	r.Header = res.Header
+	r.TLS = res.TLS
+	if res.Request != nil {
+		r.URL = res.Request.URL.String()
+		r.Method = res.Request.Method
+		r.RequestHeader = res.Request.Header
+	}
+	r.RawBody, _ = io.ReadAll(io.LimitReader(res.Body, maxRawBody))
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(r.RawBody), res.Body), res.Body}
+}
+
+// doJSON sends payload, if any, as json with method to endpoint and records the response.
+// The body of a 2xx response is decoded into Response.Data unless T is any. The Response is
+// only nil when no response was received, non-2xx statuses are returned as *APIError with it.
+func doJSON[T any](ctx context.Context, c *Client, logger zerolog.Logger, method, endpoint string, payload any) (*Response[T], error) {
+
+	var (
+		body io.Reader
+		sent []byte
+	)
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			logger.Error().Caller().Err(err).Send()
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+		body, sent = bytes.NewReader(b), b
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		logger.Error().Caller().Err(err).Send()
+		return nil, ErrInvalidRequest
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	result := Response[T]{
+		URLPath:     req.URL.Path,
+		RequestBody: sent,
+	}
+	startTime := time.Now()
+
+	// perform request
+	res, err := c.do(req)
+	if err != nil {
+		logger.Error().Caller().Err(err).Send()
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// record metrics
+	result.ResponseTime = time.Since(startTime)
+	result.capture(res)
+	logger.Debug().Int("status_code", res.StatusCode).Send()
+	if err = checkResponse(res); err != nil {
+		logger.Warn().Err(err).Send()
+		return &result, err
+	}
+
+	if _, discard := any(&result.Data).(*any); discard {
+		return &result, nil
+	}
+	if err = json.NewDecoder(res.Body).Decode(&result.Data); err != nil {
+		return &result, fmt.Errorf("decoding %s: %w", result.URLPath, err)
+	}
+	return &result, nil
+}
+
+// withData returns the response r carrying data instead of its own
+func withData[T, U any](r *Response[T], data U) *Response[U] {
+	return &Response[U]{
+		Data:          data,
+		URLPath:       r.URLPath,
+		ResponseTime:  r.ResponseTime,
+		StatusCode:    r.StatusCode,
+		URL:           r.URL,
+		Header:        r.Header,
+		RawBody:       r.RawBody,
+		Method:        r.Method,
+		RequestHeader: r.RequestHeader,
+		RequestBody:   r.RequestBody,
+		TLS:           r.TLS,
+	}
+}
@@ -0,0 +1,193 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// retryPolicy describes how transient failures are retried by *Client
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	retryPosts  bool
+}
+
+// WithRetry retries idempotent requests (GET, PUT, DELETE) up to maxAttempts times
+// on 5xx responses, timeouts and connection resets, waiting a jittered exponential
+// backoff starting at baseDelay between attempts. Requests of any method answered with 429
+// are retried too, after the Retry-After delay when oncall sends one.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry.maxAttempts = maxAttempts
+		c.retry.baseDelay = baseDelay
+	}
+}
+
+// WithPostRetry also retries the POST requests creating entities oncall keeps unique: teams,
+// users, rosters and the users, admins and services of a team or roster. oncall answers a
+// duplicate of these with 422, so a retried create whose first attempt reached the server is
+// reported as already existing instead of creating the entity twice. Other POST requests,
+// such as the creation of events, linked events and notifications or swaps and overrides,
+// would create a second copy and are never retried.
+func WithPostRetry() Option {
+	return func(c *Client) {
+		c.retry.retryPosts = true
+	}
+}
+
+func (p retryPolicy) allows(req *http.Request) bool {
+	if p.maxAttempts <= 1 {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return p.retryPosts && uniqueCreate(req.URL.EscapedPath())
+	}
+	return false
+}
+
+// uniqueCreate reports whether a POST to path creates an entity oncall rejects duplicates of
+func uniqueCreate(path string) bool {
+	_, rest, found := strings.Cut(path, "/api/v0/")
+	if !found {
+		return false
+	}
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	switch len(segments) {
+	case 1:
+		// /teams and /users
+		return segments[0] == "teams" || segments[0] == "users"
+	case 3:
+		// /teams/{team}/users, admins, services and rosters
+		return segments[0] == "teams" && slices.Contains([]string{"users", "admins", "services", "rosters"}, segments[2])
+	case 5:
+		// /teams/{team}/rosters/{roster}/users
+		return segments[0] == "teams" && segments[2] == "rosters" && segments[4] == "users"
+	}
+	return false
+}
+
+// backoff returns the delay before the given attempt (starting from 1 for the first retry)
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	// equal jitter: half fixed, half random
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// doRetry sends req through the http client, retrying transient failures according to the
+// retry policy. Each attempt is bounded by the request timeout, see withTimeout, the context
+// of req bounds the attempts and their backoff together.
+func (c *Client) doRetry(req *http.Request) (*http.Response, error) {
+	c.setCSRF(req)
+	if c.retry.maxAttempts <= 1 {
+		return c.sendAttempt(req)
+	}
+	idempotent := c.retry.allows(req)
+	ctx := req.Context()
+	var (
+		res *http.Response
+		err error
+	)
+	for attempt := 0; attempt < c.retry.maxAttempts; attempt++ {
+		r := req
+		if attempt > 0 {
+			delay := c.retry.backoff(attempt)
+			c.logger.Debug().
+				Str("method", req.Method).
+				Str("url", req.URL.String()).
+				Int("attempt", attempt+1).
+				Dur("delay", delay).
+				Msg("retrying request")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				if r.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		res, err = c.sendAttempt(r)
+		// oncall did not process a rate limited request, retrying it is always safe
+		limited := err == nil && res.StatusCode == http.StatusTooManyRequests
+		if attempt == c.retry.maxAttempts-1 || !(limited || idempotent && isTransient(res, err)) {
+			return res, err
+		}
+		if res != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+	}
+	return res, err
+}
+
+// sendAttempt sends an attempt of req within the request timeout. The timeout keeps running
+// until the body of the response is closed.
+func (c *Client) sendAttempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := c.withTimeout(req.Context())
+	res, err := c.sendHedged(req.WithContext(ctx))
+	if err != nil || res == nil {
+		cancel()
+		return res, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// send performs a single attempt of req once the Retry-After delay of the last 429 elapsed and
+// the rate limiter allows it, signing it first when the client authenticates as an application
+// and running the hooks
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if err := c.throttle.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	if c.limiter != nil {
+		if err := c.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.appAuth != nil {
+		if err := c.appAuth.sign(req); err != nil {
+			return nil, err
+		}
+	}
+	c.calls.inc(req.Method)
+	countAttempt(req)
+	res, err := c.hooks.roundTrip(c.httpClient, req)
+	c.throttle.observe(req, res)
+	return res, err
+}
+
+// isTransient reports whether a request outcome is worth retrying
+func isTransient(res *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, io.EOF)
+	}
+	return res.StatusCode >= http.StatusInternalServerError
+}
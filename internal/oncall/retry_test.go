@@ -0,0 +1,37 @@
+package oncall
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryAllowsPosts(t *testing.T) {
+	p := retryPolicy{maxAttempts: 3, retryPosts: true}
+	for path, want := range map[string]bool{
+		"/api/v0/teams/":                                true,
+		"/api/v0/users":                                 true,
+		"/oncall/api/v0/teams/infra/users":              true,
+		"/api/v0/teams/infra/admins":                    true,
+		"/api/v0/teams/infra/services":                  true,
+		"/api/v0/teams/infra/rosters/":                  true,
+		"/api/v0/teams/a%2Fb/rosters/primary/users/":    true,
+		"/api/v0/events/":                               false,
+		"/api/v0/events/link":                           false,
+		"/api/v0/events/swap":                           false,
+		"/api/v0/events/override":                       false,
+		"/api/v0/users/alice/notifications":             false,
+		"/api/v0/teams/infra/rosters/primary/schedules": false,
+		"/api/v0/schedules/1/populate":                  false,
+		"/login":                                        false,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://oncall"+path, nil)
+		if got := p.allows(req); got != want {
+			t.Errorf("allows(POST %s) = %v, want %v", path, got, want)
+		}
+	}
+	req := httptest.NewRequest(http.MethodPost, "http://oncall/api/v0/teams/", nil)
+	if (retryPolicy{maxAttempts: 3}).allows(req) {
+		t.Error("POST retried without WithPostRetry")
+	}
+}
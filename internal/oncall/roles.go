@@ -0,0 +1,66 @@
+package oncall
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+const rolesEndpoint = "/api/v0/roles/"
+
+// RoleRecord is a role known to oncall
+type RoleRecord struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	DisplayOrder int    `json:"display_order"`
+}
+
+// roleCache holds the role names of the server once fetched by Roles
+type roleCache struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// GetRoles returns the roles known to oncall
+func (c *Client) GetRoles(ctx context.Context) (*Response[[]RoleRecord], error) {
+	logger := c.logger.With().Str("action", "get_roles").Logger()
+	endpoint, err := c.endpoint(rolesEndpoint)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[[]RoleRecord](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// Roles returns the names of the roles known to oncall in their display order. They are
+// fetched once per client, failed fetches are tried again by the next call.
+func (c *Client) Roles(ctx context.Context) ([]string, error) {
+	c.roles.mu.Lock()
+	defer c.roles.mu.Unlock()
+	if c.roles.names != nil {
+		return c.roles.names, nil
+	}
+	res, err := c.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records := slices.Clone(res.Data)
+	slices.SortStableFunc(records, func(a, b RoleRecord) int { return cmp.Compare(a.DisplayOrder, b.DisplayOrder) })
+	names := make([]string, len(records))
+	for i, r := range records {
+		names[i] = r.Name
+	}
+	c.roles.names = names
+	return names, nil
+}
+
+// knownRoles returns the roles of the server for validation, nil if they cannot be fetched
+func (c *Client) knownRoles(ctx context.Context) []string {
+	roles, err := c.Roles(ctx)
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("error fetching roles, roles are not validated")
+		return nil
+	}
+	return roles
+}
@@ -0,0 +1,120 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// CreateRoster creates an empty roster in a team
+func (c *Client) CreateRoster(ctx context.Context, team, name string) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "create_roster").Str("team", team).Str("roster", name).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.RosterCreateDTO{Name: name})
+}
+
+// DeleteRoster deletes a roster of a team together with its schedules
+func (c *Client) DeleteRoster(ctx context.Context, team, name string) error {
+	logger := c.logger.With().Str("action", "delete_roster").Str("team", team).Str("roster", name).Logger()
+	if err := c.guard.check("team", team); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
+	}
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters", name)
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// AddUserToRoster adds a member of the team to a roster. Users out of rotation
+// are part of the roster but are skipped by its schedulers.
+func (c *Client) AddUserToRoster(ctx context.Context, team, roster, user string, inRotation bool) (*Response[any], error) {
+	logger := c.logger.With().
+		Str("action", "add_user_to_roster").
+		Str("team", team).
+		Str("roster", roster).
+		Str("user", user).
+		Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters", roster, "users")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.RosterUserDTO{Name: user, InRotation: inRotation})
+}
+
+// RemoveUserFromRoster removes a user from a roster, the user stays in the team
+func (c *Client) RemoveUserFromRoster(ctx context.Context, team, roster, user string) error {
+	logger := c.logger.With().
+		Str("action", "remove_user_from_roster").
+		Str("team", team).
+		Str("roster", roster).
+		Str("user", user).
+		Logger()
+	if err := errors.Join(c.guard.check("team", team), c.guard.check("user", user)); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
+	}
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters", roster, "users", user)
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// SetRosterUserScheduling puts a roster member in or out of rotation
+func (c *Client) SetRosterUserScheduling(ctx context.Context, team, roster, user string, inRotation bool) (*Response[any], error) {
+	logger := c.logger.With().
+		Str("action", "set_roster_user_scheduling").
+		Str("team", team).
+		Str("roster", roster).
+		Str("user", user).
+		Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters", roster, "users", user)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, dto.RosterUserDTO{InRotation: inRotation})
+}
+
+// createRosters creates the configured rosters of a team, their members and schedules.
+// Existing rosters and members are kept, only the rotation of members is updated.
+func (c *Client) createRosters(ctx context.Context, team string, rosters []Roster) error {
+	var errs MultiError
+	// existing rosters of the team, to find the schedules already created
+	var existing map[string]RosterRecord
+	if slices.ContainsFunc(rosters, func(r Roster) bool { return len(r.Schedules) > 0 }) {
+		res, err := c.GetTeam(ctx, team)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			errs.Add("get", "team", team, team, err)
+			return errs.Err()
+		}
+		if res != nil {
+			existing = res.Data.Rosters
+		}
+	}
+	for _, r := range rosters {
+		if _, err := c.CreateRoster(ctx, team, r.Name); err != nil && !errors.Is(err, ErrConflict) {
+			errs.Add("create", "roster", r.Name, team, err)
+			continue
+		}
+		for _, m := range r.Users {
+			inRotation := m.InRotation == nil || *m.InRotation
+			_, err := c.AddUserToRoster(ctx, team, r.Name, m.Name, inRotation)
+			if errors.Is(err, ErrConflict) {
+				_, err = c.SetRosterUserScheduling(ctx, team, r.Name, m.Name, inRotation)
+			}
+			errs.addDetail("add_to_roster", "user", m.Name, team, "roster "+r.Name, err)
+		}
+		errs.Add("create", "schedule", r.Name, team, c.createSchedules(ctx, team, r, existing[r.Name]))
+	}
+	return errs.Err()
+}
@@ -0,0 +1,117 @@
+package oncall
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+const defaultScheduler = "default"
+
+// scheduleDTO converts a configured roster schedule into the payload of the schedules endpoint
+func scheduleDTO(s RosterSchedule) dto.ScheduleCreateDTO {
+	data := dto.ScheduleCreateDTO{
+		Role:                  s.Role,
+		AutoPopulateThreshold: s.AutoPopulateThreshold,
+		Events:                make([]dto.ScheduleEventDTO, 0, len(s.Events)),
+		Scheduler:             schedulerDTO(s.Scheduler, s.Order),
+	}
+	if s.AdvancedMode {
+		data.AdvancedMode = 1
+	}
+	for _, e := range s.Events {
+		data.Events = append(data.Events, dto.ScheduleEventDTO{Start: e.Start, Duration: e.Duration})
+	}
+	return data
+}
+
+func schedulerDTO(name string, order []string) dto.SchedulerDTO {
+	if name == "" {
+		name = defaultScheduler
+	}
+	if order == nil {
+		order = []string{}
+	}
+	return dto.SchedulerDTO{Name: name, Data: order}
+}
+
+// CreateRosterSchedule attaches a schedule with its scheduler to a roster and returns the id of the schedule
+func (c *Client) CreateRosterSchedule(ctx context.Context, team, roster string, s RosterSchedule) (*Response[int64], error) {
+	logger := c.logger.With().
+		Str("action", "create_roster_schedule").
+		Str("team", team).
+		Str("roster", roster).
+		Str("role", s.Role).
+		Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "rosters", roster, "schedules")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	created, err := doJSON[struct {
+		ID int64 `json:"id"`
+	}](ctx, c, logger, http.MethodPost, endpoint, scheduleDTO(s))
+	if created == nil {
+		return nil, err
+	}
+	return withData(created, created.Data.ID), err
+}
+
+// SetScheduler changes the scheduler of a schedule. order is the user rotation of "round-robin".
+func (c *Client) SetScheduler(ctx context.Context, scheduleID int64, name string, order []string) (*Response[any], error) {
+	logger := c.logger.With().
+		Str("action", "set_scheduler").
+		Int64("schedule", scheduleID).
+		Str("scheduler", name).
+		Logger()
+	endpoint, err := c.endpoint(schedulesEndpoint, strconv.FormatInt(scheduleID, 10))
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, dto.SchedulerUpdateDTO{Scheduler: schedulerDTO(name, order)})
+}
+
+// PopulateSchedule asks oncall to create the events of a schedule from start on,
+// up to the auto populate threshold of the schedule
+func (c *Client) PopulateSchedule(ctx context.Context, scheduleID int64, start time.Time) (*Response[any], error) {
+	logger := c.logger.With().
+		Str("action", "populate_schedule").
+		Int64("schedule", scheduleID).
+		Time("start", start).
+		Logger()
+	endpoint, err := c.endpoint(schedulesEndpoint, strconv.FormatInt(scheduleID, 10), "populate")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.PopulateDTO{Start: start.Unix()})
+}
+
+// createSchedules creates the configured schedules of a roster. A schedule whose role already
+// has one in the roster only gets its scheduler updated.
+func (c *Client) createSchedules(ctx context.Context, team string, r Roster, existing RosterRecord) error {
+	var errs MultiError
+	for _, s := range r.Schedules {
+		var id int64
+		i := slices.IndexFunc(existing.Schedules, func(e ScheduleRecord) bool { return e.Role == s.Role })
+		if i >= 0 {
+			id = existing.Schedules[i].ID
+			_, err := c.SetScheduler(ctx, id, s.Scheduler, s.Order)
+			errs.addDetail("set_scheduler", "schedule", r.Name, team, s.Role, err)
+		} else {
+			res, err := c.CreateRosterSchedule(ctx, team, r.Name, s)
+			if err != nil {
+				errs.addDetail("create", "schedule", r.Name, team, s.Role, err)
+				continue
+			}
+			id = res.Data
+		}
+		if s.Populate {
+			_, err := c.PopulateSchedule(ctx, id, time.Now())
+			errs.addDetail("populate", "schedule", r.Name, team, s.Role, err)
+		}
+	}
+	return errs.Err()
+}
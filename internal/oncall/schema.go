@@ -0,0 +1,300 @@
+package oncall
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with a field of a config file, at the line and column of its node
+type FieldError struct {
+	File   string
+	Line   int
+	Column int
+	// Path locates the field from the root of the file, e.g. teams[0].users[2].duty[1].date
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	pos := e.File
+	if e.Line > 0 {
+		pos += ":" + strconv.Itoa(e.Line) + ":" + strconv.Itoa(e.Column)
+	}
+	if e.Path == "" {
+		return pos + ": " + e.Err.Error()
+	}
+	return pos + ": " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError lists the problems found by LoadConfig, in the order of the file. Its entries
+// match ErrInvalidRequest.
+type SchemaError struct {
+	Errors []*FieldError
+}
+
+func (s *SchemaError) Error() string {
+	msgs := make([]string, len(s.Errors))
+	for i, e := range s.Errors {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (s *SchemaError) Unwrap() []error {
+	errs := make([]error, len(s.Errors))
+	for i, e := range s.Errors {
+		errs[i] = e
+	}
+	return errs
+}
+
+// LoadOption configures LoadConfig
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	allowed map[string]bool
+	format  string
+	// values are the values the config files are rendered with, see WithValues
+	values map[string]any
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	o := loadOptions{allowed: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// AllowKeys lets the config file hold the top level keys of the other blocks read from the
+// same file, e.g. the scale block of the prober. Their content is not checked.
+func AllowKeys(keys ...string) LoadOption {
+	return func(o *loadOptions) {
+		for _, k := range keys {
+			o.allowed[k] = true
+		}
+	}
+}
+
+// requiredFields are the fields a mapping of the type must set to a non empty value
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(Team{}):         {"name"},
+	reflect.TypeOf(User{}):         {"name"},
+	reflect.TypeOf(Roster{}):       {"name"},
+	reflect.TypeOf(RosterMember{}): {"name"},
+	reflect.TypeOf(Duty{}):         {"date"},
+}
+
+// fieldFormats check the scalar fields of a type whose format the decoder does not know
+var fieldFormats = map[reflect.Type]map[string]func(string) error{
+	reflect.TypeOf(Team{}): {
+		"scheduling_timezone": checkTimezone,
+	},
+	reflect.TypeOf(Duty{}): {
+		"date":       checkDate,
+		"until":      checkDate,
+		"start_time": checkTime,
+		"end_time":   checkTime,
+		"timezone":   checkTimezone,
+		"every": func(s string) error {
+			_, err := parseWeekdays(s)
+			return err
+		},
+	},
+}
+
+func checkTimezone(s string) error {
+	if _, err := loadLocation(s); err != nil {
+		return fmt.Errorf("unknown timezone %q", s)
+	}
+	return nil
+}
+
+func checkDate(s string) error {
+	if _, err := time.Parse(DutyDateLayout, s); err != nil {
+		return fmt.Errorf("%q is not formatted as dd/mm/yyyy", s)
+	}
+	return nil
+}
+
+func checkTime(s string) error {
+	if _, _, err := parseDutyTime(s); err != nil {
+		return fmt.Errorf("%q is not formatted as hh:mm", s)
+	}
+	return nil
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	durationType    = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaCheck walks the yaml nodes of a config file along the Go types they decode to
+type schemaCheck struct {
+	file    configNode
+	allowed map[string]bool
+	errs    []*FieldError
+}
+
+func (s *schemaCheck) add(n *yaml.Node, path string, format string, args ...any) {
+	s.errs = append(s.errs, &FieldError{
+		File:   s.file.fileOf(n),
+		Line:   n.Line,
+		Column: n.Column,
+		Path:   path,
+		Err:    fmt.Errorf("%w: "+format, append([]any{ErrInvalidRequest}, args...)...),
+	})
+}
+
+func (s *schemaCheck) walk(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// types decoding themselves and durations report their own format errors
+	if reflect.PointerTo(t).Implements(unmarshalerType) || t == durationType {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			s.add(n, path, "expected a mapping")
+			return
+		}
+		s.mapping(n, t, path)
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			s.add(n, path, "expected a list")
+			return
+		}
+		for i, e := range n.Content {
+			s.walk(e, t.Elem(), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			s.add(n, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			s.walk(n.Content[i+1], t.Elem(), fieldPath(path, n.Content[i].Value))
+		}
+	}
+}
+
+// mapping checks the keys of a mapping decoding to the struct t: they must be fields of t,
+// the required ones present and not empty, and formatted as fieldFormats expects
+func (s *schemaCheck) mapping(n *yaml.Node, t reflect.Type, path string) {
+	fields := yamlFields(t)
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		p := fieldPath(path, k.Value)
+		if seen[k.Value] {
+			s.add(k, p, "duplicate field")
+			continue
+		}
+		seen[k.Value] = true
+		f, ok := fields[k.Value]
+		if !ok {
+			if path == "" && s.allowed[k.Value] {
+				continue
+			}
+			if guess := closest(k.Value, fields); guess != "" {
+				s.add(k, p, "unknown field %q, did you mean %q?", k.Value, guess)
+			} else {
+				s.add(k, p, "unknown field %q", k.Value)
+			}
+			continue
+		}
+		s.walk(v, f, p)
+		if check, ok := fieldFormats[t][k.Value]; ok && v.Kind == yaml.ScalarNode && v.Value != "" {
+			if err := check(v.Value); err != nil {
+				s.add(v, p, "%v", err)
+			}
+		}
+	}
+	for _, name := range requiredFields[t] {
+		if !seen[name] {
+			s.add(n, path, "missing required field %q", name)
+			continue
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if v := n.Content[i+1]; n.Content[i].Value == name && v.Kind == yaml.ScalarNode && strings.TrimSpace(v.Value) == "" {
+				s.add(v, fieldPath(path, name), "required field is empty")
+			}
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of the exported fields of t to their types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closest returns the field whose name is within two edits of key, for misspelled keys
+func closest(key string, fields map[string]reflect.Type) string {
+	best, dist := "", 3
+	for _, name := range sortedKeys(fields) {
+		if d := editDistance(key, name); d < dist {
+			best, dist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
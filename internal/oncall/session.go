@@ -0,0 +1,82 @@
+package oncall
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// statusAuthenticationTimeout is answered by oncall when the CSRF token of a session expired
+const statusAuthenticationTimeout = 419
+
+// session refreshes the login of a client when oncall rejects its cookies or CSRF token
+type session struct {
+	// mu serializes the relogins, so that requests rejected at once log in a single time
+	mu sync.Mutex
+	// logins counts the successful logins, telling a request whether the session it was
+	// rejected with was already replaced
+	logins   atomic.Int64
+	relogins atomic.Int64
+}
+
+// Relogins returns the number of times the client logged in again after its session expired
+func (c *Client) Relogins() int64 {
+	return c.session.relogins.Load()
+}
+
+// do sends req within a span and through the circuit breaker, see doTraced, doBreaker and doSession.
+// Mutating requests of a dry run are withheld, see WithDryRun.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if res, withheld, err := c.withhold(req); withheld {
+		return res, err
+	}
+	return c.doTraced(req, c.doBreaker)
+}
+
+// doSession sends req, logging in again and replaying req once if the session expired.
+// Requests rejected by the same session wait for a single relogin and are replayed with it.
+func (c *Client) doSession(req *http.Request) (*http.Response, error) {
+	sent := c.session.logins.Load()
+	res, err := c.doRetry(req)
+	if err != nil || !c.canRelogin(req, res) {
+		return res, err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	logger := c.logger.With().Str("action", "relogin").Int("status_code", res.StatusCode).Str("url", req.URL.Path).Logger()
+	c.session.mu.Lock()
+	if c.session.logins.Load() == sent {
+		if err = c.Login(req.Context()); err != nil {
+			c.session.mu.Unlock()
+			logger.Error().Err(err).Msg("session expired and login failed")
+			return nil, err
+		}
+		c.session.relogins.Add(1)
+		logger.Info().Msg("session expired, logged in again")
+	} else {
+		logger.Debug().Msg("session expired, replaying with the session of a concurrent relogin")
+	}
+	c.session.mu.Unlock()
+
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if r.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return c.doRetry(r)
+}
+
+// canRelogin reports whether res rejected the session of a request that can be replayed
+func (c *Client) canRelogin(req *http.Request, res *http.Response) bool {
+	if res.StatusCode != http.StatusUnauthorized && res.StatusCode != statusAuthenticationTimeout {
+		return false
+	}
+	if c.appAuth != nil || strings.HasSuffix(req.URL.Path, loginEndpoint) {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
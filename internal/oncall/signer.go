@@ -0,0 +1,126 @@
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestSigner authenticates requests to a gateway fronting oncall, e.g. with AWS SigV4, an
+// OAuth2 access token or a custom HMAC. Sign is called right before each attempt is sent, after
+// the hooks of WithRequestHook, so retried and replayed requests are signed again. Sign must not
+// consume req.Body, see RequestBody.
+type RequestSigner interface {
+	Sign(req *http.Request) error
+}
+
+// RequestSignerFunc adapts a function to a RequestSigner
+type RequestSignerFunc func(req *http.Request) error
+
+func (f RequestSignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// WithRequestSigner signs every request sent to oncall with s, including logins.
+// Signers run in the order they were added, a signing error fails the request.
+func WithRequestSigner(s RequestSigner) Option {
+	return func(c *Client) {
+		c.hooks.signers = append(c.hooks.signers, s)
+	}
+}
+
+// RequestBody returns a copy of the body of req for signers hashing it, nil if req has no body
+func RequestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// tokenExpiryMargin is how long before its expiry a token of ClientCredentials is renewed
+const tokenExpiryMargin = 30 * time.Second
+
+// ClientCredentials is a RequestSigner setting the bearer token of the OAuth2 client
+// credentials grant. The token is fetched from TokenURL on first use and renewed once expired.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient fetches the tokens, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (cc *ClientCredentials) Sign(req *http.Request) error {
+	token, err := cc.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns the current access token, fetching a new one if it expired
+func (cc *ClientCredentials) Token(ctx context.Context) (string, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.token != "" && time.Now().Before(cc.expires) {
+		return cc.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cc.Scopes) > 0 {
+		form.Set("scope", strings.Join(cc.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(cc.ClientID), url.QueryEscape(cc.ClientSecret))
+	cl := cc.HTTPClient
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	res, err := cl.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching oauth2 token: %w", err)
+	}
+	defer res.Body.Close()
+	if err = checkResponse(res); err != nil {
+		return "", fmt.Errorf("fetching oauth2 token: %w", err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding oauth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("fetching oauth2 token: empty access_token")
+	}
+	cc.token = token.AccessToken
+	// tokens without expiry are renewed hourly
+	lifetime := time.Hour
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+	cc.expires = time.Now().Add(max(lifetime-tokenExpiryMargin, lifetime/2))
+	return cc.token, nil
+}
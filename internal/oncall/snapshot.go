@@ -0,0 +1,122 @@
+package oncall
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultSnapshotConcurrency is the number of teams fetched at once by Snapshot
+const defaultSnapshotConcurrency = 4
+
+// SnapshotOptions narrows down what Snapshot pulls from oncall
+type SnapshotOptions struct {
+	// Teams restricts the snapshot to these teams, every team if empty
+	Teams []string
+	// Concurrency is the number of teams fetched at once, 4 if zero
+	Concurrency int
+	// Events also pulls the events of each team lying within [EventsStart, EventsEnd].
+	// Zero bounds are ignored.
+	Events      bool
+	EventsStart time.Time
+	EventsEnd   time.Time
+	// Progress is called after each team is fetched, from the fetching goroutine
+	Progress func(SnapshotProgress)
+}
+
+// SnapshotProgress reports how many teams of a snapshot are fetched
+type SnapshotProgress struct {
+	Team  string
+	Done  int
+	Total int
+}
+
+// ServerState is the state of oncall at the time of a snapshot.
+// The rosters and their schedules are part of the team records.
+type ServerState struct {
+	TakenAt time.Time
+	Teams   map[string]TeamRecord
+	Users   map[string]UserRecord
+	// Events are keyed by team, only set with SnapshotOptions.Events
+	Events map[string][]Event
+}
+
+// Snapshot pulls the users and the teams of oncall, with their rosters and optionally their events,
+// into a single ServerState. Teams are fetched concurrently. Entities that could not be fetched are
+// missing from the state and reported in the returned *MultiError.
+func (c *Client) Snapshot(ctx context.Context, opts SnapshotOptions) (*ServerState, error) {
+	state := &ServerState{
+		TakenAt: time.Now(),
+		Teams:   make(map[string]TeamRecord),
+		Users:   make(map[string]UserRecord),
+	}
+	if opts.Events {
+		state.Events = make(map[string][]Event)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultSnapshotConcurrency
+	}
+	var errs MultiError
+
+	teams := opts.Teams
+	if len(teams) == 0 {
+		res, err := c.GetTeams(ctx)
+		if err != nil {
+			errs.Add("snapshot", "team", "", "", err)
+			return state, errs.Err()
+		}
+		teams = res.Data
+	}
+	users, err := c.GetUsers(ctx, UserFilter{})
+	if err != nil {
+		errs.Add("snapshot", "user", "", "", err)
+	} else {
+		for _, u := range users.Data {
+			state.Users[u.Name] = u
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done int
+		sem  = make(chan struct{}, max(opts.Concurrency, 1))
+	)
+	for _, team := range teams {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(team string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			record, recordErr := c.GetTeam(ctx, team)
+			var (
+				events    *Response[[]Event]
+				eventsErr error
+			)
+			if opts.Events {
+				events, eventsErr = c.GetEvents(ctx, EventFilter{Team: team, Start: opts.EventsStart, End: opts.EventsEnd})
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if recordErr != nil {
+				errs.Add("snapshot", "team", team, team, recordErr)
+			} else {
+				state.Teams[team] = record.Data
+			}
+			if eventsErr != nil {
+				errs.Add("snapshot", "event", "", team, eventsErr)
+			} else if events != nil {
+				state.Events[team] = events.Data
+			}
+			done++
+			if opts.Progress != nil {
+				opts.Progress(SnapshotProgress{Team: team, Done: done, Total: len(teams)})
+			}
+		}(team)
+	}
+	wg.Wait()
+	return state, errs.Err()
+}
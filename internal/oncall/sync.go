@@ -0,0 +1,492 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// SyncOption configures Sync
+type SyncOption func(*syncOptions)
+
+type syncOptions struct {
+	prune bool
+	plan  bool
+}
+
+// SyncPrune makes Sync also delete what the configured teams have on the server but the config
+// does not list: members, admins, services, rosters and their members, and the events that were
+// not created by a roster schedule. Teams and users missing from the config are never deleted.
+func SyncPrune() SyncOption {
+	return func(o *syncOptions) {
+		o.prune = true
+	}
+}
+
+// SyncPlan makes Sync only compute the changes, oncall is read but nothing is changed
+func SyncPlan() SyncOption {
+	return func(o *syncOptions) {
+		o.plan = true
+	}
+}
+
+// SyncAction is what a change of Sync does to an entity
+type SyncAction string
+
+const (
+	SyncCreate SyncAction = "create"
+	SyncUpdate SyncAction = "update"
+	SyncDelete SyncAction = "delete"
+)
+
+// SyncChange is a change Sync applied, or planned with SyncPlan
+type SyncChange struct {
+	Action SyncAction `json:"action"`
+	// Kind is the kind of entity, as in EntityError
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Team string `json:"team,omitempty"`
+	// Detail identifies the entity within Name, e.g. the roster of a member or the role of an event
+	Detail string `json:"detail,omitempty"`
+	// Fields are the changed fields of an update, keyed by their json name
+	Fields  map[string]any `json:"fields,omitempty"`
+	Applied bool           `json:"applied"`
+	Err     error          `json:"-"`
+}
+
+// SyncReport lists the changes of Sync in the order they were applied
+type SyncReport struct {
+	Changes []SyncChange `json:"changes"`
+	// Plan is set when the changes were only planned, see SyncPlan
+	Plan bool `json:"plan"`
+}
+
+// Failures lists the failed changes as entity errors, as returned by Sync
+func (r *SyncReport) Failures() []*EntityError {
+	var errs MultiError
+	for _, ch := range r.Changes {
+		if ch.Err != nil {
+			errs.addDetail(string(ch.Action), ch.Kind, ch.Name, ch.Team, ch.Detail, ch.Err)
+		}
+	}
+	return errs.Errors
+}
+
+// syncStep is a planned change and the requests applying it
+type syncStep struct {
+	change SyncChange
+	apply  func(ctx context.Context) error
+}
+
+// Sync makes oncall match config: what the configured teams and users hold on the server is
+// compared with the config and only the missing or different entities are created or updated,
+// and deleted with SyncPrune. Creates and updates are applied before deletes, which remove the
+// events, roster members, rosters, services and admins of each team before its members.
+// Running Sync again with the same config changes nothing.
+//
+// Events are compared from the earliest configured duty on. The schedulers of existing roster
+// schedules are left as they are.
+func (c *Client) Sync(ctx context.Context, config Config, opts ...SyncOption) (*SyncReport, error) {
+	var o syncOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if roles := c.knownRoles(ctx); roles != nil {
+		if err := config.ValidateRoles(roles); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := SnapshotOptions{}
+	for _, t := range config.Teams {
+		snapshot.Teams = append(snapshot.Teams, t.Name)
+		for _, u := range t.Users {
+			for _, d := range u.Schedule {
+				start, _, _ := d.Shift(t.SchedulingTimezone)
+				if !snapshot.Events || start.Before(snapshot.EventsStart) {
+					snapshot.Events, snapshot.EventsStart = true, start
+				}
+			}
+		}
+	}
+	if len(snapshot.Teams) == 0 {
+		return &SyncReport{Plan: o.plan}, nil
+	}
+	state, err := c.Snapshot(ctx, snapshot)
+	if err = withoutNotFound(err); err != nil {
+		return nil, err
+	}
+
+	steps, err := c.planSync(ctx, config, state, o.prune)
+	if err != nil {
+		return nil, err
+	}
+	report := &SyncReport{Plan: o.plan}
+	for _, s := range steps {
+		if !o.plan {
+			s.change.Err = s.apply(ctx)
+			s.change.Applied = s.change.Err == nil
+		}
+		report.Changes = append(report.Changes, s.change)
+	}
+	if f := report.Failures(); len(f) > 0 {
+		return report, &MultiError{Errors: f}
+	}
+	return report, nil
+}
+
+// withoutNotFound drops the entities missing from the server out of a *MultiError of Snapshot:
+// they are created by Sync
+func withoutNotFound(err error) error {
+	var multi *MultiError
+	if !errors.As(err, &multi) {
+		return err
+	}
+	var errs MultiError
+	for _, e := range multi.Errors {
+		if !errors.Is(e.Err, ErrNotFound) {
+			errs.Errors = append(errs.Errors, e)
+		}
+	}
+	return errs.Err()
+}
+
+// planSync computes the changes making state match config, creates and updates first
+func (c *Client) planSync(ctx context.Context, config Config, state *ServerState, prune bool) ([]syncStep, error) {
+	var steps, deletes []syncStep
+	add := func(change SyncChange, apply func(context.Context) error) {
+		if change.Action == SyncDelete {
+			deletes = append(deletes, syncStep{change: change, apply: apply})
+			return
+		}
+		steps = append(steps, syncStep{change: change, apply: apply})
+	}
+
+	for _, t := range config.Teams {
+		t := t
+		record, exists := state.Teams[t.Name]
+		if !exists {
+			add(SyncChange{Action: SyncCreate, Kind: "team", Name: t.Name, Team: t.Name}, func(ctx context.Context) error {
+				return c.syncCreateTeam(ctx, t)
+			})
+			continue
+		}
+		if changes := teamChanges(t, record); len(changes) > 0 {
+			add(SyncChange{Action: SyncUpdate, Kind: "team", Name: t.Name, Team: t.Name, Fields: changes}, func(ctx context.Context) error {
+				_, err := c.updateFields(ctx, "sync_team", changes, teamsEndpoint, t.Name)
+				return err
+			})
+		}
+	}
+
+	planned := make(map[string]bool)
+	for _, t := range config.Teams {
+		for _, u := range t.Users {
+			if planned[u.Name] {
+				continue
+			}
+			planned[u.Name] = true
+			u := u
+			record, exists := state.Users[u.Name]
+			if !exists {
+				add(SyncChange{Action: SyncCreate, Kind: "user", Name: u.Name}, func(ctx context.Context) error {
+					_, err := c.CreateUser(ctx, u)
+					return err
+				})
+				continue
+			}
+			if !record.Active {
+				add(SyncChange{Action: SyncUpdate, Kind: "user", Name: u.Name, Fields: map[string]any{"active": true}}, func(ctx context.Context) error {
+					_, err := c.ReactivateUser(ctx, u.Name)
+					return err
+				})
+			}
+			if changes := userChanges(u, record); len(changes) > 0 {
+				add(SyncChange{Action: SyncUpdate, Kind: "user", Name: u.Name, Fields: changes}, func(ctx context.Context) error {
+					_, err := c.updateFields(ctx, "sync_user", changes, usersEndpoint, u.Name)
+					return err
+				})
+			}
+		}
+	}
+
+	for _, t := range config.Teams {
+		if err := c.planTeam(ctx, t, state, prune, add); err != nil {
+			return nil, err
+		}
+	}
+	return append(steps, deletes...), nil
+}
+
+// syncCreateTeam creates the team t alone, its members and rosters are planned separately
+func (c *Client) syncCreateTeam(ctx context.Context, t Team) error {
+	logger := c.logger.With().Str("action", "sync_team").Str("team", t.Name).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint)
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodPost, endpoint, teamDTO(t))
+	return err
+}
+
+// planTeam plans the changes of the members, notifications, events, rosters, services and
+// admins of t. The team is empty in state if it does not exist yet.
+func (c *Client) planTeam(ctx context.Context, t Team, state *ServerState, prune bool, add func(SyncChange, func(context.Context) error)) error {
+	record := state.Teams[t.Name]
+	team := t.Name
+
+	// members, admins are members of the team as well
+	members := make(map[string]bool)
+	for _, u := range t.Users {
+		u := u
+		members[u.Name] = true
+		if _, ok := record.Users[u.Name]; !ok {
+			add(SyncChange{Action: SyncCreate, Kind: "team_user", Name: u.Name, Team: team}, func(ctx context.Context) error {
+				_, err := c.AddUserToTeam(ctx, u.Name, team)
+				return err
+			})
+		}
+	}
+	for _, a := range t.Admins {
+		members[a] = true
+	}
+
+	// notifications
+	for _, u := range t.Users {
+		if len(u.Notifications) == 0 {
+			continue
+		}
+		var existing []NotificationRecord
+		if _, ok := state.Users[u.Name]; ok {
+			res, err := c.GetNotifications(ctx, u.Name)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if res != nil {
+				existing = res.Data
+			}
+		}
+		for _, n := range u.Notifications {
+			if hasNotification(existing, team, n) {
+				continue
+			}
+			user, n, want := u.Name, n, notificationDTO(team, n)
+			add(SyncChange{Action: SyncCreate, Kind: "notification", Name: user, Team: team, Detail: want.Mode + " " + want.Type}, func(ctx context.Context) error {
+				_, err := c.CreateNotification(ctx, user, team, n)
+				return err
+			})
+		}
+	}
+
+	c.planEvents(t, state.Events[team], prune, add)
+	c.planRosters(t, record, prune, add)
+
+	// services and admins
+	for _, svc := range t.Services {
+		if !slices.Contains(record.Services, svc) {
+			svc := svc
+			add(SyncChange{Action: SyncCreate, Kind: "service", Name: svc, Team: team}, func(ctx context.Context) error {
+				_, err := c.AddService(ctx, team, svc)
+				return err
+			})
+		}
+	}
+	var admins []string
+	for _, a := range record.Admins {
+		admins = append(admins, a.Name)
+	}
+	for _, a := range t.Admins {
+		if !slices.Contains(admins, a) {
+			a := a
+			add(SyncChange{Action: SyncCreate, Kind: "admin", Name: a, Team: team}, func(ctx context.Context) error {
+				_, err := c.AddAdmin(ctx, team, a)
+				return err
+			})
+		}
+	}
+	if prune {
+		for _, svc := range record.Services {
+			if !slices.Contains(t.Services, svc) {
+				svc := svc
+				add(SyncChange{Action: SyncDelete, Kind: "service", Name: svc, Team: team}, func(ctx context.Context) error {
+					return c.DeleteService(ctx, team, svc)
+				})
+			}
+		}
+		for _, a := range admins {
+			if !slices.Contains(t.Admins, a) {
+				a := a
+				add(SyncChange{Action: SyncDelete, Kind: "admin", Name: a, Team: team}, func(ctx context.Context) error {
+					return c.DeleteAdmin(ctx, team, a)
+				})
+			}
+		}
+	}
+
+	// members last, once their events, roster memberships and admin rights are gone
+	if prune {
+		for _, name := range sortedKeys(record.Users) {
+			if !members[name] {
+				name := name
+				add(SyncChange{Action: SyncDelete, Kind: "team_user", Name: name, Team: team}, func(ctx context.Context) error {
+					return c.DeleteUserFromTeam(ctx, name, team)
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// eventKey identifies an event by what the config sets
+type eventKey struct {
+	user, role string
+	start, end int64
+}
+
+// planEvents plans the events of the duties of the members of t missing from existing,
+// consecutive days being created at once as linked events
+func (c *Client) planEvents(t Team, existing []Event, prune bool, add func(SyncChange, func(context.Context) error)) {
+	have := make(map[eventKey]bool)
+	for _, e := range existing {
+		have[eventKey{e.User, e.Role, e.Start, e.End}] = true
+	}
+	want := make(map[eventKey]bool)
+	// the dates of the changes are local to the team, Validate checked its timezone
+	loc, err := loadLocation(t.SchedulingTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	for _, u := range t.Users {
+		var missing []dto.ScheduleDTO
+		for _, d := range ExpandDuties(u.Schedule) {
+			start, end, _ := d.Shift(t.SchedulingTimezone)
+			key := eventKey{u.Name, d.Role, start.Unix(), end.Unix()}
+			want[key] = true
+			if !have[key] {
+				missing = append(missing, dto.ScheduleDTO{
+					Username:      u.Name,
+					Teamname:      t.Name,
+					Role:          d.Role,
+					StartTimeUnix: key.start,
+					EndTimeUnix:   key.end,
+				})
+			}
+		}
+		for _, run := range consecutiveRuns(missing) {
+			run := run
+			detail := run[0].Role + " " + time.Unix(run[0].StartTimeUnix, 0).In(loc).Format(DutyDateLayout)
+			if len(run) > 1 {
+				detail += "-" + time.Unix(run[len(run)-1].StartTimeUnix, 0).In(loc).Format(DutyDateLayout)
+			}
+			add(SyncChange{Action: SyncCreate, Kind: "event", Name: u.Name, Team: t.Name, Detail: detail}, func(ctx context.Context) error {
+				if len(run) == 1 {
+					_, err := c.createEvent(ctx, run[0])
+					return err
+				}
+				_, err := c.CreateLinkedEvents(ctx, run)
+				return err
+			})
+		}
+	}
+	if !prune {
+		return
+	}
+	for _, e := range existing {
+		// events of roster schedules are managed by their scheduler
+		if e.ScheduleID != nil || want[eventKey{e.User, e.Role, e.Start, e.End}] {
+			continue
+		}
+		id := e.ID
+		detail := e.Role + " " + time.Unix(e.Start, 0).In(loc).Format(DutyDateLayout)
+		add(SyncChange{Action: SyncDelete, Kind: "event", Name: e.User, Team: t.Name, Detail: detail}, func(ctx context.Context) error {
+			return c.DeleteEvent(ctx, id)
+		})
+	}
+}
+
+// planRosters plans the rosters of t, their members and the schedules of roles they lack
+func (c *Client) planRosters(t Team, record TeamRecord, prune bool, add func(SyncChange, func(context.Context) error)) {
+	team := t.Name
+	for _, r := range t.Rosters {
+		r := r
+		existing, exists := record.Rosters[r.Name]
+		if !exists {
+			add(SyncChange{Action: SyncCreate, Kind: "roster", Name: r.Name, Team: team}, func(ctx context.Context) error {
+				_, err := c.CreateRoster(ctx, team, r.Name)
+				return err
+			})
+		}
+		for _, m := range r.Users {
+			name, inRotation := m.Name, m.InRotation == nil || *m.InRotation
+			i := slices.IndexFunc(existing.Users, func(u RosterUser) bool { return u.Name == name })
+			switch {
+			case i < 0:
+				add(SyncChange{Action: SyncCreate, Kind: "roster_user", Name: name, Team: team, Detail: "roster " + r.Name}, func(ctx context.Context) error {
+					_, err := c.AddUserToRoster(ctx, team, r.Name, name, inRotation)
+					return err
+				})
+			case existing.Users[i].InRotation != inRotation:
+				add(SyncChange{
+					Action: SyncUpdate, Kind: "roster_user", Name: name, Team: team, Detail: "roster " + r.Name,
+					Fields: map[string]any{"in_rotation": inRotation},
+				}, func(ctx context.Context) error {
+					_, err := c.SetRosterUserScheduling(ctx, team, r.Name, name, inRotation)
+					return err
+				})
+			}
+		}
+		for _, s := range r.Schedules {
+			if slices.ContainsFunc(existing.Schedules, func(e ScheduleRecord) bool { return e.Role == s.Role }) {
+				continue
+			}
+			s := s
+			add(SyncChange{Action: SyncCreate, Kind: "schedule", Name: r.Name, Team: team, Detail: s.Role}, func(ctx context.Context) error {
+				res, err := c.CreateRosterSchedule(ctx, team, r.Name, s)
+				if err != nil || !s.Populate {
+					return err
+				}
+				_, err = c.PopulateSchedule(ctx, res.Data, time.Now())
+				return err
+			})
+		}
+		if prune {
+			for _, u := range existing.Users {
+				if slices.ContainsFunc(r.Users, func(m RosterMember) bool { return m.Name == u.Name }) {
+					continue
+				}
+				name := u.Name
+				add(SyncChange{Action: SyncDelete, Kind: "roster_user", Name: name, Team: team, Detail: "roster " + r.Name}, func(ctx context.Context) error {
+					return c.RemoveUserFromRoster(ctx, team, r.Name, name)
+				})
+			}
+		}
+	}
+	if !prune {
+		return
+	}
+	for _, name := range sortedKeys(record.Rosters) {
+		if slices.ContainsFunc(t.Rosters, func(r Roster) bool { return r.Name == name }) {
+			continue
+		}
+		name := name
+		add(SyncChange{Action: SyncDelete, Kind: "roster", Name: name, Team: team}, func(ctx context.Context) error {
+			return c.DeleteRoster(ctx, team, name)
+		})
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
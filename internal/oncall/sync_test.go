@@ -0,0 +1,373 @@
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// fakeOncall is an in-memory oncall server serving the endpoints used by Sync
+type fakeOncall struct {
+	mu     sync.Mutex
+	teams  map[string]*TeamRecord
+	users  map[string]*UserRecord
+	events map[int64]Event
+	nextID int64
+	// writes are the requests changing the server, as "METHOD path"
+	writes []string
+}
+
+func newFakeOncall(t *testing.T) (*fakeOncall, *Client) {
+	t.Helper()
+	f := &fakeOncall{teams: make(map[string]*TeamRecord), users: make(map[string]*UserRecord), events: make(map[int64]Event)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := New(WithURL(srv.URL), WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, c
+}
+
+func (f *fakeOncall) addTeam(name string) *TeamRecord {
+	f.nextID++
+	t := &TeamRecord{ID: f.nextID, Name: name, Users: map[string]UserRecord{}, Rosters: map[string]RosterRecord{}}
+	f.teams[name] = t
+	return t
+}
+
+func (f *fakeOncall) addUser(name string) *UserRecord {
+	f.nextID++
+	u := &UserRecord{ID: f.nextID, Name: name, Active: true}
+	f.users[name] = u
+	return u
+}
+
+func (f *fakeOncall) addEvent(e dto.ScheduleDTO) int64 {
+	f.nextID++
+	f.events[f.nextID] = Event{ID: f.nextID, User: e.Username, Team: e.Teamname, Role: e.Role, Start: e.StartTimeUnix, End: e.EndTimeUnix}
+	return f.nextID
+}
+
+func (f *fakeOncall) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/login" {
+		writeFake(w, http.StatusOK, map[string]string{"csrf_token": "token"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		f.writes = append(f.writes, r.Method+" "+strings.TrimSuffix(r.URL.Path, "/"))
+	}
+	var body struct {
+		dto.TeamCreateDTO
+		dto.ScheduleDTO
+		FullName   string   `json:"full_name"`
+		Contacts   Contacts `json:"contacts"`
+		InRotation bool     `json:"in_rotation"`
+	}
+	var link []dto.ScheduleDTO
+	if r.URL.Path == "/api/v0/events/link" {
+		_ = json.NewDecoder(r.Body).Decode(&link)
+	} else {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+	p := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v0/"), "/"), "/")
+	route := r.Method + " " + p[0]
+	if len(p) > 2 {
+		route += " " + p[2]
+	}
+	if len(p) > 4 {
+		route += " " + p[4]
+	}
+	route += " " + strconv.Itoa(len(p))
+	team := func() (*TeamRecord, bool) {
+		t, ok := f.teams[p[1]]
+		if !ok {
+			writeFake(w, http.StatusNotFound, nil)
+		}
+		return t, ok
+	}
+	conflict := func() {
+		http.Error(w, `{"title":"IntegrityError","description":"already exists"}`, http.StatusUnprocessableEntity)
+	}
+
+	switch route {
+	case "GET roles 1":
+		writeFake(w, http.StatusOK, []RoleRecord{{Name: "primary"}, {Name: "secondary"}})
+	case "GET users 1":
+		users := []UserRecord{}
+		for _, name := range sortedKeys(f.users) {
+			users = append(users, *f.users[name])
+		}
+		writeFake(w, http.StatusOK, users)
+	case "POST users 1":
+		if _, ok := f.users[body.Name]; ok {
+			conflict()
+			return
+		}
+		f.addUser(body.Name)
+		writeFake(w, http.StatusCreated, nil)
+	case "PUT users 2":
+		u, ok := f.users[p[1]]
+		if !ok {
+			writeFake(w, http.StatusNotFound, nil)
+			return
+		}
+		if body.FullName != "" {
+			u.FullName = body.FullName
+		}
+		if body.Contacts.Call != "" {
+			u.Contacts.Call = body.Contacts.Call
+		}
+		if body.Contacts.Email != "" {
+			u.Contacts.Email = body.Contacts.Email
+		}
+		writeFake(w, http.StatusNoContent, nil)
+	case "POST teams 1":
+		if _, ok := f.teams[body.TeamCreateDTO.Name]; ok {
+			conflict()
+			return
+		}
+		t := f.addTeam(body.TeamCreateDTO.Name)
+		t.Email, t.SchedulingTimezone = body.Email, body.SchedulingTimezone
+		t.SlackChannel, t.SlackChannelNotifications = body.SlackChannel, body.SlackChannelNotifications
+		writeFake(w, http.StatusCreated, nil)
+	case "GET users 2":
+		if u, ok := f.users[p[1]]; ok {
+			writeFake(w, http.StatusOK, u)
+		} else {
+			writeFake(w, http.StatusNotFound, nil)
+		}
+	case "GET users notifications 3":
+		writeFake(w, http.StatusOK, []NotificationRecord{})
+	case "PUT teams 2":
+		if t, ok := team(); ok {
+			t.Email, t.SchedulingTimezone = body.Email, body.SchedulingTimezone
+			t.SlackChannel, t.SlackChannelNotifications = body.SlackChannel, body.SlackChannelNotifications
+			writeFake(w, http.StatusNoContent, nil)
+		}
+	case "GET teams 2":
+		if t, ok := team(); ok {
+			writeFake(w, http.StatusOK, t)
+		}
+	case "GET teams users 3":
+		if t, ok := team(); ok {
+			writeFake(w, http.StatusOK, sortedKeys(t.Users))
+		}
+	case "POST teams users 3":
+		if t, ok := team(); ok {
+			t.Users[body.TeamCreateDTO.Name] = *f.users[body.TeamCreateDTO.Name]
+			writeFake(w, http.StatusCreated, nil)
+		}
+	case "DELETE teams users 4":
+		if t, ok := team(); ok {
+			delete(t.Users, p[3])
+			writeFake(w, http.StatusOK, nil)
+		}
+	case "POST teams services 3":
+		if t, ok := team(); ok {
+			t.Services = append(t.Services, body.TeamCreateDTO.Name)
+			writeFake(w, http.StatusCreated, nil)
+		}
+	case "DELETE teams services 4":
+		if t, ok := team(); ok {
+			t.Services = slices.DeleteFunc(t.Services, func(s string) bool { return s == p[3] })
+			writeFake(w, http.StatusOK, nil)
+		}
+	case "POST teams admins 3":
+		if t, ok := team(); ok {
+			t.Admins = append(t.Admins, UserRecord{Name: body.TeamCreateDTO.Name})
+			t.Users[body.TeamCreateDTO.Name] = *f.users[body.TeamCreateDTO.Name]
+			writeFake(w, http.StatusCreated, nil)
+		}
+	case "DELETE teams admins 4":
+		if t, ok := team(); ok {
+			t.Admins = slices.DeleteFunc(t.Admins, func(u UserRecord) bool { return u.Name == p[3] })
+			writeFake(w, http.StatusOK, nil)
+		}
+	case "POST teams rosters 3":
+		if t, ok := team(); ok {
+			t.Rosters[body.TeamCreateDTO.Name] = RosterRecord{}
+			writeFake(w, http.StatusCreated, nil)
+		}
+	case "DELETE teams rosters 4":
+		if t, ok := team(); ok {
+			delete(t.Rosters, p[3])
+			writeFake(w, http.StatusOK, nil)
+		}
+	case "POST teams rosters users 5":
+		if t, ok := team(); ok {
+			roster := t.Rosters[p[3]]
+			roster.Users = append(roster.Users, RosterUser{Name: body.TeamCreateDTO.Name, InRotation: body.InRotation})
+			t.Rosters[p[3]] = roster
+			writeFake(w, http.StatusCreated, nil)
+		}
+	case "DELETE teams rosters users 6":
+		if t, ok := team(); ok {
+			roster := t.Rosters[p[3]]
+			roster.Users = slices.DeleteFunc(roster.Users, func(u RosterUser) bool { return u.Name == p[5] })
+			t.Rosters[p[3]] = roster
+			writeFake(w, http.StatusOK, nil)
+		}
+	case "GET events 1":
+		q := r.URL.Query()
+		events := []Event{}
+		ids := make([]int64, 0, len(f.events))
+		for id := range f.events {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			e := f.events[id]
+			start, _ := strconv.ParseInt(q.Get("start__ge"), 10, 64)
+			if (q.Get("team") == "" || e.Team == q.Get("team")) && (q.Get("user") == "" || e.User == q.Get("user")) && e.Start >= start {
+				events = append(events, e)
+			}
+		}
+		writeFake(w, http.StatusOK, events)
+	case "POST events 1":
+		writeFake(w, http.StatusCreated, f.addEvent(body.ScheduleDTO))
+	case "POST events 2":
+		var linked LinkedEvents
+		for _, e := range link {
+			linked.EventIDs = append(linked.EventIDs, f.addEvent(e))
+		}
+		writeFake(w, http.StatusCreated, linked)
+	case "DELETE events 2":
+		id, _ := strconv.ParseInt(p[1], 10, 64)
+		if _, ok := f.events[id]; !ok {
+			writeFake(w, http.StatusNotFound, nil)
+			return
+		}
+		delete(f.events, id)
+		writeFake(w, http.StatusOK, nil)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusNotImplemented)
+	}
+}
+
+func writeFake(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+func syncTestConfig() Config {
+	return Config{Teams: []Team{{
+		Name:               "infra",
+		SchedulingTimezone: "UTC",
+		Email:              "infra@example.com",
+		Users: []User{
+			{Name: "alice", FullName: "Alice", Email: "alice@example.com", Schedule: []Duty{
+				{Date: "01/01/2099", Role: "primary"},
+				{Date: "02/01/2099", Role: "primary"},
+			}},
+			{Name: "bob", PhoneNumber: "+100"},
+		},
+		Rosters:  []Roster{{Name: "main", Users: []RosterMember{{Name: "alice"}}}},
+		Services: []string{"api"},
+		Admins:   []string{"alice"},
+	}}}
+}
+
+func TestSyncPlanMakesNoWrites(t *testing.T) {
+	f, c := newFakeOncall(t)
+	report, err := c.Sync(context.Background(), syncTestConfig(), SyncPlan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Plan || len(report.Changes) == 0 {
+		t.Fatalf("plan = %v with %d changes, want a plan of the whole config", report.Plan, len(report.Changes))
+	}
+	for _, ch := range report.Changes {
+		if ch.Applied {
+			t.Errorf("change %+v applied by a plan", ch)
+		}
+	}
+	if len(f.writes) > 0 {
+		t.Errorf("plan wrote to oncall: %v", f.writes)
+	}
+}
+
+func TestSyncIsIdempotent(t *testing.T) {
+	f, c := newFakeOncall(t)
+	ctx := context.Background()
+	if _, err := c.Sync(ctx, syncTestConfig(), SyncPrune()); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.writes) == 0 {
+		t.Fatal("the first sync wrote nothing")
+	}
+	if n := len(f.events); n != 2 {
+		t.Errorf("%d events after the first sync, want 2", n)
+	}
+	f.writes = nil
+	report, err := c.Sync(ctx, syncTestConfig(), SyncPrune())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) > 0 || len(f.writes) > 0 {
+		t.Errorf("second sync changed %+v with writes %v, want nothing", report.Changes, f.writes)
+	}
+}
+
+func TestSyncPruneOrder(t *testing.T) {
+	f, c := newFakeOncall(t)
+	ctx := context.Background()
+	if _, err := c.Sync(ctx, syncTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	// entities of the team the config does not list
+	carol := f.addUser("carol")
+	infra := f.teams["infra"]
+	infra.Users["carol"] = *carol
+	infra.Services = append(infra.Services, "legacy-api")
+	infra.Admins = append(infra.Admins, UserRecord{Name: "carol"})
+	infra.Rosters["legacy"] = RosterRecord{Users: []RosterUser{{Name: "carol"}}}
+	main := infra.Rosters["main"]
+	main.Users = append(main.Users, RosterUser{Name: "carol", InRotation: true})
+	infra.Rosters["main"] = main
+	f.addEvent(dto.ScheduleDTO{Username: "carol", Teamname: "infra", Role: "primary", StartTimeUnix: 4070995200, EndTimeUnix: 4071081600})
+	f.writes = nil
+
+	cfg := syncTestConfig()
+	// a new service is created before anything is deleted
+	cfg.Teams[0].Services = append(cfg.Teams[0].Services, "web")
+	report, err := c.Sync(ctx, cfg, SyncPrune())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"POST /api/v0/teams/infra/services",
+		"DELETE /api/v0/events/" + strconv.FormatInt(f.nextID, 10),
+		"DELETE /api/v0/teams/infra/rosters/main/users/carol",
+		"DELETE /api/v0/teams/infra/rosters/legacy",
+		"DELETE /api/v0/teams/infra/services/legacy-api",
+		"DELETE /api/v0/teams/infra/admins/carol",
+		"DELETE /api/v0/teams/infra/users/carol",
+	}
+	if !slices.Equal(f.writes, want) {
+		t.Errorf("writes:\n%s\nwant:\n%s", strings.Join(f.writes, "\n"), strings.Join(want, "\n"))
+	}
+	for _, ch := range report.Changes {
+		if !ch.Applied {
+			t.Errorf("change %+v not applied", ch)
+		}
+	}
+	if _, ok := f.users["carol"]; !ok {
+		t.Error("prune deleted a user, users are never deleted")
+	}
+}
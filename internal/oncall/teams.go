@@ -0,0 +1,245 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// teamDTO converts a configured team into the payload of the teams endpoints
+func teamDTO(t Team) dto.TeamCreateDTO {
+	data := dto.TeamCreateDTO{
+		Name:                t.Name,
+		Email:               t.Email,
+		SchedulingTimezone:  t.SchedulingTimezone,
+		SlackChannel:        t.SlackChannel,
+		OverridePhoneNumber: t.OverridePhoneNumber,
+		IrisPlan:            t.IrisPlan,
+		IrisEnabled:         t.IrisEnabled,
+		Description:         t.Description,
+		APIManagedRoster:    t.APIManagedRoster,
+	}
+	if t.SlackChannel != "" {
+		data.SlackChannelNotifications = t.SlackChannel + "-alert"
+	}
+	return data
+}
+
+// UpdateTeam changes the attributes of the existing team name to those set in t.
+// Setting t.Name renames the team.
+func (c *Client) UpdateTeam(ctx context.Context, name string, t Team) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "update_team").Str("team", name).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, name)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	data := teamDTO(t)
+	if data.Name == name {
+		data.Name = ""
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, data)
+}
+
+// GetTeam returns the full record of a team: its attributes, users, admins, rosters and services
+func (c *Client) GetTeam(ctx context.Context, name string) (*Response[TeamRecord], error) {
+	logger := c.logger.With().Str("action", "get_team").Str("team", name).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, name)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[TeamRecord](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// GetServices lists the services owned by a team
+func (c *Client) GetServices(ctx context.Context, team string) (*Response[[]string], error) {
+	logger := c.logger.With().Str("action", "get_services").Str("team", team).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "services")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[[]string](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// AddService makes a team the owner of a service. oncall creates the service if needed.
+func (c *Client) AddService(ctx context.Context, team, service string) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "add_service").Str("team", team).Str("service", service).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "services")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.ServiceDTO{Name: service})
+}
+
+// DeleteService removes a service from the services owned by a team
+func (c *Client) DeleteService(ctx context.Context, team, service string) error {
+	logger := c.logger.With().Str("action", "delete_service").Str("team", team).Str("service", service).Logger()
+	if err := c.guard.check("team", team); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
+	}
+	endpoint, err := c.endpoint(teamsEndpoint, team, "services", service)
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// GetAdmins lists the names of the admins of a team
+func (c *Client) GetAdmins(ctx context.Context, team string) (*Response[[]string], error) {
+	logger := c.logger.With().Str("action", "get_admins").Str("team", team).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "admins")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[[]string](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// AddAdmin makes a user an admin of a team
+func (c *Client) AddAdmin(ctx context.Context, team, user string) (*Response[any], error) {
+	logger := c.logger.With().Str("action", "add_admin").Str("team", team).Str("user", user).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "admins")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPost, endpoint, dto.AdminDTO{Name: user})
+}
+
+// DeleteAdmin revokes the admin rights of a user on a team, the user stays a member
+func (c *Client) DeleteAdmin(ctx context.Context, team, user string) error {
+	logger := c.logger.With().Str("action", "delete_admin").Str("team", team).Str("user", user).Logger()
+	if err := errors.Join(c.guard.check("team", team), c.guard.check("user", user)); err != nil {
+		logger.Warn().Err(err).Send()
+		return err
+	}
+	endpoint, err := c.endpoint(teamsEndpoint, team, "admins", user)
+	if err != nil {
+		return ErrInvalidEndpoint
+	}
+	_, err = doJSON[any](ctx, c, logger, http.MethodDelete, endpoint, nil)
+	return err
+}
+
+// ListTeams lists the names of the teams matching filter, the filters are applied by oncall
+func (c *Client) ListTeams(ctx context.Context, filter TeamFilter) (*Response[[]string], error) {
+	logger := c.logger.With().Str("action", "get_teams").Logger()
+	endpoint, err := c.endpoint(teamsEndpoint)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	q := url.Values{}
+	for param, v := range map[string]string{
+		"name":             filter.Name,
+		"name__contains":   filter.NameContains,
+		"name__startswith": filter.NamePrefix,
+		"name__endswith":   filter.NameSuffix,
+	} {
+		if v != "" {
+			q.Set(param, v)
+		}
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			q.Set("active", "1")
+		} else {
+			q.Set("active", "0")
+		}
+	}
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+	return doJSON[[]string](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// defaultTeamsPageSize is the page size of a TeamsIterator created with a size of 0 or less
+const defaultTeamsPageSize = 50
+
+// TeamLister lists team names, implemented by Client and mock.Client
+type TeamLister interface {
+	ListTeams(ctx context.Context, filter TeamFilter) (*Response[[]string], error)
+}
+
+// TeamsIterator walks the teams matching a filter one page at a time, so that callers can
+// process hundreds of teams incrementally and stop between pages. The paging is client-side
+// only, it does not make the listing itself smaller:
+//
+//	it := client.TeamsIterator(oncall.TeamFilter{NamePrefix: "payments-"}, 20)
+//	for it.Next(ctx) {
+//		for _, team := range it.Teams() { ... }
+//	}
+//	if err := it.Err(); err != nil { ... }
+//
+// oncall does not paginate the teams endpoint, the whole listing is fetched in one response on
+// the first call to Next and paged by the iterator. Narrow the filter to fetch fewer teams.
+type TeamsIterator struct {
+	lister   TeamLister
+	filter   TeamFilter
+	pageSize int
+
+	res   *Response[[]string]
+	names []string
+	page  []string
+	err   error
+}
+
+// NewTeamsIterator pages the teams listed by l, pageSize teams at a time
+func NewTeamsIterator(l TeamLister, filter TeamFilter, pageSize int) *TeamsIterator {
+	if pageSize <= 0 {
+		pageSize = defaultTeamsPageSize
+	}
+	return &TeamsIterator{lister: l, filter: filter, pageSize: pageSize}
+}
+
+// TeamsIterator returns an iterator over the teams matching filter, see NewTeamsIterator
+func (c *Client) TeamsIterator(filter TeamFilter, pageSize int) *TeamsIterator {
+	return NewTeamsIterator(c, filter, pageSize)
+}
+
+// Next advances to the next page, false once the teams are exhausted, ctx is done or the
+// listing failed. Only the first call requests oncall.
+func (it *TeamsIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if it.res == nil {
+		res, err := it.lister.ListTeams(ctx, it.filter)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if res == nil {
+			res = &Response[[]string]{}
+		}
+		it.res, it.names = res, res.Data
+	}
+	if len(it.names) == 0 {
+		it.page = nil
+		return false
+	}
+	n := min(it.pageSize, len(it.names))
+	it.page, it.names = it.names[:n], it.names[n:]
+	return true
+}
+
+// Teams returns the names of the current page
+func (it *TeamsIterator) Teams() []string {
+	return it.page
+}
+
+// Response returns the response of the listing, nil before the first call to Next or if
+// it failed
+func (it *TeamsIterator) Response() *Response[[]string] {
+	return it.res
+}
+
+// Err returns the error that stopped the iteration, nil if the teams were exhausted
+func (it *TeamsIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,93 @@
+package oncall
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// WithValues renders the config files as text/template templates of values before parsing
+// them, so that rotations, date ranges and name prefixes can be generated, e.g.
+//
+//	{{- range $i, $name := .users }}
+//	  - name: {{ $.prefix }}-{{ $name }}
+//	    duty:
+//	      - date: {{ addDays $.start (mul $i 7) }}
+//	{{- end }}
+//
+// The templates have access to the sprig function library, and to addDays and dutyDates
+// computing dates of duties. A nil values leaves the files as they are.
+func WithValues(values map[string]any) LoadOption {
+	return func(o *loadOptions) {
+		o.values = values
+	}
+}
+
+// ReadValues reads the values of the config templates from a config file, see WithValues
+func ReadValues(filename string) (map[string]any, error) {
+	b, err := ReadConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	if err = yaml.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return values, nil
+}
+
+// templateFuncs are the functions of the config templates in addition to sprig's
+var templateFuncs = template.FuncMap{
+	// addDays returns the duty date n days after date
+	"addDays": func(date string, n any) (string, error) {
+		d, err := time.Parse(DutyDateLayout, date)
+		if err != nil {
+			return "", err
+		}
+		// values are ints, the arithmetic of sprig returns int64
+		days := reflect.ValueOf(n)
+		if !days.CanInt() {
+			return "", fmt.Errorf("addDays: %v is not a number of days", n)
+		}
+		return d.AddDate(0, 0, int(days.Int())).Format(DutyDateLayout), nil
+	},
+	// dutyDates returns the duty dates from from to until included
+	"dutyDates": func(from, until string) ([]string, error) {
+		start, err := time.Parse(DutyDateLayout, from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.Parse(DutyDateLayout, until)
+		if err != nil {
+			return nil, err
+		}
+		var dates []string
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			dates = append(dates, d.Format(DutyDateLayout))
+		}
+		return dates, nil
+	},
+}
+
+// renderConfig executes the config file b of filename as a template of values. Missing
+// values fail the rendering.
+func renderConfig(b []byte, filename string, values map[string]any) ([]byte, error) {
+	t, err := template.New(filename).
+		Option("missingkey=error").
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(templateFuncs).
+		Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package oncall
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter caps the delay of a Retry-After header honored by the client
+const maxRetryAfter = time.Minute
+
+// throttle holds the requests back while oncall asks the client to slow down, and counts
+// the requests answered with 429 by endpoint
+type throttle struct {
+	mu     sync.Mutex
+	until  time.Time
+	counts map[string]int64
+}
+
+// wait blocks until the Retry-After delay of the last 429 response elapsed
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	delay := time.Until(t.until)
+	t.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe counts res if oncall rate limited req and holds the next requests back for its
+// Retry-After delay
+func (t *throttle) observe(req *http.Request, res *http.Response) {
+	if res == nil || res.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	now := time.Now()
+	delay := retryAfter(res, now)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counts == nil {
+		t.counts = make(map[string]int64)
+	}
+	t.counts[endpointName(req.URL.Path)]++
+	if until := now.Add(delay); until.After(t.until) {
+		t.until = until
+	}
+}
+
+func (t *throttle) snapshot() map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make(map[string]int64, len(t.counts))
+	for k, v := range t.counts {
+		res[k] = v
+	}
+	return res
+}
+
+// RateLimited returns the number of requests oncall answered with 429 Too Many Requests since
+// the client was created, keyed by endpoint: the resource after /api/v0, e.g. teams or
+// events, or login
+func (c *Client) RateLimited() map[string]int64 {
+	return c.throttle.snapshot()
+}
+
+// retryAfter returns the delay of the Retry-After header of a 429 or 503 response, given in
+// seconds or as an HTTP date, capped at maxRetryAfter. It is 0 for other responses.
+func retryAfter(res *http.Response, now time.Time) time.Duration {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	h := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if h == "" {
+		return 0
+	}
+	var d time.Duration
+	if s, err := strconv.Atoi(h); err == nil {
+		d = time.Duration(s) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		d = t.Sub(now)
+	}
+	return min(max(d, 0), maxRetryAfter)
+}
+
+// endpointName names the endpoint of path as RateLimited does
+func endpointName(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, s := range segments {
+		if s == "v0" && i+1 < len(segments) {
+			return segments[i+1]
+		}
+	}
+	return segments[len(segments)-1]
+}
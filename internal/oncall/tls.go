@@ -0,0 +1,81 @@
+package oncall
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// WithTLSConfig sets the TLS configuration used to reach an https oncall server.
+// The CA, certificate and skip verify options apply on top of it when passed after it.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tls = cfg.Clone()
+	}
+}
+
+// WithCAFile trusts the PEM certificates of file in addition to the system roots,
+// e.g. the internal CA oncall is served with
+func WithCAFile(file string) Option {
+	return func(c *Client) {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			c.optErrs = append(c.optErrs, fmt.Errorf("reading CA file: %w", err))
+			return
+		}
+		cfg := c.tlsConfig()
+		if cfg.RootCAs == nil {
+			if cfg.RootCAs, err = x509.SystemCertPool(); err != nil {
+				cfg.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(b) {
+			c.optErrs = append(c.optErrs, fmt.Errorf("no certificate found in CA file %s", file))
+		}
+	}
+}
+
+// WithClientCert presents the PEM certificate and key of certFile and keyFile to oncall
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *Client) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			c.optErrs = append(c.optErrs, fmt.Errorf("loading client certificate: %w", err))
+			return
+		}
+		cfg := c.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithInsecureSkipVerify accepts any certificate presented by oncall. It is meant for tests only.
+func WithInsecureSkipVerify() Option {
+	return func(c *Client) {
+		c.tlsConfig().InsecureSkipVerify = true
+	}
+}
+
+// tlsConfig returns the TLS configuration being built by the options
+func (c *Client) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return c.tls
+}
+
+// TLSOptions returns the TLS options matching the flags shared by the daemons, none if every
+// argument is empty
+func TLSOptions(caFile, certFile, keyFile string, insecure bool) []Option {
+	var opts []Option
+	if caFile != "" {
+		opts = append(opts, WithCAFile(caFile))
+	}
+	if certFile != "" || keyFile != "" {
+		opts = append(opts, WithClientCert(certFile, keyFile))
+	}
+	if insecure {
+		opts = append(opts, WithInsecureSkipVerify())
+	}
+	return opts
+}
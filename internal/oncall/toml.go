@@ -0,0 +1,130 @@
+package oncall
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// parseTOML decodes a TOML document into the yaml nodes it is equivalent to, so that TOML
+// configs are decoded and checked like the yaml ones. The keys keep the order of the
+// document, dates and times are kept as strings. TOML does not report the positions of its
+// keys, the schema errors of a TOML file are located by their path only.
+func parseTOML(src []byte) (*yaml.Node, error) {
+	var doc map[string]any
+	md, err := toml.Decode(string(src), &doc)
+	if err != nil {
+		return nil, err
+	}
+	// order maps a key, without the indices of the arrays of tables, to its first definition
+	order := make(map[string]int)
+	for i, k := range md.Keys() {
+		if _, found := order[k.String()]; !found {
+			order[k.String()] = i
+		}
+	}
+	root, err := tomlNode(doc, nil, order)
+	if err != nil {
+		return nil, err
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, nil
+}
+
+func tomlNode(v any, path toml.Key, order map[string]int) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		rank := func(k string) int {
+			if i, found := order[append(path[:len(path):len(path)], k).String()]; found {
+				return i
+			}
+			return math.MaxInt
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			if ra, rb := rank(a), rank(b); ra != rb {
+				return ra - rb
+			}
+			return strings.Compare(a, b)
+		})
+		m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			value, err := tomlNode(v[k], append(path[:len(path):len(path)], k), order)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+		}
+		return m, nil
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return tomlNode(items, path, order)
+	case []any:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			n, err := tomlNode(item, path, order)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, n)
+		}
+		return seq, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: tomlFloat(v)}, nil
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tomlTime(v)}, nil
+	}
+	return nil, fmt.Errorf("toml: %s: unsupported value %T", path, v)
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// tomlTime formats t as written in TOML, local dates and times without an offset
+func tomlTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format("2006-01-02")
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// lookup returns the value of key in the mapping m, nil if it is not set
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package oncall
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `
+name = "ops"
+retries = 3
+ratio = 0.5
+enabled = true
+
+[[teams]]
+name = "infra"
+scheduling_timezone = "Europe/Moscow"
+start = 2024-01-02
+at = 09:30:00
+since = 2024-01-02T09:30:00
+until = 2024-01-02T09:30:00Z
+
+  [[teams.users]]
+  name = "alice"
+  contacts = { email = "alice@example.com", call = "+1" }
+
+  [[teams.users]]
+  name = "bob"
+  duty = [{ date = "02/01/2024", role = "primary" }]
+
+[[teams]]
+name = "db"
+`
+	want := `name: ops
+retries: 3
+ratio: 0.5
+enabled: true
+teams:
+    - name: infra
+      scheduling_timezone: Europe/Moscow
+      start: "2024-01-02"
+      at: 09:30:00
+      since: 2024-01-02T09:30:00
+      until: "2024-01-02T09:30:00Z"
+      users:
+        - name: alice
+          contacts:
+            email: alice@example.com
+            call: "+1"
+        - name: bob
+          duty:
+            - date: 02/01/2024
+              role: primary
+    - name: db
+`
+	doc, err := parseTOML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("parseTOML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, src := range []string{
+		"name = ",
+		"name = \"a\"\nname = \"b\"",
+		"[teams]\n[teams]",
+		"name = \"unterminated",
+	} {
+		if _, err := parseTOML([]byte(src)); err == nil {
+			t.Errorf("parseTOML(%q): expected an error", src)
+		}
+	}
+}
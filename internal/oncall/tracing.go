@@ -0,0 +1,71 @@
+package oncall
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by the client
+const tracerName = "github.com/lordvidex/oncall-go-client/internal/oncall"
+
+// WithTracer creates a span for every call made to oncall with tp. The span covers every attempt,
+// login and replay of the call and records its method, path, status code and number of attempts.
+// The trace context is propagated to oncall in the traceparent header.
+func WithTracer(tp trace.TracerProvider) Option {
+	return func(c *Client) {
+		if tp == nil {
+			c.tracer = nil
+			return
+		}
+		c.tracer = tp.Tracer(tracerName)
+	}
+}
+
+type attemptsKey struct{}
+
+// countAttempt increments the attempts counter of a traced call
+func countAttempt(req *http.Request) {
+	if n, ok := req.Context().Value(attemptsKey{}).(*atomic.Int64); ok {
+		n.Add(1)
+	}
+}
+
+// doTraced sends req within a span when the client has a tracer
+func (c *Client) doTraced(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if c.tracer == nil {
+		return next(req)
+	}
+	ctx, span := c.tracer.Start(req.Context(), "oncall "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.path", req.URL.Path),
+			attribute.String("server.address", req.URL.Host),
+		),
+	)
+	defer span.End()
+
+	var attempts atomic.Int64
+	ctx = context.WithValue(ctx, attemptsKey{}, &attempts)
+	req = req.WithContext(ctx)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := next(req)
+	span.SetAttributes(attribute.Int64("oncall.attempts", attempts.Load()))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+	return res, nil
+}
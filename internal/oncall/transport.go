@@ -0,0 +1,83 @@
+package oncall
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WithProxy sends the requests to oncall through the HTTP proxy at proxyURL, e.g.
+// http://proxy.corp:3128. Without it the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables are honored.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) {
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Host == "" {
+			c.optErrs = append(c.optErrs, fmt.Errorf("invalid proxy url %q", proxyURL))
+			return
+		}
+		c.proxy = http.ProxyURL(u)
+	}
+}
+
+// WithTransport sets the transport the client is built on. It is cloned, and the TLS, proxy
+// and tuning options apply on top of it when passed after it.
+func WithTransport(t *http.Transport) Option {
+	return func(c *Client) {
+		c.transport = t.Clone()
+	}
+}
+
+// WithMaxIdleConnsPerHost keeps up to n idle connections to oncall open for reuse instead of
+// the 2 of net/http, so that frequent and concurrent calls skip the TCP and TLS handshakes
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.baseTransport().MaxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout closes the idle connections to oncall after d, 0 keeps them forever.
+// It should be longer than the interval between calls for connections to be reused.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.baseTransport().IdleConnTimeout = d
+	}
+}
+
+// WithoutHTTP2 speaks HTTP/1.1 to oncall even when the server negotiates HTTP/2
+func WithoutHTTP2() Option {
+	return func(c *Client) {
+		t := c.baseTransport()
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+}
+
+// baseTransport returns the transport being built by the options
+func (c *Client) baseTransport() *http.Transport {
+	if c.transport == nil {
+		c.transport = http.DefaultTransport.(*http.Transport).Clone()
+		c.transport.Proxy = http.ProxyFromEnvironment
+	}
+	return c.transport
+}
+
+// applyTransport installs the transport built by the transport, TLS and proxy options,
+// reporting their errors
+func (c *Client) applyTransport() error {
+	if err := errors.Join(c.optErrs...); err != nil {
+		return err
+	}
+	transport := c.baseTransport()
+	if c.proxy != nil {
+		transport.Proxy = c.proxy
+	}
+	if c.tls != nil {
+		transport.TLSClientConfig = c.tls
+	}
+	c.httpClient.Transport = transport
+	return nil
+}
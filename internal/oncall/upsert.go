@@ -0,0 +1,142 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// WithUpsert makes CreateEntities and CreateTeam reconcile the teams and users that already
+// exist instead of reporting a conflict: the existing entity is fetched and only the fields
+// that differ from the config are updated. Their steps are then reported as updated or
+// unchanged. Empty fields of the config are left as they are on the server.
+func WithUpsert() Option {
+	return func(c *Client) {
+		c.upsert = true
+	}
+}
+
+// upsertTeam updates the fields of the existing team t.Name that differ from t
+func (c *Client) upsertTeam(ctx context.Context, t Team) StepReport {
+	existing, err := c.GetTeam(ctx, t.Name)
+	if err != nil {
+		return newStep(existing, err)
+	}
+	changes := teamChanges(t, existing.Data)
+	if len(changes) == 0 {
+		return unchanged(existing)
+	}
+	return updated(c.updateFields(ctx, "upsert_team", changes, teamsEndpoint, t.Name))
+}
+
+// upsertUser creates u, or updates the fields of the existing user that differ from u.
+// A soft-deleted user is reactivated first.
+func (c *Client) upsertUser(ctx context.Context, u User) StepReport {
+	endpoint, err := c.endpoint(usersEndpoint)
+	if err != nil {
+		return batchStep(ErrInvalidEndpoint)
+	}
+	logger := c.logger.With().Str("user", u.Name).Str("action", "upsert_user").Logger()
+	created, err := doJSON[any](ctx, c, logger, http.MethodPost, endpoint, map[string]string{"name": u.Name})
+	if err == nil {
+		step := newStep(created, nil)
+		if _, err = c.UpdateUser(ctx, u.Name, u); err != nil {
+			step.Outcome, step.Err = OutcomeFailed, err
+		}
+		return step
+	}
+	if !errors.Is(err, ErrConflict) {
+		return newStep(created, err)
+	}
+
+	existing, err := c.GetUser(ctx, u.Name)
+	if err != nil {
+		return newStep(existing, err)
+	}
+	reactivated := false
+	if !existing.Data.Active {
+		if res, err := c.ReactivateUser(ctx, u.Name); err != nil {
+			return newStep(res, err)
+		}
+		reactivated = true
+	}
+	changes := userChanges(u, existing.Data)
+	if len(changes) == 0 {
+		if reactivated {
+			return StepReport{Outcome: OutcomeUpdated, StatusCode: existing.StatusCode}
+		}
+		return unchanged(existing)
+	}
+	return updated(c.updateFields(ctx, "upsert_user", changes, usersEndpoint, u.Name))
+}
+
+// updateFields sends a PUT of the changed fields to the entity at elem
+func (c *Client) updateFields(ctx context.Context, action string, changes map[string]any, elem ...string) (*Response[any], error) {
+	logger := c.logger.With().Str("action", action).Strs("entity", elem[1:]).Logger()
+	endpoint, err := c.endpoint(elem[0], elem[1:]...)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	logger.Debug().Interface("changes", changes).Msg("updating changed fields")
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, changes)
+}
+
+func unchanged[T any](res *Response[T]) StepReport {
+	step := newStep(res, nil)
+	step.Outcome = OutcomeUnchanged
+	return step
+}
+
+func updated(res *Response[any], err error) StepReport {
+	step := newStep(res, err)
+	if err == nil {
+		step.Outcome = OutcomeUpdated
+	}
+	return step
+}
+
+// teamChanges returns the fields of t that differ from the existing team, keyed by their
+// json name. Empty strings and unset flags of t are not changes.
+func teamChanges(t Team, have TeamRecord) map[string]any {
+	want := teamDTO(t)
+	changes := make(map[string]any)
+	str := func(key, w, h string) {
+		if w != "" && w != h {
+			changes[key] = w
+		}
+	}
+	str("email", want.Email, have.Email)
+	str("scheduling_timezone", want.SchedulingTimezone, have.SchedulingTimezone)
+	str("slack_channel", want.SlackChannel, have.SlackChannel)
+	str("slack_channel_notifications", want.SlackChannelNotifications, have.SlackChannelNotifications)
+	str("override_phone_number", want.OverridePhoneNumber, have.OverridePhoneNumber)
+	str("iris_plan", want.IrisPlan, have.IrisPlan)
+	str("description", want.Description, have.Description)
+	if want.IrisEnabled != nil && *want.IrisEnabled != have.IrisEnabled {
+		changes["iris_enabled"] = *want.IrisEnabled
+	}
+	if want.APIManagedRoster != nil && *want.APIManagedRoster != have.APIManagedRoster {
+		changes["api_managed_roster"] = *want.APIManagedRoster
+	}
+	return changes
+}
+
+// userChanges returns the fields of u that differ from the existing user, keyed by their
+// json name. Empty fields of u are not changes.
+func userChanges(u User, have UserRecord) map[string]any {
+	changes := make(map[string]any)
+	if u.FullName != "" && u.FullName != have.FullName {
+		changes["full_name"] = u.FullName
+	}
+	contacts := make(map[string]string)
+	if u.PhoneNumber != "" && u.PhoneNumber != have.Contacts.Call {
+		contacts["call"] = u.PhoneNumber
+	}
+	if u.Email != "" && u.Email != have.Contacts.Email {
+		contacts["email"] = u.Email
+	}
+	if len(contacts) > 0 {
+		changes["contacts"] = contacts
+	}
+	return changes
+}
@@ -0,0 +1,117 @@
+package oncall
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/lordvidex/oncall-go-client/internal/oncall/dto"
+)
+
+// GetUsers lists the users of the oncall server matching filter.
+// oncall has no team filter on the users endpoint, so Team is resolved against the team's members.
+func (c *Client) GetUsers(ctx context.Context, filter UserFilter) (*Response[[]UserRecord], error) {
+	logger := c.logger.With().Str("action", "get_users").Logger()
+	endpoint, err := c.endpoint(usersEndpoint)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+
+	var members map[string]bool
+	if filter.Team != "" {
+		names, err := c.getTeamUsers(ctx, filter.Team)
+		if err != nil {
+			return nil, err
+		}
+		members = make(map[string]bool, len(names))
+		for _, n := range names {
+			members[n] = true
+		}
+	}
+
+	q := url.Values{}
+	if filter.Name != "" {
+		q.Set("name", filter.Name)
+	}
+	if filter.NameContains != "" {
+		q.Set("name__contains", filter.NameContains)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			q.Set("active", "1")
+		} else {
+			q.Set("active", "0")
+		}
+	}
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+
+	result, err := doJSON[[]UserRecord](ctx, c, logger, http.MethodGet, endpoint, nil)
+	if err != nil || members == nil {
+		return result, err
+	}
+	users := result.Data
+	result.Data = nil
+	for _, u := range users {
+		if members[u.Name] {
+			result.Data = append(result.Data, u)
+		}
+	}
+	return result, nil
+}
+
+// GetUser returns the full record of a single user
+func (c *Client) GetUser(ctx context.Context, name string) (*Response[UserRecord], error) {
+	logger := c.logger.With().Str("action", "get_user").Str("user", name).Logger()
+	endpoint, err := c.endpoint(usersEndpoint, name)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	return doJSON[UserRecord](ctx, c, logger, http.MethodGet, endpoint, nil)
+}
+
+// UpdateUser replaces the full name and contacts of an existing user with those of u
+func (c *Client) UpdateUser(ctx context.Context, name string, u User) (*Response[any], error) {
+	logger := c.logger.With().Str("user", name).Str("action", "update_user").Logger()
+	logger.Debug().Msg("updating user data")
+	endpoint, err := c.endpoint(usersEndpoint, name)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	data := dto.UserCreateDTO{
+		Name:     u.Name,
+		FullName: u.FullName,
+		Contacts: dto.ContactsDTO{
+			Call:  u.PhoneNumber,
+			Email: u.Email,
+		},
+	}
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, data)
+}
+
+// getTeamUsers returns the names of the members of a team
+func (c *Client) getTeamUsers(ctx context.Context, team string) ([]string, error) {
+	logger := c.logger.With().Str("action", "get_team_users").Str("team", team).Logger()
+	endpoint, err := c.endpoint(teamsEndpoint, team, "users")
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	res, err := doJSON[[]string](ctx, c, logger, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// ReactivateUser sets the active flag of a user that oncall soft-deleted,
+// making the user available to teams and rosters again
+func (c *Client) ReactivateUser(ctx context.Context, name string) (*Response[any], error) {
+	logger := c.logger.With().Str("user", name).Str("action", "reactivate_user").Logger()
+	endpoint, err := c.endpoint(usersEndpoint, name)
+	if err != nil {
+		return nil, ErrInvalidEndpoint
+	}
+	active := true
+	return doJSON[any](ctx, c, logger, http.MethodPut, endpoint, dto.UserCreateDTO{Active: &active})
+}
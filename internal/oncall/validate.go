@@ -0,0 +1,234 @@
+package oncall
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DutyDateLayout is the layout of Duty.Date, day first
+const DutyDateLayout = "02/01/2006"
+
+// DutyTimeLayout is the layout of Duty.StartTime and Duty.EndTime
+const DutyTimeLayout = "15:04"
+
+// Validate checks cfg before anything is sent to oncall: teams and users need a name, team
+// names are unique, scheduling timezones are IANA locations and schedules pass ValidateSchedule
+// apart from its warnings. Every problem is returned in a *MultiError whose entries match
+// ErrInvalidRequest.
+func (cfg Config) Validate() error {
+	var errs MultiError
+	seen := make(map[string]bool)
+	invalid := func(kind, name, team, format string, args ...any) {
+		errs.Add("validate", kind, name, team, fmt.Errorf("%w: "+format, append([]any{ErrInvalidRequest}, args...)...))
+	}
+	for i, t := range cfg.Teams {
+		if t.Name == "" {
+			invalid("team", fmt.Sprintf("#%d", i), "", "team without name")
+			continue
+		}
+		if seen[t.Name] {
+			invalid("team", t.Name, t.Name, "duplicate team")
+		}
+		seen[t.Name] = true
+		if t.SchedulingTimezone != "" {
+			if _, err := time.LoadLocation(t.SchedulingTimezone); err != nil {
+				invalid("team", t.Name, t.Name, "unknown scheduling timezone %q", t.SchedulingTimezone)
+			}
+		}
+		for j, u := range t.Users {
+			if u.Name == "" {
+				invalid("user", fmt.Sprintf("#%d", j), t.Name, "user without name")
+				continue
+			}
+			for _, issue := range ValidateSchedule(u.Name, t.Name, t.SchedulingTimezone, u.Schedule) {
+				if !issue.Warning {
+					errs.addDetail("validate", "event", u.Name, t.Name, issue.Date, fmt.Errorf("%w: %s", ErrInvalidRequest, issue.Message))
+				}
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// ValidateRoles checks that every role of cfg is one of roles, those oncall knows as returned
+// by Client.Roles: the roles of the duties, roster schedules, notifications and expected
+// on-call users. oncall accepts events of any role, but a misspelled role is never counted as
+// on call. Every unknown role is returned in a *MultiError whose entries match ErrInvalidRequest.
+func (cfg Config) ValidateRoles(roles []string) error {
+	var errs MultiError
+	unknown := func(kind, name, team, detail, role string) {
+		if role == "" || slices.Contains(roles, role) {
+			return
+		}
+		errs.addDetail("validate", kind, name, team, detail,
+			fmt.Errorf("%w: unknown role %q, oncall knows %s", ErrInvalidRequest, role, strings.Join(roles, ", ")))
+	}
+	for _, t := range cfg.Teams {
+		for _, role := range sortedKeys(t.ExpectOnCall) {
+			unknown("team", t.Name, t.Name, "expect_on_call", role)
+		}
+		for _, u := range t.Users {
+			for _, d := range u.Schedule {
+				unknown("event", u.Name, t.Name, d.Date, d.Role)
+				for _, role := range d.Roles {
+					unknown("event", u.Name, t.Name, d.Date, role)
+				}
+			}
+			for _, n := range u.Notifications {
+				for _, role := range n.Roles {
+					unknown("notification", u.Name, t.Name, "", role)
+				}
+			}
+		}
+		for _, r := range t.Rosters {
+			for _, sc := range r.Schedules {
+				unknown("schedule", r.Name, t.Name, "", sc.Role)
+			}
+		}
+	}
+	return errs.Err()
+}
+
+// IssueKind classifies a problem found by ValidateSchedule
+type IssueKind string
+
+const (
+	// IssueInvalidDate is a date not formatted as DutyDateLayout
+	IssueInvalidDate IssueKind = "invalid_date"
+	// IssueMissingRole is a duty without role
+	IssueMissingRole IssueKind = "missing_role"
+	// IssueDuplicateDay is a day, or a shift of a day, listed by several duties, its roles
+	// belong in a single duty
+	IssueDuplicateDay IssueKind = "duplicate_day"
+	// IssueOverlappingRoles is a role held twice on the same day
+	IssueOverlappingRoles IssueKind = "overlapping_roles"
+	// IssuePastDate is a day before the current UTC day, a warning as oncall accepts it
+	IssuePastDate IssueKind = "past_date"
+	// IssueMixedTimezones is a date carrying a timezone. Duties are days of the scheduling
+	// timezone of the team or of their timezone, so a zone in the date is ignored at best and
+	// the dates of a schedule written in several zones are off by a day.
+	IssueMixedTimezones IssueKind = "mixed_timezones"
+	// IssueInvalidShift is a duty whose start_time, end_time, duration or timezone is invalid
+	IssueInvalidShift IssueKind = "invalid_shift"
+	// IssueInvalidRecurrence is a duty whose every, repeat, weekly_rotation or until is invalid
+	IssueInvalidRecurrence IssueKind = "invalid_recurrence"
+)
+
+// Issue is a problem of the schedule of a user
+type Issue struct {
+	Kind IssueKind `json:"kind"`
+	User string    `json:"user"`
+	Team string    `json:"team"`
+	Date string    `json:"date"`
+	Role string    `json:"role,omitempty"`
+	// Message explains the issue and how to fix it
+	Message string `json:"message"`
+	// Warning is set for issues that do not prevent creating the schedule
+	Warning bool `json:"warning,omitempty"`
+}
+
+func (i Issue) Error() string {
+	return fmt.Sprintf("%s of %s in %s on %s: %s", i.Kind, i.User, i.Team, i.Date, i.Message)
+}
+
+// zonedDateLayouts are dates with a timezone that ValidateSchedule recognizes as such
+var zonedDateLayouts = []string{time.RFC3339, DutyDateLayout + " -07:00", DutyDateLayout + " MST", DutyDateLayout + "Z07:00"}
+
+// ValidateSchedule checks the duties of user in team, whose scheduling timezone is timezone,
+// without contacting oncall: dates must be formatted as DutyDateLayout without a timezone,
+// shift times and timezones valid, each shift listed once with every role held by a single
+// shift at a time, and duties should not be in the past. Recurring duties are checked on each
+// day they recur on. The issues are in the order of the duties.
+func ValidateSchedule(user, team, timezone string, duties []Duty) []Issue {
+	var issues []Issue
+	add := func(kind IssueKind, date, role, format string, args ...any) {
+		issues = append(issues, Issue{
+			Kind:    kind,
+			User:    user,
+			Team:    team,
+			Date:    date,
+			Role:    role,
+			Message: fmt.Sprintf(format, args...),
+			Warning: kind == IssuePastDate,
+		})
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// shifts listed by the duties and held by each role
+	listed := make(map[shift]bool)
+	held := make(map[string][]shift)
+	zones := make(map[string]bool)
+	expanded := make([]Duty, 0, len(duties))
+	for _, d := range duties {
+		if !d.recurs() && d.Until == "" {
+			expanded = append(expanded, d)
+			continue
+		}
+		occurrences, err := d.Occurrences()
+		if err != nil {
+			add(IssueInvalidRecurrence, d.Date, d.Role, "%s", err)
+			continue
+		}
+		expanded = append(expanded, occurrences...)
+	}
+	for _, d := range expanded {
+		day, err := time.Parse(DutyDateLayout, d.Date)
+		if err != nil {
+			zoned := false
+			for _, layout := range zonedDateLayouts {
+				if t, err := time.Parse(layout, d.Date); err == nil {
+					name, offset := t.Zone()
+					zones[fmt.Sprintf("%s%d", name, offset)] = true
+					zoned = true
+					break
+				}
+			}
+			if zoned {
+				add(IssueMixedTimezones, d.Date, d.Role, "date %q carries a timezone, write it as dd/mm/yyyy and set the timezone of the duty instead", d.Date)
+			} else {
+				add(IssueInvalidDate, d.Date, d.Role, "date %q is not formatted as dd/mm/yyyy", d.Date)
+			}
+			continue
+		}
+		start, end, err := d.Shift(timezone)
+		if err != nil {
+			add(IssueInvalidShift, d.Date, d.Role, "%s", err)
+			continue
+		}
+		roles := d.Expand()
+		if len(roles) == 0 {
+			add(IssueMissingRole, d.Date, "", "duty without role, set role or roles")
+		}
+		if day.Before(today) {
+			add(IssuePastDate, d.Date, d.Role, "duty of %s is in the past", d.Date)
+		}
+		s := shift{start, end}
+		if listed[s] {
+			add(IssueDuplicateDay, d.Date, d.Role, "%s is listed by several duties, list its roles in a single duty", d.Date)
+		}
+		listed[s] = true
+		if d.Role != "" && slices.Contains(d.Roles, d.Role) {
+			add(IssueOverlappingRoles, d.Date, d.Role, "role %s is both the role and one of the roles of %s", d.Role, d.Date)
+		}
+		for _, r := range roles {
+			if slices.ContainsFunc(held[r.Role], s.overlaps) {
+				add(IssueOverlappingRoles, d.Date, r.Role, "role %s is held twice on %s", r.Role, d.Date)
+			}
+			held[r.Role] = append(held[r.Role], s)
+		}
+	}
+	if len(zones) > 1 {
+		add(IssueMixedTimezones, "", "", "dates are written in %d different timezones", len(zones))
+	}
+	return issues
+}
+
+// shift is the time range of a duty
+type shift struct {
+	start, end time.Time
+}
+
+func (s shift) overlaps(o shift) bool {
+	return s.start.Before(o.end) && o.start.Before(s.end)
+}
@@ -0,0 +1,91 @@
+// Package probe defines the versioned result of a probe cycle published by the prober, so that
+// downstream consumers keep working as scenarios and fields are added
+package probe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of ProbeResult, major.minor. The minor version is bumped when
+// fields or scenarios are added, consumers must ignore the fields and scenarios they do not
+// know. The major version is bumped when fields are removed or change meaning.
+const SchemaVersion = "1.0"
+
+// schemaMajor is the major version of SchemaVersion, the only one Decode accepts
+const schemaMajor = 1
+
+// Statuses of a scenario in a cycle
+const (
+	// StatusOK is a scenario whose runs all succeeded
+	StatusOK = "ok"
+	// StatusFailed is a scenario with at least a failed run
+	StatusFailed = "failed"
+	// StatusThrottled is a scenario whose runs were all rate limited by oncall, not
+	// accounted for in the SLA
+	StatusThrottled = "throttled"
+)
+
+// ProbeResult is the outcome of the scenarios of a probe cycle
+type ProbeResult struct {
+	SchemaVersion string `json:"schema_version"`
+	// RunID identifies the cycle, as in the logs and the evidence of the prober
+	RunID string `json:"run_id"`
+	// Instance is the prober that ran the cycle
+	Instance string    `json:"instance,omitempty"`
+	Start    time.Time `json:"start"`
+	// DurationSeconds is the time the cycle took
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Scenarios are sorted by name, a scenario that did not run in the cycle is absent
+	Scenarios []ScenarioResult `json:"scenarios"`
+}
+
+// ScenarioResult counts the runs of a scenario in a cycle. Throttled runs are not part of Runs.
+type ScenarioResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Runs      int    `json:"runs"`
+	Successes int    `json:"successes"`
+	Throttled int    `json:"throttled"`
+	// SuccessRate is Successes divided by Runs, absent without runs
+	SuccessRate *float64 `json:"success_rate,omitempty"`
+}
+
+// NewScenarioResult returns the result of a scenario with its status and success rate
+func NewScenarioResult(name string, runs, successes, throttled int) ScenarioResult {
+	r := ScenarioResult{Name: name, Runs: runs, Successes: successes, Throttled: throttled}
+	switch {
+	case runs == 0:
+		r.Status = StatusThrottled
+	case successes < runs:
+		r.Status = StatusFailed
+	default:
+		r.Status = StatusOK
+	}
+	if runs > 0 {
+		rate := float64(successes) / float64(runs)
+		r.SuccessRate = &rate
+	}
+	return r
+}
+
+// Decode decodes a ProbeResult of any minor version of the supported major version, the
+// fields added by newer minor versions are ignored
+func Decode(b []byte) (*ProbeResult, error) {
+	var r ProbeResult
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	if r.SchemaVersion == "" {
+		return nil, errors.New("probe result without schema_version")
+	}
+	major, _, _ := strings.Cut(r.SchemaVersion, ".")
+	if v, err := strconv.Atoi(major); err != nil || v != schemaMajor {
+		return nil, fmt.Errorf("unsupported probe result schema_version %q, expected %d.x", r.SchemaVersion, schemaMajor)
+	}
+	return &r, nil
+}
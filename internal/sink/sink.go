@@ -0,0 +1,62 @@
+// Package sink emits the metrics of a prometheus registry to backends other than a scrape endpoint
+package sink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+)
+
+// Sink receives the metric families of a registry every flush interval
+type Sink interface {
+	Flush(families []*dto.MetricFamily) error
+}
+
+// Run gathers g every interval and flushes the result to s until ctx is done
+func Run(ctx context.Context, g prometheus.Gatherer, s Sink, interval time.Duration, logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			families, err := g.Gather()
+			if err != nil {
+				logger.Warn().Err(err).Msg("error gathering metrics")
+			}
+			if err = s.Flush(families); err != nil {
+				logger.Warn().Err(err).Msg("error flushing metrics")
+			}
+		}
+	}
+}
+
+// New returns the sink selected by kind: "statsd" or "dogstatsd", sending to addr.
+// "prometheus" returns a nil sink as metrics are only scraped.
+func New(kind, addr, prefix string) (Sink, error) {
+	switch kind {
+	case "", "prometheus":
+		return nil, nil
+	case "statsd":
+		return NewStatsD(addr, prefix, false)
+	case "dogstatsd":
+		return NewStatsD(addr, prefix, true)
+	}
+	return nil, fmt.Errorf("unknown metrics sink %q", kind)
+}
+
+// Start pushes the default registry to the sink selected by kind in the background.
+// Nothing is started for "prometheus".
+func Start(ctx context.Context, kind, addr, prefix string, interval time.Duration, logger zerolog.Logger) error {
+	s, err := New(kind, addr, prefix)
+	if err != nil || s == nil {
+		return err
+	}
+	go Run(ctx, prometheus.DefaultGatherer, s, interval, logger)
+	return nil
+}
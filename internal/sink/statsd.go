@@ -0,0 +1,146 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxPacket keeps datagrams below the usual MTU
+const maxPacket = 1432
+
+// StatsD sends gauges as is and counters as deltas since the previous flush.
+// Histograms and summaries are sent as the deltas of their count and sum.
+// With DogStatsD, labels become tags; otherwise their values are appended to the metric name.
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	// last holds the previous value of every counter series
+	last map[string]float64
+}
+
+// NewStatsD creates a sink sending UDP datagrams to addr
+func NewStatsD(addr, prefix string, dogstatsd bool) (*StatsD, error) {
+	if addr == "" {
+		return nil, errors.New("statsd address must be set")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsD{conn: conn, prefix: prefix, dogstatsd: dogstatsd, last: make(map[string]float64)}, nil
+}
+
+func (s *StatsD) Flush(families []*dto.MetricFamily) error {
+	var lines []string
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			name, tags := s.series(f.GetName(), m.GetLabel())
+			switch f.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = s.count(lines, name, tags, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				lines = append(lines, s.line(name, tags, m.GetGauge().GetValue(), "g"))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, s.line(name, tags, m.GetUntyped().GetValue(), "g"))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				lines = s.count(lines, name+".count", tags, float64(h.GetSampleCount()))
+				lines = s.count(lines, name+".sum", tags, h.GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				sm := m.GetSummary()
+				lines = s.count(lines, name+".count", tags, float64(sm.GetSampleCount()))
+				lines = s.count(lines, name+".sum", tags, sm.GetSampleSum())
+			}
+		}
+	}
+	return s.send(lines)
+}
+
+// count appends the delta of a counter since the last flush. The first flush only records the value.
+func (s *StatsD) count(lines []string, name, tags string, v float64) []string {
+	key := name + "|" + tags
+	prev, seen := s.last[key]
+	s.last[key] = v
+	if !seen || v < prev {
+		// counters that reset start a new baseline
+		return lines
+	}
+	if v == prev {
+		return lines
+	}
+	return append(lines, s.line(name, tags, v-prev, "c"))
+}
+
+func (s *StatsD) series(name string, labels []*dto.LabelPair) (string, string) {
+	if s.prefix != "" {
+		name = s.prefix + "." + name
+	}
+	pairs := make([]*dto.LabelPair, len(labels))
+	copy(pairs, labels)
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+	if !s.dogstatsd {
+		for _, l := range pairs {
+			name += "." + sanitize(l.GetValue())
+		}
+		return name, ""
+	}
+	tags := make([]string, len(pairs))
+	for i, l := range pairs {
+		tags[i] = l.GetName() + ":" + sanitize(l.GetValue())
+	}
+	return name, strings.Join(tags, ",")
+}
+
+func (s *StatsD) line(name, tags string, v float64, kind string) string {
+	line := name + ":" + strconv.FormatFloat(v, 'f', -1, 64) + "|" + kind
+	if tags != "" {
+		line += "|#" + tags
+	}
+	return line
+}
+
+// send writes lines in as few datagrams as possible
+func (s *StatsD) send(lines []string) error {
+	var (
+		buf  bytes.Buffer
+		errs []error
+	)
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		if _, err := s.conn.Write(buf.Bytes()); err != nil {
+			errs = append(errs, err)
+		}
+		buf.Reset()
+	}
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(l) > maxPacket {
+			flush()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(l)
+	}
+	flush()
+	return errors.Join(errs...)
+}
+
+// sanitize replaces the characters with a meaning in the statsd protocol
+func sanitize(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '#', '@', ' ', '\n', '/':
+			return '_'
+		}
+		return r
+	}, v)
+}
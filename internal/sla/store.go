@@ -0,0 +1,229 @@
+// Package sla stores SLA records shared by the checker and the prober
+package sla
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+
+	"github.com/lordvidex/oncall-go-client/migrations"
+)
+
+// Store writes SLA records into the sla_record table
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// Migrate applies the sla_record migrations to the database
+func Migrate(databaseURL string) error {
+	goose.SetBaseFS(migrations.FS)
+	if err := goose.SetDialect("pgx"); err != nil {
+		return err
+	}
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	return goose.Up(db, ".")
+}
+
+// New connects to the database. Migrations are not run, see Migrate.
+func New(ctx context.Context, databaseURL string) (*Store, error) {
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{pool: pool}, nil
+}
+
+// Insert records the value of an SLI together with its objective
+func (s *Store) Insert(ctx context.Context, alias, metric string, slo, value float64, slaMet bool) error {
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO sla_record (alias, metric, slo, value, met) 
+VALUES ($1, $2, $3, $4, $5)`,
+		alias,
+		metric,
+		slo,
+		value,
+		slaMet,
+	)
+	return err
+}
+
+// ReplaceRange replaces the records of alias between from and to (inclusive) with records in
+// a single transaction, so that a failed insert leaves the stored records as they were
+func (s *Store) ReplaceRange(ctx context.Context, alias string, from, to time.Time, records []Record) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(
+		ctx,
+		`DELETE FROM sla_record WHERE alias = $1 AND datetime BETWEEN $2 AND $3`,
+		alias,
+		from,
+		to,
+	)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO sla_record (datetime, alias, metric, slo, value, met) 
+VALUES ($1, $2, $3, $4, $5, $6)`,
+			r.Time,
+			r.Alias,
+			r.Metric,
+			r.SLO,
+			r.Value,
+			r.Met,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+// Close closes the connections to the database
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// Record is a stored SLI evaluation
+type Record struct {
+	Time   time.Time `json:"time"`
+	Alias  string    `json:"alias"`
+	Metric string    `json:"metric"`
+	SLO    float64   `json:"slo"`
+	Value  float64   `json:"value"`
+	Met    bool      `json:"met"`
+}
+
+// Records returns the records of alias between from and to (inclusive), oldest first
+func (s *Store) Records(ctx context.Context, alias string, from, to time.Time) ([]Record, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT datetime, alias, metric, slo, value, met FROM sla_record
+WHERE alias = $1 AND datetime BETWEEN $2 AND $3 ORDER BY datetime`,
+		alias,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r        Record
+			slo, val float32
+		)
+		if err = rows.Scan(&r.Time, &r.Alias, &r.Metric, &slo, &val, &r.Met); err != nil {
+			return nil, err
+		}
+		r.SLO, r.Value = float64(slo), float64(val)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Counts returns the number of records of alias since the given time and how many of them met their objective
+func (s *Store) Counts(ctx context.Context, alias string, since time.Time) (met, total int64, err error) {
+	err = s.pool.QueryRow(
+		ctx,
+		`SELECT COUNT(*) FILTER (WHERE met), COUNT(*) FROM sla_record WHERE alias = $1 AND datetime >= $2`,
+		alias,
+		since,
+	).Scan(&met, &total)
+	return met, total, err
+}
+
+// Baseline compares the latency records of a week with the records of the weeks before it
+type Baseline struct {
+	// Week is the start of the compared week
+	Week            time.Time `json:"week"`
+	Alias           string    `json:"alias"`
+	Samples         int       `json:"samples"`
+	BaselineSamples int       `json:"baseline_samples"`
+	P50             float64   `json:"p50"`
+	P90             float64   `json:"p90"`
+	P99             float64   `json:"p99"`
+	BaselineP50     float64   `json:"baseline_p50"`
+	BaselineP90     float64   `json:"baseline_p90"`
+	BaselineP99     float64   `json:"baseline_p99"`
+	// PValue is the probability of latencies at least this much higher than the baseline by chance
+	PValue     float64 `json:"p_value"`
+	Regression bool    `json:"regression"`
+}
+
+// InsertBaseline records b, replacing the baseline already stored for its alias and week
+func (s *Store) InsertBaseline(ctx context.Context, b Baseline) error {
+	_, err := s.pool.Exec(
+		ctx,
+		`INSERT INTO latency_baseline (week, alias, samples, baseline_samples, p50, p90, p99,
+baseline_p50, baseline_p90, baseline_p99, p_value, regression)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (alias, week) DO UPDATE SET samples = EXCLUDED.samples,
+baseline_samples = EXCLUDED.baseline_samples, p50 = EXCLUDED.p50, p90 = EXCLUDED.p90,
+p99 = EXCLUDED.p99, baseline_p50 = EXCLUDED.baseline_p50, baseline_p90 = EXCLUDED.baseline_p90,
+baseline_p99 = EXCLUDED.baseline_p99, p_value = EXCLUDED.p_value, regression = EXCLUDED.regression`,
+		b.Week,
+		b.Alias,
+		b.Samples,
+		b.BaselineSamples,
+		b.P50,
+		b.P90,
+		b.P99,
+		b.BaselineP50,
+		b.BaselineP90,
+		b.BaselineP99,
+		b.PValue,
+		b.Regression,
+	)
+	return err
+}
+
+// Baselines returns the baselines of alias for the weeks starting since the given time, oldest first
+func (s *Store) Baselines(ctx context.Context, alias string, since time.Time) ([]Baseline, error) {
+	rows, err := s.pool.Query(
+		ctx,
+		`SELECT week, alias, samples, baseline_samples, p50, p90, p99,
+baseline_p50, baseline_p90, baseline_p99, p_value, regression FROM latency_baseline
+WHERE alias = $1 AND week >= $2 ORDER BY week`,
+		alias,
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var baselines []Baseline
+	for rows.Next() {
+		var b Baseline
+		err = rows.Scan(&b.Week, &b.Alias, &b.Samples, &b.BaselineSamples, &b.P50, &b.P90, &b.P99,
+			&b.BaselineP50, &b.BaselineP90, &b.BaselineP99, &b.PValue, &b.Regression)
+		if err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, b)
+	}
+	return baselines, rows.Err()
+}
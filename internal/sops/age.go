@@ -0,0 +1,73 @@
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageVersion is the first line of a binary age file
+const ageVersion = "age-encryption.org/v1"
+
+// ErrNoIdentity is returned when no identity can decrypt a file
+var ErrNoIdentity = errors.New("no age identity matches the recipients of the file")
+
+// Identity is an age identity, e.g. an AGE-SECRET-KEY-1 line of an age key file
+type Identity = age.Identity
+
+// ParseIdentity parses an AGE-SECRET-KEY-1 secret key
+func ParseIdentity(s string) (Identity, error) {
+	id, err := age.ParseX25519Identity(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age secret key: %w", err)
+	}
+	return id, nil
+}
+
+// ParseIdentities parses the identities of an age key file, skipping blank and comment lines
+func ParseIdentities(r io.Reader) ([]Identity, error) {
+	var ids []Identity
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, scanner.Err()
+}
+
+// IsAge reports whether b is an age encrypted file, binary or armored
+func IsAge(b []byte) bool {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	return bytes.HasPrefix(b, []byte(ageVersion+"\n")) || bytes.HasPrefix(b, []byte(armor.Header))
+}
+
+// DecryptAge decrypts the age encrypted file b, binary or armored, with the first of ids it
+// is encrypted to
+func DecryptAge(b []byte, ids []Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(b)
+	if t := bytes.TrimLeft(b, " \t\r\n"); bytes.HasPrefix(t, []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(t))
+	}
+	r, err := age.Decrypt(src, ids...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, ErrNoIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
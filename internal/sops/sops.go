@@ -0,0 +1,305 @@
+// Package sops decrypts the yaml files encrypted by SOPS with age keys, and the files encrypted
+// with age itself, so that configs holding contact details and credentials can be kept in git
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// metadataKey is the top level key SOPS stores its metadata under
+const metadataKey = "sops"
+
+// macOnlyEncryptedInit starts the MAC of the files encrypted with --mac-only-encrypted, so that
+// it differs from the MAC of the same values authenticated with the plaintext ones
+var macOnlyEncryptedInit = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0xb, 0xb, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+// metadata is the part of the SOPS metadata needed to decrypt and authenticate the values
+type metadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+// IsEncrypted reports whether the yaml document root carries SOPS metadata
+func IsEncrypted(root *yaml.Node) bool {
+	_, meta := metadataNode(root)
+	return meta != nil
+}
+
+func metadataNode(root *yaml.Node) (*yaml.Node, *yaml.Node) {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == metadataKey && n.Content[i+1].Kind == yaml.MappingNode {
+			return n, n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// DecryptNode decrypts in place the values of the SOPS encrypted document root with the data
+// key of its age recipients, and removes the SOPS metadata. Encrypted comments are dropped.
+//
+// Every value is authenticated with its path by AES-GCM, and the file by the MAC SOPS computes
+// over all its values, the plaintext ones included unless the file was encrypted with
+// --mac-only-encrypted. A file whose values were added, removed or modified since it was
+// encrypted fails with a MAC mismatch.
+func DecryptNode(root *yaml.Node, ids []Identity) error {
+	doc, metaNode := metadataNode(root)
+	if metaNode == nil {
+		return errors.New("sops: no sops metadata")
+	}
+	var meta metadata
+	if err := metaNode.Decode(&meta); err != nil {
+		return fmt.Errorf("sops: metadata: %w", err)
+	}
+	if len(meta.Age) == 0 {
+		return errors.New("sops: the file is not encrypted to any age recipient, only age keys are supported")
+	}
+	if len(ids) == 0 {
+		return errors.New("sops: no age identity, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+	var key []byte
+	for _, r := range meta.Age {
+		k, err := DecryptAge([]byte(r.Enc), ids)
+		if errors.Is(err, ErrNoIdentity) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("sops: data key of %s: %w", r.Recipient, err)
+		}
+		key = k
+		break
+	}
+	if key == nil {
+		return fmt.Errorf("sops: %w", ErrNoIdentity)
+	}
+
+	content := make([]*yaml.Node, 0, len(doc.Content)-2)
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != metadataKey {
+			content = append(content, doc.Content[i], doc.Content[i+1])
+		}
+	}
+	doc.Content = content
+	d := decryption{key: key, onlyEncrypted: meta.MACOnlyEncrypted, mac: sha512.New()}
+	if d.onlyEncrypted {
+		d.mac.Write(macOnlyEncryptedInit)
+	}
+	if err := d.values(doc, nil); err != nil {
+		return err
+	}
+	return d.verify(meta)
+}
+
+// decryption decrypts the values of a document and computes their MAC as SOPS does
+type decryption struct {
+	key []byte
+	// onlyEncrypted leaves the plaintext values out of the MAC
+	onlyEncrypted bool
+	mac           hash.Hash
+}
+
+// values decrypts the scalars under n. SOPS authenticates a value with the keys of the
+// mappings leading to it, list indices are not part of the path.
+func (d *decryption) values(n *yaml.Node, path []string) error {
+	dropEncryptedComments(n)
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := d.values(c, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			dropEncryptedComments(n.Content[i])
+			p := append(path[:len(path):len(path)], n.Content[i].Value)
+			if err := d.values(n.Content[i+1], p); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return fmt.Errorf("sops: %s: aliases are not supported in encrypted files", strings.Join(path, "."))
+	case yaml.ScalarNode:
+		if !strings.HasPrefix(n.Value, "ENC[") {
+			if d.onlyEncrypted || n.ShortTag() == "!!null" {
+				return nil
+			}
+			b, err := plainBytes(n)
+			if err != nil {
+				return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+			}
+			d.mac.Write(b)
+			return nil
+		}
+		value, tag, err := decryptValue(n.Value, d.key, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+		}
+		n.Value, n.Tag, n.Style = value, tag, 0
+		b, err := plainBytes(n)
+		if err != nil {
+			return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+		}
+		d.mac.Write(b)
+	}
+	return nil
+}
+
+// verify compares the MAC of the decrypted values with the MAC of the file, encrypted with
+// its last modification time
+func (d *decryption) verify(meta metadata) error {
+	if meta.MAC == "" {
+		return errors.New("sops: the file has no MAC")
+	}
+	modified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("sops: lastmodified: %w", err)
+	}
+	want, _, err := decryptValue(meta.MAC, d.key, modified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("sops: MAC: %w", err)
+	}
+	if got := fmt.Sprintf("%X", d.mac.Sum(nil)); got != want {
+		return errors.New("sops: MAC mismatch, the values of the file were modified after it was encrypted")
+	}
+	return nil
+}
+
+// plainBytes returns the bytes SOPS authenticates for the scalar n: its value formatted
+// from the type decoded by yaml, booleans being True or False
+func plainBytes(n *yaml.Node) ([]byte, error) {
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", n.Value)
+}
+
+func dropEncryptedComments(n *yaml.Node) {
+	for _, c := range []*string{&n.HeadComment, &n.LineComment, &n.FootComment} {
+		if strings.Contains(*c, "ENC[") {
+			*c = ""
+		}
+	}
+}
+
+// decryptValue decrypts a value formatted as ENC[AES256_GCM,data:...,iv:...,tag:...,type:...]
+// and returns it with the yaml tag of its type
+func decryptValue(s string, key []byte, aad string) (string, string, error) {
+	body, ok := strings.CutSuffix(strings.TrimPrefix(s, "ENC["), "]")
+	if !ok {
+		return "", "", errors.New("malformed encrypted value")
+	}
+	fields := strings.Split(body, ",")
+	if fields[0] != "AES256_GCM" {
+		return "", "", fmt.Errorf("unsupported cipher %q", fields[0])
+	}
+	parts := make(map[string][]byte)
+	var typ string
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(f, ":")
+		if k == "type" {
+			typ = v
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return "", "", fmt.Errorf("malformed %s of encrypted value: %w", k, err)
+		}
+		parts[k] = b
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(parts["iv"]))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := gcm.Open(nil, parts["iv"], append(parts["data"], parts["tag"]...), []byte(aad))
+	if err != nil {
+		return "", "", errors.New("value authentication failed")
+	}
+	switch typ {
+	case "str", "bytes":
+		return string(plain), "!!str", nil
+	case "int":
+		return string(plain), "!!int", nil
+	case "float":
+		return string(plain), "!!float", nil
+	case "bool":
+		return strings.ToLower(string(plain)), "!!bool", nil
+	}
+	return "", "", fmt.Errorf("unsupported value type %q", typ)
+}
+
+// LoadIdentities reads the age identities from SOPS_AGE_KEY, then from the file
+// SOPS_AGE_KEY_FILE or, when it is not set, the default key file of SOPS if it exists
+func LoadIdentities() ([]Identity, error) {
+	var ids []Identity
+	if keys := os.Getenv("SOPS_AGE_KEY"); keys != "" {
+		parsed, err := ParseIdentities(strings.NewReader(keys))
+		if err != nil {
+			return nil, fmt.Errorf("SOPS_AGE_KEY: %w", err)
+		}
+		ids = append(ids, parsed...)
+	}
+	file, explicit := os.LookupEnv("SOPS_AGE_KEY_FILE")
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ids, nil
+		}
+		file = filepath.Join(dir, "sops", "age", "keys.txt")
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return ids, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	parsed, err := ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return append(ids, parsed...), nil
+}
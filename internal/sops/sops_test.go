@@ -0,0 +1,157 @@
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The files of testdata were encrypted from plain.yaml by sops 3.9.4 and age 1.1.1 to the key
+// of keys.txt, e.g.
+//
+//	sops --encrypt --age <recipient> --encrypted-regex '^(phone_number|email|full_name|max_users|ratio)$' plain.yaml > sops.yaml
+//	sops --encrypt --age <recipient> --mac-only-encrypted --encrypted-regex '^(phone_number|email)$' plain.yaml > sops-mac-only-encrypted.yaml
+//	age -r <recipient> -a -o age.yaml.age plain.yaml
+
+func testIdentities(t *testing.T) []Identity {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "keys.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ids, err := ParseIdentities(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func decodeYAML(t *testing.T, b []byte) any {
+	t.Helper()
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func decryptSOPS(b []byte, ids []Identity) (any, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(&root) {
+		return nil, errors.New("not detected as encrypted")
+	}
+	if err := DecryptNode(&root, ids); err != nil {
+		return nil, err
+	}
+	var v any
+	err := root.Decode(&v)
+	return v, err
+}
+
+func TestDecryptNode(t *testing.T) {
+	ids := testIdentities(t)
+	want := decodeYAML(t, readTestdata(t, "plain.yaml"))
+	for _, name := range []string{"sops.yaml", "sops-mac-only-encrypted.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := decryptSOPS(readTestdata(t, name), ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decrypted %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecryptNodeTampered(t *testing.T) {
+	ids := testIdentities(t)
+	tests := []struct {
+		name string
+		file string
+		old  string
+		new  string
+		err  string
+	}{
+		{"modified plaintext value", "sops.yaml", "role: primary", "role: secondary", "MAC mismatch"},
+		{"modified plaintext boolean", "sops.yaml", "iris_enabled: true", "iris_enabled: false", "MAC mismatch"},
+		{"removed plaintext value", "sops.yaml", "      scheduling_timezone: Europe/Berlin\n", "", "MAC mismatch"},
+		{"moved encrypted value", "sops.yaml", "phone_number: ENC", "override_phone_number: ENC", "authentication failed"},
+		{"removed MAC", "sops.yaml", "    mac: ENC", "    notmac: ENC", "no MAC"},
+		{"modified last modification", "sops.yaml", `lastmodified: "20`, `lastmodified: "19`, "MAC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := readTestdata(t, tt.file)
+			if !bytes.Contains(b, []byte(tt.old)) {
+				t.Fatalf("%s does not contain %q", tt.file, tt.old)
+			}
+			b = bytes.Replace(b, []byte(tt.old), []byte(tt.new), 1)
+			_, err := decryptSOPS(b, ids)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecryptNodeMACOnlyEncrypted(t *testing.T) {
+	// plaintext values are not authenticated by the MAC of these files
+	b := bytes.Replace(readTestdata(t, "sops-mac-only-encrypted.yaml"), []byte("role: primary"), []byte("role: secondary"), 1)
+	if _, err := decryptSOPS(b, testIdentities(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecryptNodeWrongIdentity(t *testing.T) {
+	other, err := ParseIdentity("AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = decryptSOPS(readTestdata(t, "sops.yaml"), []Identity{other})
+	if !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("error %v, want %v", err, ErrNoIdentity)
+	}
+}
+
+func TestDecryptAge(t *testing.T) {
+	ids := testIdentities(t)
+	want := readTestdata(t, "plain.yaml")
+	for _, name := range []string{"age.yaml.age", "age-binary.yaml.age"} {
+		t.Run(name, func(t *testing.T) {
+			b := readTestdata(t, name)
+			if !IsAge(b) {
+				t.Fatal("not detected as age")
+			}
+			got, err := DecryptAge(b, ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decrypted %q, want %q", got, want)
+			}
+		})
+	}
+	if IsAge(want) {
+		t.Error("plain.yaml detected as age")
+	}
+}
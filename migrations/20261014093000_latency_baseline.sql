@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS latency_baseline (
+    id BIGSERIAL PRIMARY KEY,
+    week TIMESTAMPTZ NOT NULL,
+    alias VARCHAR(255) NOT NULL,
+    samples INTEGER NOT NULL,
+    baseline_samples INTEGER NOT NULL,
+    p50 FLOAT8 NOT NULL,
+    p90 FLOAT8 NOT NULL,
+    p99 FLOAT8 NOT NULL,
+    baseline_p50 FLOAT8 NOT NULL,
+    baseline_p90 FLOAT8 NOT NULL,
+    baseline_p99 FLOAT8 NOT NULL,
+    p_value FLOAT8 NOT NULL,
+    regression BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (alias, week)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS latency_baseline;
+-- +goose StatementEnd